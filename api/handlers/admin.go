@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+const (
+	defaultJobRunLimit = 50
+	maxJobRunLimit     = 500
+)
+
+// JobRunInfo represents one run of a background job
+type JobRunInfo struct {
+	RunID      string     `json:"run_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	JobName    string     `json:"job_name" example:"expiry-sweep"`
+	Instance   string     `json:"instance" example:"url-shortener-7d9f8b6c4-x2k8p"`
+	StartedAt  time.Time  `json:"started_at" example:"2024-01-01T03:00:00Z"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" example:"2024-01-01T03:00:02Z"`
+	Status     string     `json:"status" example:"succeeded" enums:"running,succeeded,failed"`
+	Affected   *int64     `json:"affected,omitempty" example:"42"`
+	Error      string     `json:"error,omitempty" example:""`
+}
+
+// ListJobRunsResponse represents the response for listing job runs
+type ListJobRunsResponse struct {
+	Runs []JobRunInfo `json:"runs"`
+}
+
+func jobRunToInfo(run sqlc.JobRun) JobRunInfo {
+	info := JobRunInfo{
+		RunID:     run.RunID.String(),
+		JobName:   run.JobName,
+		Instance:  run.Instance,
+		StartedAt: run.StartedAt.Time,
+		Status:    run.Status,
+		Error:     run.Error.String,
+	}
+	if run.FinishedAt.Valid {
+		info.FinishedAt = &run.FinishedAt.Time
+	}
+	if run.Affected.Valid {
+		info.Affected = &run.Affected.Int64
+	}
+	return info
+}
+
+// ListJobRuns lists the run history of background jobs
+// @Summary List Job Runs
+// @Description List recent runs of the background jobs (expiry sweep, click retention, cache cleanup, ...), newest first. Requires an admin account.
+// @Tags admin
+// @Security ApiKeyAuth
+// @Produce json
+// @Param job query string false "Only show runs of this job"
+// @Param limit query int false "Maximum number of runs (1-500)" default(50)
+// @Success 200 {object} ListJobRunsResponse "Job runs"
+// @Failure 400 {object} problem.Problem "Invalid limit"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "Admin access required"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/admin/jobs/runs [get]
+func ListJobRuns(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultJobRunLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxJobRunLimit {
+				problem.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		runs, err := db.ListJobRuns(r.Context(), sqlc.ListJobRunsParams{
+			Limit:   int32(limit),
+			JobName: stringToNullable(r.URL.Query().Get("job")),
+		})
+		if err != nil {
+			serverError(w, r, "Failed to fetch job runs", err)
+			return
+		}
+
+		response := ListJobRunsResponse{Runs: []JobRunInfo{}}
+		for _, run := range runs {
+			response.Runs = append(response.Runs, jobRunToInfo(run))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
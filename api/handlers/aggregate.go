@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+const (
+	defaultAggregateDays = 30
+	maxAggregateDays     = 366
+	defaultAggregateTop  = 10
+	maxAggregateTop      = 100
+	dateLayout           = "2006-01-02"
+)
+
+// AnalyticsPeriod is a range of whole UTC days; To is exclusive
+type AnalyticsPeriod struct {
+	From time.Time `json:"from" example:"2024-03-01T00:00:00Z"`
+	To   time.Time `json:"to" example:"2024-03-31T00:00:00Z"`
+}
+
+// AnalyticsCount is the click count of one value of a breakdown in the
+// period and in the previous period
+type AnalyticsCount struct {
+	Value          string `json:"value" example:"GH"`
+	Clicks         int64  `json:"clicks" example:"120"`
+	PreviousClicks int64  `json:"previous_clicks" example:"80"`
+	// ChangePercent is null when there were no clicks in the previous period
+	ChangePercent *float64 `json:"change_percent" example:"50"`
+}
+
+// LinkAnalytics is the click count of one link in the period and in the
+// previous period, with the conversions recorded in the period
+type LinkAnalytics struct {
+	ShortID        string             `json:"short_id" example:"abc123"`
+	Title          string             `json:"title,omitempty" example:"Spring launch"`
+	LongURL        string             `json:"long_url" example:"https://example.com/spring"`
+	Clicks         int64              `json:"clicks" example:"120"`
+	PreviousClicks int64              `json:"previous_clicks" example:"80"`
+	ChangePercent  *float64           `json:"change_percent" example:"50"`
+	Conversions    int64              `json:"conversions" example:"6"`
+	ConversionRate *float64           `json:"conversion_rate" example:"4.17"`
+	Revenue        map[string]float64 `json:"revenue"`
+}
+
+// AggregateAnalyticsResponse summarizes the clicks of a set of links
+type AggregateAnalyticsResponse struct {
+	Period         AnalyticsPeriod    `json:"period"`
+	PreviousPeriod AnalyticsPeriod    `json:"previous_period"`
+	Links          int                `json:"links" example:"42"`
+	Clicks         int64              `json:"clicks" example:"1200"`
+	PreviousClicks int64              `json:"previous_clicks" example:"1000"`
+	ChangePercent  *float64           `json:"change_percent" example:"20"`
+	Conversions    int64              `json:"conversions" example:"48"`
+	ConversionRate *float64           `json:"conversion_rate" example:"3.5"`
+	Revenue        map[string]float64 `json:"revenue"`
+	TopLinks       []LinkAnalytics    `json:"top_links"`
+	Countries      []AnalyticsCount   `json:"countries"`
+	Devices        []AnalyticsCount   `json:"devices"`
+	Referrers      []AnalyticsCount   `json:"referrers"`
+}
+
+// changePercent returns the change from previous to current in percent,
+// rounded to one decimal, or nil when there is nothing to compare with
+func changePercent(current, previous int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round(float64(current-previous)/float64(previous)*1000) / 10
+	return &change
+}
+
+// parseAggregatePeriod reads the inclusive from and to dates. Without them
+// the period is the last 30 days including today.
+func parseAggregatePeriod(r *http.Request) (AnalyticsPeriod, error) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return AnalyticsPeriod{}, fmt.Errorf("to must be a date like 2024-03-31")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -(defaultAggregateDays - 1))
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return AnalyticsPeriod{}, fmt.Errorf("from must be a date like 2024-03-01")
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return AnalyticsPeriod{}, fmt.Errorf("from must not be after to")
+	}
+	to = to.AddDate(0, 0, 1)
+	if to.Sub(from) > maxAggregateDays*24*time.Hour {
+		return AnalyticsPeriod{}, fmt.Errorf("the period can be at most %d days", maxAggregateDays)
+	}
+	return AnalyticsPeriod{From: from, To: to}, nil
+}
+
+// aggregateClicks counts the clicks of links in a period, keyed by
+// dimension and value
+func aggregateClicks(ctx context.Context, db *sqlc.Queries, shortIDs []string, period AnalyticsPeriod, rolledUpTo pgtype.Timestamp) (map[string]map[string]int64, error) {
+	rows, err := db.AggregateClickRollups(ctx, sqlc.AggregateClickRollupsParams{
+		ShortIds:   shortIDs,
+		FromTime:   pgtype.Timestamp{Time: period.From, Valid: true},
+		ToTime:     pgtype.Timestamp{Time: period.To, Valid: true},
+		RolledUpTo: rolledUpTo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]map[string]int64{}
+	for _, row := range rows {
+		if counts[row.Dimension] == nil {
+			counts[row.Dimension] = map[string]int64{}
+		}
+		counts[row.Dimension][row.Value] += row.Clicks
+	}
+	return counts, nil
+}
+
+// linkConversions are the conversions of one link in a period
+type linkConversions struct {
+	conversions, converted int64
+	revenue                map[string]float64
+}
+
+// aggregateConversions collects the conversions of links in a period,
+// attributed to the period in which they were recorded
+func aggregateConversions(ctx context.Context, db *sqlc.Queries, shortIDs []string, period AnalyticsPeriod) (map[string]*linkConversions, error) {
+	from := pgtype.Timestamp{Time: period.From, Valid: true}
+	to := pgtype.Timestamp{Time: period.To, Valid: true}
+	totals, err := db.AggregateConversions(ctx, sqlc.AggregateConversionsParams{ShortIds: shortIDs, FromTime: from, ToTime: to})
+	if err != nil {
+		return nil, err
+	}
+	revenue, err := db.AggregateConversionRevenue(ctx, sqlc.AggregateConversionRevenueParams{ShortIds: shortIDs, FromTime: from, ToTime: to})
+	if err != nil {
+		return nil, err
+	}
+
+	conversions := map[string]*linkConversions{}
+	for _, row := range totals {
+		conversions[row.ShortID] = &linkConversions{
+			conversions: row.Conversions,
+			converted:   row.ConvertedClicks,
+			revenue:     map[string]float64{},
+		}
+	}
+	for _, row := range revenue {
+		if c := conversions[row.ShortID]; c != nil {
+			c.revenue[row.Currency] += row.Revenue
+		}
+	}
+	return conversions, nil
+}
+
+// breakdown compares the clicks of one dimension between two periods and
+// returns the top values of the current one. Clicks without a value are
+// reported under empty, like clickTotals does.
+func breakdown(current, previous map[string]int64, empty string, top int) []AnalyticsCount {
+	merged := map[string]AnalyticsCount{}
+	for value, clicks := range current {
+		key := value
+		if key == "" {
+			key = empty
+		}
+		c := merged[key]
+		c.Value, c.Clicks = key, c.Clicks+clicks
+		merged[key] = c
+	}
+	for value, clicks := range previous {
+		key := value
+		if key == "" {
+			key = empty
+		}
+		// Values only seen in the previous period are not part of the top list
+		if c, ok := merged[key]; ok {
+			c.PreviousClicks += clicks
+			merged[key] = c
+		}
+	}
+
+	counts := make([]AnalyticsCount, 0, len(merged))
+	for _, c := range merged {
+		c.ChangePercent = changePercent(c.Clicks, c.PreviousClicks)
+		counts = append(counts, c)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Clicks != counts[j].Clicks {
+			return counts[i].Clicks > counts[j].Clicks
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if len(counts) > top {
+		counts = counts[:top]
+	}
+	return counts
+}
+
+// GetAggregateAnalytics summarizes clicks across links
+// @Summary Get Aggregate Analytics
+// @Description Summarize the clicks of all your URLs, the URLs carrying a tag, or a list of short IDs over a range of whole UTC days: total clicks, the most clicked links and the top countries, devices and referrer hosts, each compared with the period of the same length just before. Conversions recorded in the period are reported with their conversion rate and revenue per currency, in total and per link. Counts are served from the click rollups like the analytics of a single URL.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Param tag_id query string false "Only URLs carrying this tag"
+// @Param short_ids query string false "Comma-separated short IDs of URLs to include"
+// @Param from query string false "First day of the period (YYYY-MM-DD, default 29 days before to)"
+// @Param to query string false "Last day of the period (YYYY-MM-DD, default today)"
+// @Param top query int false "Number of links and values per breakdown to return (1-100)" default(10)
+// @Produce json
+// @Success 200 {object} AggregateAnalyticsResponse "Click summary"
+// @Failure 400 {object} problem.Problem "Invalid parameters"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Tag or URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/analytics [get]
+func GetAggregateAnalytics(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		period, err := parseAggregatePeriod(r)
+		if err != nil {
+			problem.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		length := period.To.Sub(period.From)
+		previous := AnalyticsPeriod{From: period.From.Add(-length), To: period.From}
+
+		top := defaultAggregateTop
+		if v := r.URL.Query().Get("top"); v != "" {
+			top, err = strconv.Atoi(v)
+			if err != nil || top < 1 || top > maxAggregateTop {
+				problem.Error(w, "top must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+		}
+
+		params := sqlc.ListAnalyticsLinksParams{UserID: pgtype.UUID{Bytes: userID, Valid: true}}
+		if v := r.URL.Query().Get("tag_id"); v != "" {
+			tagID, err := uuid.Parse(v)
+			if err != nil {
+				problem.Error(w, "Invalid tag ID", http.StatusBadRequest)
+				return
+			}
+			if _, err := db.GetTag(r.Context(), sqlc.GetTagParams{TagID: tagID, UserID: userID}); err != nil {
+				rowError(w, r, "Tag not found", "Failed to fetch tag", err)
+				return
+			}
+			params.TagID = pgtype.UUID{Bytes: tagID, Valid: true}
+		}
+		requested := map[string]bool{}
+		if v := r.URL.Query().Get("short_ids"); v != "" {
+			for _, shortID := range strings.Split(v, ",") {
+				if shortID = strings.TrimSpace(shortID); shortID != "" && !requested[shortID] {
+					requested[shortID] = true
+					params.ShortIds = append(params.ShortIds, shortID)
+				}
+			}
+		}
+
+		links, err := db.ListAnalyticsLinks(r.Context(), params)
+		if err != nil {
+			serverError(w, r, "Failed to fetch analytics", err)
+			return
+		}
+		// Without a tag every requested short ID must be one of the user's URLs
+		if len(requested) > 0 && !params.TagID.Valid && len(links) != len(requested) {
+			problem.Error(w, "URL not found", http.StatusNotFound)
+			return
+		}
+
+		response := AggregateAnalyticsResponse{
+			Period:         period,
+			PreviousPeriod: previous,
+			Links:          len(links),
+			Revenue:        map[string]float64{},
+			TopLinks:       []LinkAnalytics{},
+			Countries:      []AnalyticsCount{},
+			Devices:        []AnalyticsCount{},
+			Referrers:      []AnalyticsCount{},
+		}
+		if len(links) == 0 {
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		rolledUpTo, err := db.GetRollupWatermark(r.Context())
+		if err != nil {
+			serverError(w, r, "Failed to fetch analytics", err)
+			return
+		}
+		shortIDs := make([]string, len(links))
+		for i, link := range links {
+			shortIDs[i] = link.ShortID
+		}
+		current, err := aggregateClicks(r.Context(), db, shortIDs, period, rolledUpTo)
+		if err != nil {
+			serverError(w, r, "Failed to fetch analytics", err)
+			return
+		}
+		before, err := aggregateClicks(r.Context(), db, shortIDs, previous, rolledUpTo)
+		if err != nil {
+			serverError(w, r, "Failed to fetch analytics", err)
+			return
+		}
+
+		conversions, err := aggregateConversions(r.Context(), db, shortIDs, period)
+		if err != nil {
+			serverError(w, r, "Failed to fetch analytics", err)
+			return
+		}
+
+		var converted int64
+		for _, link := range links {
+			clicks, previousClicks := current["link"][link.ShortID], before["link"][link.ShortID]
+			response.Clicks += clicks
+			response.PreviousClicks += previousClicks
+			linkConverted := &linkConversions{revenue: map[string]float64{}}
+			if c := conversions[link.ShortID]; c != nil {
+				linkConverted = c
+			}
+			response.Conversions += linkConverted.conversions
+			converted += linkConverted.converted
+			for currency, revenue := range linkConverted.revenue {
+				response.Revenue[currency] += revenue
+			}
+			if clicks == 0 && linkConverted.conversions == 0 {
+				continue
+			}
+			response.TopLinks = append(response.TopLinks, LinkAnalytics{
+				ShortID:        link.ShortID,
+				Title:          link.Title.String,
+				LongURL:        link.LongUrl,
+				Clicks:         clicks,
+				PreviousClicks: previousClicks,
+				ChangePercent:  changePercent(clicks, previousClicks),
+				Conversions:    linkConverted.conversions,
+				ConversionRate: conversionRate(linkConverted.converted, clicks),
+				Revenue:        linkConverted.revenue,
+			})
+		}
+		response.ChangePercent = changePercent(response.Clicks, response.PreviousClicks)
+		response.ConversionRate = conversionRate(converted, response.Clicks)
+		sort.Slice(response.TopLinks, func(i, j int) bool {
+			if response.TopLinks[i].Clicks != response.TopLinks[j].Clicks {
+				return response.TopLinks[i].Clicks > response.TopLinks[j].Clicks
+			}
+			return response.TopLinks[i].ShortID < response.TopLinks[j].ShortID
+		})
+		if len(response.TopLinks) > top {
+			response.TopLinks = response.TopLinks[:top]
+		}
+
+		response.Countries = breakdown(current["country"], before["country"], "unknown", top)
+		response.Devices = breakdown(current["device"], before["device"], "unknown", top)
+		response.Referrers = breakdown(current["referrer"], before["referrer"], "direct", top)
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -1,30 +1,148 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
 	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/tracing"
 )
 
 // AnalyticsResponse represents the analytics response
 type AnalyticsResponse map[string]int
 
+// Analytics dimensions served from the click rollups
+const (
+	groupByLocation = "location"
+	groupByCountry  = "country"
+	groupByDevice   = "device"
+	groupByReferrer = "referrer"
+	groupByHour     = "hour"
+	groupByDay      = "day"
+)
+
+// clickTotals counts the clicks of a URL by one rollup dimension. Clicks with
+// no value for the dimension are reported as "unknown", or "direct" for the
+// referrer.
+func clickTotals(ctx context.Context, db *sqlc.Queries, shortID, groupBy string, rolledUpTo pgtype.Timestamp) (map[string]int, error) {
+	rows, err := db.ClickRollupTotals(ctx, sqlc.ClickRollupTotalsParams{ShortID: shortID, RolledUpTo: rolledUpTo})
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := map[string]int{}
+	for _, row := range rows {
+		key, empty := row.Location, "unknown"
+		switch groupBy {
+		case groupByCountry:
+			key = row.Country
+		case groupByDevice:
+			key = row.Device
+		case groupByReferrer:
+			key, empty = row.Referrer, "direct"
+		}
+		if key == "" {
+			key = empty
+		}
+		analytics[key] += int(row.Clicks)
+	}
+	return analytics, nil
+}
+
+// clickSeries counts the clicks of a URL per hour or day, keyed by the
+// RFC 3339 start of each bucket.
+func clickSeries(ctx context.Context, db *sqlc.Queries, shortID, groupBy string, rolledUpTo pgtype.Timestamp) (map[string]int, error) {
+	analytics := map[string]int{}
+	if groupBy == groupByHour {
+		rows, err := db.ClickSeriesHourly(ctx, sqlc.ClickSeriesHourlyParams{ShortID: shortID, RolledUpTo: rolledUpTo})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			analytics[row.Bucket.Time.Format(time.RFC3339)] = int(row.Clicks)
+		}
+		return analytics, nil
+	}
+
+	rows, err := db.ClickSeriesDaily(ctx, sqlc.ClickSeriesDailyParams{ShortID: shortID, RolledUpTo: rolledUpTo})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		analytics[row.Bucket.Time.Format(time.RFC3339)] = int(row.Clicks)
+	}
+	return analytics, nil
+}
+
+// Analytics metrics
+const (
+	metricClicks  = "clicks"
+	metricUniques = "uniques"
+)
+
+func validGroupBy(groupBy string) bool {
+	switch groupBy {
+	case "", groupByLocation, groupByCountry, groupByDevice, groupByReferrer, groupByHour, groupByDay, "source", "variant":
+		return true
+	}
+	return false
+}
+
+// uniqueVisitors counts the daily unique visitors of a URL by dimension. They
+// are computed from the hashed visitor IDs of raw clicks, so they cover the
+// click retention period.
+func uniqueVisitors(ctx context.Context, db *sqlc.Queries, shortID, groupBy string) (map[string]int, error) {
+	if groupBy == "" {
+		groupBy = groupByLocation
+	}
+	rows, err := db.CountUniqueVisitors(ctx, sqlc.CountUniqueVisitorsParams{
+		GroupBy: groupBy,
+		ShortID: pgtype.Text{String: shortID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := map[string]int{}
+	for _, row := range rows {
+		key := row.Dimension
+		if key == "" {
+			switch groupBy {
+			case groupByLocation, groupByCountry, groupByDevice:
+				key = "unknown"
+			case groupByReferrer:
+				key = "direct"
+			}
+		}
+		analytics[key] += int(row.Visitors)
+	}
+	return analytics, nil
+}
+
 // GetAnalytics gets analytics for a specific URL
 // @Summary Get URL Analytics
-// @Description Get click analytics for a specific URL owned by the authenticated user
+// @Description Get click analytics for a specific URL owned by the authenticated user. Clicks can be grouped by visitor location, country, device or referrer host, counted per hour or day, grouped by source (qr for scanned QR codes, direct otherwise) or, for A/B split links, by variant name. Clicks made before a split was set up are reported under an empty variant name. Location, country, device, referrer and time series are served from pre-aggregated rollups plus the clicks of the last hour; hourly series cover the last 90 days. With metric=uniques the same groups report unique visitors instead, counted from a salted visitor hash that rotates daily, so a visitor returning on another day counts again.
 // @Tags analytics
 // @Security ApiKeyAuth
 // @Param shortID path string true "Short URL ID"
+// @Param group_by query string false "Group clicks by" Enums(location, country, device, referrer, hour, day, source, variant) default(location)
+// @Param metric query string false "Count clicks or unique visitors" Enums(clicks, uniques) default(clicks)
 // @Produce json
-// @Success 200 {object} AnalyticsResponse "Analytics data by location"
-// @Failure 401 {object} map[string]string "Unauthorized or URL not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Success 200 {object} AnalyticsResponse "Click or visitor counts per group"
+// @Failure 400 {object} problem.Problem "Invalid group_by or metric"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/analytics/{shortID} [get]
-func GetAnalytics(db *sqlc.Queries, geoAPIURL string) http.HandlerFunc {
+func GetAnalytics(db *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		shortID := chi.URLParam(r, "shortID")
 		userIDStr, _ := r.Context().Value("user_id").(string)
@@ -32,61 +150,133 @@ func GetAnalytics(db *sqlc.Queries, geoAPIURL string) http.HandlerFunc {
 		// Parse userID from context
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
 		// Ensure user owns the URL
-		url, err := db.GetURL(r.Context(), shortID)
-		if err != nil || (url.UserID.Valid && url.UserID.Bytes != userID) {
-			http.Error(w, "Unauthorized or URL not found", http.StatusUnauthorized)
+		if _, err := db.GetOwnedURL(r.Context(), shortID, userID); err != nil {
+			dbError(w, r, "Failed to fetch URL", err)
+			return
+		}
+
+		groupBy := r.URL.Query().Get("group_by")
+		switch r.URL.Query().Get("metric") {
+		case "", metricClicks:
+		case metricUniques:
+			if !validGroupBy(groupBy) {
+				problem.Error(w, "Invalid group_by", http.StatusBadRequest)
+				return
+			}
+			analytics, err := uniqueVisitors(r.Context(), db, shortID, groupBy)
+			if err != nil {
+				serverError(w, r, "Failed to fetch analytics", err)
+				return
+			}
+			json.NewEncoder(w).Encode(analytics)
+			return
+		default:
+			problem.Error(w, "Invalid metric", http.StatusBadRequest)
 			return
 		}
 
-		clicks, _ := db.ListClicks(r.Context(), pgtype.Text{String: shortID, Valid: true})
 		analytics := map[string]int{}
-		for _, click := range clicks {
-			location := getGeoLocation(click.IpAddress.String, geoAPIURL)
-			analytics[location]++
+		switch groupBy {
+		case "variant":
+			rows, queryErr := db.CountClicksByVariant(r.Context(), pgtype.Text{String: shortID, Valid: true})
+			err = queryErr
+			for _, row := range rows {
+				analytics[row.Variant] = int(row.Clicks)
+			}
+		case "source":
+			rows, queryErr := db.CountClicksBySource(r.Context(), pgtype.Text{String: shortID, Valid: true})
+			err = queryErr
+			for _, row := range rows {
+				analytics[row.Source] = int(row.Clicks)
+			}
+		case "", groupByLocation, groupByCountry, groupByDevice, groupByReferrer, groupByHour, groupByDay:
+			rolledUpTo, watermarkErr := db.GetRollupWatermark(r.Context())
+			if watermarkErr != nil {
+				err = watermarkErr
+				break
+			}
+			if groupBy == groupByHour || groupBy == groupByDay {
+				analytics, err = clickSeries(r.Context(), db, shortID, groupBy, rolledUpTo)
+			} else {
+				analytics, err = clickTotals(r.Context(), db, shortID, groupBy, rolledUpTo)
+			}
+		default:
+			problem.Error(w, "Invalid group_by", http.StatusBadRequest)
+			return
 		}
+		if err != nil {
+			serverError(w, r, "Failed to fetch analytics", err)
+			return
+		}
+
 		json.NewEncoder(w).Encode(analytics)
 	}
 }
 
-func getGeoLocation(ip, geoAPIURL string) string {
+// geoClient bounds geo lookups made while serving redirects
+var geoClient = &http.Client{Timeout: 2 * time.Second, Transport: tracing.Transport(nil)}
+
+// geoInfo is the location of an IP address
+type geoInfo struct {
+	CountryCode string `json:"country_code"`
+	// Location is "City, Country", or just the country when the city is unknown
+	Location string `json:"location"`
+}
+
+// lookupGeo returns the location of an IP address; fields are empty when it
+// is unknown. Results are cached in Redis for a day.
+func lookupGeo(ctx context.Context, redisClient *redis.Client, ip, geoAPIURL string) geoInfo {
+	var info geoInfo
 	if ip == "" {
-		return "unknown"
+		return info
 	}
 
-	// Create request to the geo API
-	resp, err := http.Get(geoAPIURL + "/" + ip)
+	key := "geo:" + ip
+	if data, err := redisClient.Get(ctx, key).Bytes(); err == nil {
+		if err := json.Unmarshal(data, &info); err == nil {
+			return info
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, geoAPIURL+"/"+ip, nil)
+	if err != nil {
+		return info
+	}
+	resp, err := geoClient.Do(req)
 	if err != nil {
-		return "unknown"
+		return info
 	}
 	defer resp.Body.Close()
 
-	// Parse the response
 	var result struct {
-		Country string `json:"country"`
-		City    string `json:"city"`
-		Status  string `json:"status"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "unknown"
+		Country     string `json:"country"`
+		CountryCode string `json:"countryCode"`
+		City        string `json:"city"`
+		Status      string `json:"status"`
 	}
-
-	// Check if the request was successful
-	if result.Status != "success" {
-		return "unknown"
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Status != "success" {
+		return info
 	}
 
-	// Return country or city+country if available
+	info.CountryCode = result.CountryCode
+	info.Location = result.Country
 	if result.City != "" {
-		return result.City + ", " + result.Country
+		info.Location = result.City + ", " + result.Country
 	}
-	if result.Country != "" {
-		return result.Country
+
+	if data, err := json.Marshal(info); err == nil {
+		redisClient.Set(ctx, key, data, 24*time.Hour)
 	}
-	return "unknown"
+	return info
+}
+
+// lookupCountryCode returns the ISO country code of an IP address, or an
+// empty string when it is unknown.
+func lookupCountryCode(ctx context.Context, redisClient *redis.Client, ip, geoAPIURL string) string {
+	return lookupGeo(ctx, redisClient, ip, geoAPIURL).CountryCode
 }
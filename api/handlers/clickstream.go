@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/streamtoken"
+)
+
+// streamHeartbeat keeps idle streams open through proxies and detects
+// clients that went away.
+const streamHeartbeat = 15 * time.Second
+
+// streamWriteTimeout bounds a single WebSocket write
+const streamWriteTimeout = 10 * time.Second
+
+// ClickEvent is a click pushed to live click streams
+type ClickEvent struct {
+	ShortID  string    `json:"short_id" example:"abc123"`
+	Time     time.Time `json:"time" example:"2024-03-01T09:00:00Z"`
+	Country  string    `json:"country,omitempty" example:"GH"`
+	Device   string    `json:"device,omitempty" example:"mobile"`
+	Referrer string    `json:"referrer,omitempty" example:"twitter.com"`
+	Source   string    `json:"source,omitempty" example:"qr"`
+}
+
+// clickChannel is the Redis pub/sub channel the clicks of a link are
+// published on. Every replica publishes there, so a stream served by any
+// replica sees all clicks.
+func clickChannel(shortID string) string {
+	return "clickstream:" + shortID
+}
+
+// publishClick pushes a click to the live streams of its link. Nobody
+// listening is the common case and costs a single PUBLISH.
+func publishClick(ctx context.Context, redisClient *redis.Client, event ClickEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	redisClient.Publish(ctx, clickChannel(event.ShortID), data)
+}
+
+// StreamTokenResponse is a token for opening a click stream from a browser
+type StreamTokenResponse struct {
+	Token     string    `json:"token" example:"3f2a9c0d1e4b5a6978c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4"`
+	ExpiresAt time.Time `json:"expires_at" example:"2024-03-01T09:01:00Z"`
+}
+
+// CreateStreamToken issues a token for opening a click stream of a URL
+// @Summary Create Stream Token
+// @Description Issue a token for opening one live click stream of one of your URLs from a browser, where EventSource and WebSocket cannot send the X-API-Key header. Pass it as the token query parameter of the stream. Tokens expire after a minute and work once; fetch a new one to reconnect.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Param shortID path string true "Short URL ID"
+// @Produce json
+// @Success 200 {object} StreamTokenResponse "Stream token"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/analytics/{shortID}/stream-token [post]
+func CreateStreamToken(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		userID, _ := r.Context().Value("user_id").(string)
+		token, expiresAt, err := streamtoken.Issue(r.Context(), redisClient, userID, link.ShortID)
+		if err != nil {
+			serverError(w, r, "Failed to create stream token", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(StreamTokenResponse{Token: token, ExpiresAt: expiresAt})
+	}
+}
+
+// StreamClicks streams the clicks of a URL as Server-Sent Events
+// @Summary Stream Clicks (SSE)
+// @Description Stream the clicks of one of your URLs as they happen, as Server-Sent Events. Each click is sent as a "click" event whose data is a ClickEvent; comment lines are sent every 15 seconds to keep the connection open.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Param shortID path string true "Short URL ID"
+// @Param token query string false "Stream token, for browsers that cannot send X-API-Key"
+// @Produce text/event-stream
+// @Success 200 {object} ClickEvent "Stream of click events"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Streaming unsupported"
+// @Router /api/analytics/{shortID}/stream [get]
+func StreamClicks(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			serverError(w, r, "Streaming unsupported", errors.New("response writer cannot flush"))
+			return
+		}
+
+		sub := redisClient.Subscribe(r.Context(), clickChannel(link.ShortID))
+		defer sub.Close()
+		if _, err := sub.Receive(r.Context()); err != nil {
+			serverError(w, r, "Failed to subscribe to clicks", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		// Stop nginx from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, ": connected\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		messages := sub.Channel()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				if _, err := fmt.Fprintf(w, "event: click\ndata: %s\n\n", msg.Payload); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+var clickStreamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	Error: func(w http.ResponseWriter, _ *http.Request, status int, reason error) {
+		problem.Error(w, reason.Error(), status)
+	},
+}
+
+// StreamClicksWebSocket streams the clicks of a URL over a WebSocket
+// @Summary Stream Clicks (WebSocket)
+// @Description Stream the clicks of one of your URLs as they happen over a WebSocket. Each click is sent as a JSON text message holding a ClickEvent; messages from the client are ignored.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Param shortID path string true "Short URL ID"
+// @Param token query string false "Stream token, for browsers that cannot send X-API-Key"
+// @Success 101 {object} ClickEvent "Switching to the WebSocket protocol"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Router /api/analytics/{shortID}/ws [get]
+func StreamClicksWebSocket(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		// The upgrader answers failed handshakes itself
+		conn, err := clickStreamUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		sub := redisClient.Subscribe(ctx, clickChannel(link.ShortID))
+		defer sub.Close()
+
+		// Read until the client closes the connection; a read is also what
+		// processes pongs and close frames
+		go func() {
+			defer cancel()
+			conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+			})
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		messages := sub.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+					return
+				}
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
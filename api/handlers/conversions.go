@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/counters"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// Click ID modes decide how the click ID of a redirect reaches the
+// destination site
+const (
+	// ClickIDModeQuery appends the click ID to the destination URL
+	ClickIDModeQuery = "query"
+	// ClickIDModeCookie sets the click ID as a cookie that the conversion
+	// pixel reads back
+	ClickIDModeCookie = "cookie"
+)
+
+// Conversion sources
+const (
+	conversionSourceAPI   = "api"
+	conversionSourcePixel = "pixel"
+)
+
+const (
+	clickIDCookie          = "click_id"
+	defaultConversionEvent = "conversion"
+	maxConversionEvent     = 64
+	// maxConversionValue fits the NUMERIC(18, 4) value column
+	maxConversionValue = 1e14
+)
+
+var (
+	errClickNotFound      = errors.New("click not found")
+	errConversionTooLate  = errors.New("click is outside the conversion window")
+	errInvalidValue       = errors.New("value must be a non-negative number below 10^14")
+	errInvalidCurrency    = errors.New("currency must be a three-letter ISO 4217 code")
+	errInvalidEvent       = errors.New("event must be at most 64 characters")
+	errConversionNotFound = errors.New("conversion not found")
+)
+
+// transparentGIF is the 1x1 image served by the conversion pixel
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// ValidClickIDMode reports whether m is a supported click ID mode
+func ValidClickIDMode(m string) bool {
+	return m == ClickIDModeQuery || m == ClickIDModeCookie
+}
+
+// appendClickID adds the click ID to the query string of a destination,
+// leaving the rest of the query as it is
+func appendClickID(target, param string, clickID uuid.UUID) string {
+	u, err := url.Parse(target)
+	if err != nil || param == "" {
+		return target
+	}
+	query := u.Query()
+	if query.Has(param) {
+		query.Set(param, clickID.String())
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += url.QueryEscape(param) + "=" + clickID.String()
+	return u.String()
+}
+
+// setClickIDCookie remembers the click for the conversion pixel. The pixel is
+// loaded from the destination site, so the cookie has to be sent cross-site.
+func setClickIDCookie(w http.ResponseWriter, r *http.Request, clickID uuid.UUID, window time.Duration) {
+	secure := isHTTPS(r)
+	sameSite := http.SameSiteLaxMode
+	if secure {
+		sameSite = http.SameSiteNoneMode
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     clickIDCookie,
+		Value:    clickID.String(),
+		Path:     "/",
+		MaxAge:   int(window.Seconds()),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: sameSite,
+	})
+}
+
+// conversionInput is a conversion as reported by the API or the pixel
+type conversionInput struct {
+	ClickID  string
+	Event    string
+	Value    string
+	Currency string
+}
+
+// recordConversion stores a conversion against its click. With an owner, only
+// clicks on that user's links are accepted. A click converts once per event;
+// reporting it again returns the stored conversion with created set to false.
+func recordConversion(ctx context.Context, db *sqlc.Queries, input conversionInput, source string, owner *uuid.UUID, window time.Duration) (conversion sqlc.Conversion, created bool, err error) {
+	clickID, err := uuid.Parse(input.ClickID)
+	if err != nil {
+		return conversion, false, errClickNotFound
+	}
+
+	event := strings.TrimSpace(input.Event)
+	if event == "" {
+		event = defaultConversionEvent
+	}
+	if len(event) > maxConversionEvent {
+		return conversion, false, errInvalidEvent
+	}
+
+	params := sqlc.CreateConversionParams{
+		ConversionID: uuid.New(),
+		ClickID:      clickID,
+		Event:        event,
+		Source:       source,
+		CreatedAt:    pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}
+	if input.Value != "" {
+		value, err := strconv.ParseFloat(input.Value, 64)
+		if err != nil || value < 0 || value >= maxConversionValue || math.IsNaN(value) {
+			return conversion, false, errInvalidValue
+		}
+		if err := params.Value.Scan(strconv.FormatFloat(value, 'f', -1, 64)); err != nil {
+			return conversion, false, errInvalidValue
+		}
+		currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+		if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return conversion, false, errInvalidCurrency
+		}
+		params.Currency = pgtype.Text{String: currency, Valid: true}
+	}
+
+	click, err := db.GetConversionClick(ctx, clickID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return conversion, false, errClickNotFound
+		}
+		return conversion, false, err
+	}
+	if owner != nil && (!click.UserID.Valid || click.UserID.Bytes != *owner) {
+		return conversion, false, errClickNotFound
+	}
+	if window > 0 && time.Since(click.ClickedAt.Time) > window {
+		return conversion, false, errConversionTooLate
+	}
+	params.ShortID = click.ShortID
+
+	conversion, err = db.CreateConversion(ctx, params)
+	if err == nil {
+		return conversion, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return conversion, false, err
+	}
+	conversion, err = db.GetConversionByEvent(ctx, sqlc.GetConversionByEventParams{ClickID: clickID, Event: event})
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = errConversionNotFound
+	}
+	return conversion, false, err
+}
+
+// ConversionRequest is the body of a server-side conversion postback
+type ConversionRequest struct {
+	ClickID  string      `json:"click_id" example:"3f2b8c1e-5d4a-4b7e-9c2f-1a2b3c4d5e6f" binding:"required"`
+	Event    string      `json:"event,omitempty" example:"purchase"`
+	Value    json.Number `json:"value,omitempty" swaggertype:"number" example:"49.99"`
+	Currency string      `json:"currency,omitempty" example:"USD"`
+}
+
+// ConversionInfo is a recorded conversion
+type ConversionInfo struct {
+	ConversionID string    `json:"conversion_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ClickID      string    `json:"click_id" example:"3f2b8c1e-5d4a-4b7e-9c2f-1a2b3c4d5e6f"`
+	ShortID      string    `json:"short_id" example:"abc123"`
+	Event        string    `json:"event" example:"purchase"`
+	Value        *float64  `json:"value,omitempty" example:"49.99"`
+	Currency     string    `json:"currency,omitempty" example:"USD"`
+	Source       string    `json:"source" example:"api"`
+	CreatedAt    time.Time `json:"created_at" example:"2024-03-01T09:30:00Z"`
+}
+
+func conversionToInfo(c sqlc.Conversion) ConversionInfo {
+	info := ConversionInfo{
+		ConversionID: c.ConversionID.String(),
+		ClickID:      c.ClickID.String(),
+		ShortID:      c.ShortID,
+		Event:        c.Event,
+		Currency:     c.Currency.String,
+		Source:       c.Source,
+		CreatedAt:    c.CreatedAt.Time,
+	}
+	if value, err := c.Value.Float64Value(); err == nil && value.Valid {
+		info.Value = &value.Float64
+	}
+	return info
+}
+
+// CreateConversion records a conversion reported by a server
+// @Summary Record Conversion
+// @Description Record a conversion against the click ID of a redirect to one of your URLs, optionally with its value and ISO 4217 currency. A click converts once per event: reporting the same click and event again returns the stored conversion with 200, so postbacks can be retried safely. Clicks older than the conversion window are rejected.
+// @Tags conversions
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body ConversionRequest true "Conversion"
+// @Success 201 {object} ConversionInfo "Conversion recorded"
+// @Success 200 {object} ConversionInfo "Conversion already recorded"
+// @Failure 400 {object} problem.Problem "Invalid event, value or currency"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Click not found"
+// @Failure 422 {object} problem.Problem "Click is outside the conversion window"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/conversions [post]
+func CreateConversion(db *sqlc.Queries, window time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var input ConversionRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		conversion, created, err := recordConversion(r.Context(), db, conversionInput{
+			ClickID:  input.ClickID,
+			Event:    input.Event,
+			Value:    input.Value.String(),
+			Currency: input.Currency,
+		}, conversionSourceAPI, &userID, window)
+		switch {
+		case errors.Is(err, errClickNotFound):
+			problem.Error(w, "Click not found", http.StatusNotFound)
+			return
+		case errors.Is(err, errConversionTooLate):
+			problem.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, errInvalidValue), errors.Is(err, errInvalidCurrency), errors.Is(err, errInvalidEvent):
+			problem.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			serverError(w, r, "Failed to record conversion", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(conversionToInfo(conversion))
+	}
+}
+
+// ConversionPixel records a conversion from a tracking pixel
+// @Summary Conversion Pixel
+// @Description A 1x1 GIF to embed on the page shown after a conversion. The click ID is taken from the click_id parameter or, for links with click ID mode "cookie", from the cookie set by the redirect. The image is served whether or not a conversion was recorded.
+// @Tags conversions
+// @Produce image/gif
+// @Param click_id query string false "Click ID (defaults to the click ID cookie)"
+// @Param event query string false "Conversion event" default(conversion)
+// @Param value query number false "Conversion value"
+// @Param currency query string false "ISO 4217 currency of the value"
+// @Success 200 {file} file "Transparent 1x1 GIF"
+// @Router /conversions/pixel.gif [get]
+func ConversionPixel(db *sqlc.Queries, window time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		clickID := query.Get("click_id")
+		if clickID == "" {
+			if cookie, err := r.Cookie(clickIDCookie); err == nil {
+				clickID = cookie.Value
+			}
+		}
+
+		if clickID != "" {
+			recordConversion(r.Context(), db, conversionInput{
+				ClickID:  clickID,
+				Event:    query.Get("event"),
+				Value:    query.Get("value"),
+				Currency: query.Get("currency"),
+			}, conversionSourcePixel, nil, window)
+		}
+
+		w.Header().Set("Content-Type", "image/gif")
+		w.Header().Set("Cache-Control", noCacheControl)
+		w.Write(transparentGIF)
+	}
+}
+
+// ConversionStatsResponse summarizes the conversions of a URL
+type ConversionStatsResponse struct {
+	ShortID         string `json:"short_id" example:"abc123"`
+	Clicks          int64  `json:"clicks" example:"1520"`
+	Conversions     int64  `json:"conversions" example:"61"`
+	ConvertedClicks int64  `json:"converted_clicks" example:"57"`
+	// ConversionRate is the percentage of clicks that converted
+	ConversionRate *float64           `json:"conversion_rate" example:"3.75"`
+	Revenue        map[string]float64 `json:"revenue"`
+	Events         map[string]int64   `json:"events"`
+}
+
+// conversionRate returns the percentage of clicks that converted, rounded to
+// two decimals, or nil without clicks
+func conversionRate(converted, clicks int64) *float64 {
+	if clicks == 0 {
+		return nil
+	}
+	rate := math.Round(float64(converted)/float64(clicks)*10000) / 100
+	return &rate
+}
+
+// GetConversionStats gets conversion analytics for a URL
+// @Summary Get Conversion Analytics
+// @Description Get the conversions of one of your URLs: how many were recorded, how many clicks converted, the conversion rate against the total click count, revenue per currency and conversions per event.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Param shortID path string true "Short URL ID"
+// @Produce json
+// @Success 200 {object} ConversionStatsResponse "Conversion analytics"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/analytics/{shortID}/conversions [get]
+func GetConversionStats(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		totals, err := db.GetConversionTotals(r.Context(), link.ShortID)
+		if err != nil {
+			serverError(w, r, "Failed to fetch conversions", err)
+			return
+		}
+		revenue, err := db.ListConversionRevenue(r.Context(), link.ShortID)
+		if err != nil {
+			serverError(w, r, "Failed to fetch conversions", err)
+			return
+		}
+		events, err := db.ListConversionEvents(r.Context(), link.ShortID)
+		if err != nil {
+			serverError(w, r, "Failed to fetch conversions", err)
+			return
+		}
+		counts, err := counters.Get(r.Context(), redisClient, db, []string{link.ShortID})
+		if err != nil {
+			serverError(w, r, "Failed to fetch click counts", err)
+			return
+		}
+
+		clicks := counts[link.ShortID].Clicks
+		response := ConversionStatsResponse{
+			ShortID:         link.ShortID,
+			Clicks:          clicks,
+			Conversions:     totals.Conversions,
+			ConvertedClicks: totals.ConvertedClicks,
+			ConversionRate:  conversionRate(totals.ConvertedClicks, clicks),
+			Revenue:         map[string]float64{},
+			Events:          map[string]int64{},
+		}
+		for _, row := range revenue {
+			response.Revenue[row.Currency] = row.Revenue
+		}
+		for _, row := range events {
+			response.Events[row.Event] = row.Conversions
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/useragent"
+)
+
+// appOpenTimeout is how long the deep-link page waits for the app to take
+// over before sending the visitor to the web destination.
+const appOpenTimeout = 1500
+
+// AppLinksOptions describes the mobile apps that may handle links of the
+// service domain.
+type AppLinksOptions struct {
+	// AppleAppIDs are the "TEAMID.bundle.id" identifiers of the iOS apps
+	AppleAppIDs []string
+	// ApplePaths are the apple-app-site-association path patterns
+	ApplePaths []string
+	// AndroidPackage is the package name of the Android app
+	AndroidPackage string
+	// AndroidFingerprints are the SHA-256 fingerprints of the app's signing
+	// certificates
+	AndroidFingerprints []string
+}
+
+var deepLinkTemplate = template.Must(template.New("deeplink").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Opening app…</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 24rem; margin: 4rem auto; padding: 0 1rem; color: #222; text-align: center; }
+a { display: block; margin-top: 1rem; }
+</style>
+<script>
+window.location.href = {{.AppURL}};
+setTimeout(function () {
+	if (!document.hidden) {
+		window.location.replace({{.Fallback}});
+	}
+}, {{.Timeout}});
+</script>
+</head>
+<body>
+<p>Opening the app…</p>
+<a href="{{.AppURL}}">Open in app</a>
+<a href="{{.Fallback}}">Continue to website</a>
+</body>
+</html>
+`))
+
+// ValidAppURL reports whether u can be used as a deep link: a custom scheme
+// URL, an Android intent URL, or a universal/app link on the web.
+func ValidAppURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Scheme == "" {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "javascript", "data", "vbscript", "file":
+		return false
+	}
+	return true
+}
+
+// isWebURL reports whether a deep link is a universal/app link, which the OS
+// hands to the app itself when it is installed.
+func isWebURL(u string) bool {
+	lower := strings.ToLower(u)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// appURLFor returns the deep link configured for the visitor's platform
+func appURLFor(r *http.Request, entry cachedURL) string {
+	switch useragent.Parse(r.UserAgent()).OS {
+	case useragent.OSIOS:
+		return entry.IOSAppURL
+	case useragent.OSAndroid:
+		return entry.AndroidAppURL
+	}
+	return ""
+}
+
+// withIntentFallback adds the web destination to an Android intent URL so
+// Chrome opens it itself when the app is not installed.
+func withIntentFallback(appURL, fallback string) string {
+	if !strings.HasPrefix(appURL, "intent:") || strings.Contains(appURL, "S.browser_fallback_url=") {
+		return appURL
+	}
+	before, ok := strings.CutSuffix(appURL, "end")
+	if !ok {
+		return appURL
+	}
+	if !strings.HasSuffix(before, ";") {
+		before += ";"
+	}
+	return before + "S.browser_fallback_url=" + url.QueryEscape(fallback) + ";end"
+}
+
+// serveDeepLink renders the page that tries to open the app and falls back to
+// the web destination when nothing takes over.
+func serveDeepLink(w http.ResponseWriter, appURL, fallback string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", noCacheControl)
+	deepLinkTemplate.Execute(w, map[string]interface{}{
+		// The app URL was validated when the link was saved; custom schemes
+		// would otherwise be replaced by html/template
+		"AppURL":   template.URL(withIntentFallback(appURL, fallback)),
+		"Fallback": fallback,
+		"Timeout":  appOpenTimeout,
+	})
+}
+
+// AppleAppSiteAssociation serves the apple-app-site-association file
+// @Summary Apple App Site Association
+// @Description Lets the configured iOS apps open short links as universal links. Returns 404 when no iOS app is configured.
+// @Tags deep links
+// @Produce json
+// @Success 200 {object} map[string]interface{} "apple-app-site-association file"
+// @Failure 404 {object} problem.Problem "No iOS app configured"
+// @Router /.well-known/apple-app-site-association [get]
+func AppleAppSiteAssociation(opts AppLinksOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(opts.AppleAppIDs) == 0 {
+			problem.Error(w, "No iOS app configured", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"applinks": map[string]interface{}{
+				"apps": []string{},
+				"details": []map[string]interface{}{
+					{"appIDs": opts.AppleAppIDs, "paths": opts.ApplePaths},
+				},
+			},
+		})
+	}
+}
+
+// AssetLinks serves the Android Digital Asset Links file
+// @Summary Android Asset Links
+// @Description Lets the configured Android app open short links as verified app links. Returns 404 when no Android app is configured.
+// @Tags deep links
+// @Produce json
+// @Success 200 {array} map[string]interface{} "assetlinks.json file"
+// @Failure 404 {object} problem.Problem "No Android app configured"
+// @Router /.well-known/assetlinks.json [get]
+func AssetLinks(opts AppLinksOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if opts.AndroidPackage == "" {
+			problem.Error(w, "No Android app configured", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{
+				"relation": []string{"delegate_permission/common.handle_all_urls"},
+				"target": map[string]interface{}{
+					"namespace":                "android_app",
+					"package_name":             opts.AndroidPackage,
+					"sha256_cert_fingerprints": opts.AndroidFingerprints,
+				},
+			},
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/logging"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// serverError logs err with the request's logger and answers 500 with
+// message, keeping the cause out of the response.
+func serverError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	logging.FromContext(r.Context()).Error(message, "error", err)
+	problem.Error(w, message, http.StatusInternalServerError)
+}
+
+// dbError answers a failed database call with the status of its domain
+// error: 404 for missing rows, 403 for other users' resources, 409 for
+// conflicts and 422 for invalid values. Anything else is a server error
+// answered with message.
+func dbError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	var domainErr *sqlc.Error
+	if !errors.As(sqlc.MapError(err), &domainErr) {
+		serverError(w, r, message, err)
+		return
+	}
+
+	switch domainErr.Kind {
+	case sqlc.ErrNotFound:
+		problem.Error(w, domainErr.Detail, http.StatusNotFound)
+	case sqlc.ErrForbidden:
+		problem.Error(w, domainErr.Detail, http.StatusForbidden)
+	case sqlc.ErrConflict:
+		problem.Error(w, domainErr.Detail, http.StatusConflict)
+	default:
+		problem.Error(w, domainErr.Detail, http.StatusUnprocessableEntity)
+	}
+}
+
+// rowError is dbError for calls on a single row, answering 404 with notFound
+// when the row does not exist
+func rowError(w http.ResponseWriter, r *http.Request, notFound, message string, err error) {
+	if errors.Is(sqlc.MapError(err), sqlc.ErrNotFound) {
+		problem.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+	dbError(w, r, message, err)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/export"
+	"github.com/yeboahd24/url-shortener/logging"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// parseExportRange reads the optional from and to query parameters
+func parseExportRange(r *http.Request) (from, to *time.Time, err error) {
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s must be an RFC 3339 time", p.name)
+		}
+		t = t.UTC()
+		*p.dst = &t
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+// writeExport streams the clicks matching filter in the requested format.
+// Once the response has started, errors can only cut it short, so they are
+// logged instead.
+func writeExport(w http.ResponseWriter, r *http.Request, db *sqlc.Queries, filter export.Filter, name string) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	contentType := export.ContentType(format)
+	if contentType == "" {
+		problem.Error(w, "format must be csv, ndjson or parquet", http.StatusBadRequest)
+		return
+	}
+
+	columns, err := export.ParseColumns(r.URL.Query().Get("columns"))
+	if err != nil {
+		problem.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter.From, filter.To, err = parseExportRange(r)
+	if err != nil {
+		problem.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	writer, err := export.NewWriter(format, w, columns)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to start click export", "error", err)
+		return
+	}
+	written, err := export.Stream(r.Context(), db, filter, writer)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Click export failed", "error", err, "clicks", written)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to finish click export", "error", err)
+	}
+}
+
+// ExportClicks exports the raw clicks of a URL
+// @Summary Export URL Clicks
+// @Description Download the raw clicks of one of your URLs as CSV, newline-delimited JSON or Parquet. The file is streamed while clicks are read, oldest first, so exports of any size start immediately. A failure part way through ends the download early; a Parquet file cut short has no footer and cannot be read.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Param shortID path string true "Short URL ID"
+// @Param format query string false "File format" Enums(csv, ndjson, parquet) default(csv)
+// @Param columns query string false "Comma-separated columns to include, in order: id, short_id, clicked_at, ip_address, user_agent, country, location, device, referrer, source, variant_id, visitor_hash, click_id (default all)"
+// @Param from query string false "Only clicks at or after this RFC 3339 time"
+// @Param to query string false "Only clicks before this RFC 3339 time"
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.apache.parquet
+// @Success 200 {file} file "Click export"
+// @Failure 400 {object} problem.Problem "Invalid format, columns or time range"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Router /api/analytics/{shortID}/export [get]
+func ExportClicks(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		filter := export.Filter{UserID: link.UserID.Bytes, ShortID: link.ShortID}
+		writeExport(w, r, db, filter, "clicks-"+link.ShortID)
+	}
+}
+
+// ExportAllClicks exports the raw clicks of every URL of the user
+// @Summary Export All Clicks
+// @Description Download the raw clicks of all your URLs as CSV, newline-delimited JSON or Parquet, streamed like the export of a single URL. Use the short_id column to tell links apart.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Param format query string false "File format" Enums(csv, ndjson, parquet) default(csv)
+// @Param columns query string false "Comma-separated columns to include, in order: id, short_id, clicked_at, ip_address, user_agent, country, location, device, referrer, source, variant_id, visitor_hash, click_id (default all)"
+// @Param from query string false "Only clicks at or after this RFC 3339 time"
+// @Param to query string false "Only clicks before this RFC 3339 time"
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.apache.parquet
+// @Success 200 {file} file "Click export"
+// @Failure 400 {object} problem.Problem "Invalid format, columns or time range"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Router /api/analytics/export [get]
+func ExportAllClicks(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		writeExport(w, r, db, export.Filter{UserID: userID}, "clicks")
+	}
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// FolderRequest represents the request body for creating or renaming a folder
+type FolderRequest struct {
+	Name string `json:"name" example:"Spring Campaign" binding:"required"`
+}
+
+// FolderInfo represents folder information
+type FolderInfo struct {
+	FolderID  string    `json:"folder_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name      string    `json:"name" example:"Spring Campaign"`
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// ListFoldersResponse represents the response for listing folders
+type ListFoldersResponse struct {
+	Folders []FolderInfo `json:"folders"`
+}
+
+func folderToInfo(folder sqlc.Folder) FolderInfo {
+	return FolderInfo{
+		FolderID:  folder.FolderID.String(),
+		Name:      folder.Name,
+		CreatedAt: folder.CreatedAt.Time,
+	}
+}
+
+// resolveFolderID parses a folder ID supplied by the client and checks that
+// the folder belongs to the user. An empty string resolves to no folder.
+// Malformed IDs and folders the user does not have fail with
+// sqlc.ErrNotFound; other errors come from the database.
+func resolveFolderID(ctx context.Context, db *sqlc.Queries, userID uuid.UUID, folderIDStr string) (pgtype.UUID, error) {
+	if folderIDStr == "" {
+		return pgtype.UUID{Valid: false}, nil
+	}
+
+	folderID, err := uuid.Parse(folderIDStr)
+	if err != nil {
+		return pgtype.UUID{Valid: false}, &sqlc.Error{Kind: sqlc.ErrNotFound, Detail: "Folder not found", Err: err}
+	}
+
+	folder, err := db.GetFolder(ctx, sqlc.GetFolderParams{FolderID: folderID, UserID: userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.UUID{Valid: false}, &sqlc.Error{Kind: sqlc.ErrNotFound, Detail: "Folder not found", Err: err}
+	}
+	if err != nil {
+		return pgtype.UUID{Valid: false}, err
+	}
+	return sqlc.UUIDToNullable(&folder.FolderID), nil
+}
+
+// CreateFolder creates a folder for the authenticated user
+// @Summary Create Folder
+// @Description Create a folder for organizing URLs
+// @Tags folders
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param folder body FolderRequest true "Folder to create"
+// @Success 200 {object} FolderInfo "Folder created successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 409 {object} problem.Problem "A folder with this name already exists"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/folders [post]
+func CreateFolder(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var input FolderRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		name := strings.TrimSpace(input.Name)
+		if name == "" {
+			problem.Error(w, "Folder name is required", http.StatusBadRequest)
+			return
+		}
+
+		folder, err := db.CreateFolder(r.Context(), sqlc.CreateFolderParams{
+			FolderID:  uuid.New(),
+			UserID:    userID,
+			Name:      name,
+			CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			dbError(w, r, "Failed to create folder", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(folderToInfo(folder))
+	}
+}
+
+// ListFolders lists all folders for the authenticated user
+// @Summary List Folders
+// @Description List all folders created by the authenticated user
+// @Tags folders
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} ListFoldersResponse "Folders retrieved successfully"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/folders [get]
+func ListFolders(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		folders, err := db.ListUserFolders(r.Context(), userID)
+		if err != nil {
+			serverError(w, r, "Failed to fetch folders", err)
+			return
+		}
+
+		response := ListFoldersResponse{Folders: []FolderInfo{}}
+		for _, folder := range folders {
+			response.Folders = append(response.Folders, folderToInfo(folder))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UpdateFolder renames a folder for the authenticated user
+// @Summary Update Folder
+// @Description Rename a folder owned by the authenticated user
+// @Tags folders
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param folderID path string true "Folder ID"
+// @Param folder body FolderRequest true "New folder name"
+// @Success 200 {object} FolderInfo "Folder updated successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Folder not found"
+// @Failure 409 {object} problem.Problem "A folder with this name already exists"
+// @Router /api/folders/{folderID} [put]
+func UpdateFolder(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+		if err != nil {
+			problem.Error(w, "Invalid folder ID", http.StatusBadRequest)
+			return
+		}
+
+		var input FolderRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		name := strings.TrimSpace(input.Name)
+		if name == "" {
+			problem.Error(w, "Folder name is required", http.StatusBadRequest)
+			return
+		}
+
+		folder, err := db.UpdateFolder(r.Context(), sqlc.UpdateFolderParams{
+			FolderID: folderID,
+			UserID:   userID,
+			Name:     name,
+		})
+		if err != nil {
+			rowError(w, r, "Folder not found", "Failed to update folder", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(folderToInfo(folder))
+	}
+}
+
+// DeleteFolder deletes a folder for the authenticated user
+// @Summary Delete Folder
+// @Description Delete a folder owned by the authenticated user. URLs in the folder are kept and become unfiled.
+// @Tags folders
+// @Security ApiKeyAuth
+// @Param folderID path string true "Folder ID"
+// @Produce json
+// @Success 200 {object} map[string]string "Folder deleted successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Folder not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/folders/{folderID} [delete]
+func DeleteFolder(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+		if err != nil {
+			problem.Error(w, "Invalid folder ID", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := db.DeleteFolder(r.Context(), sqlc.DeleteFolderParams{
+			FolderID: folderID,
+			UserID:   userID,
+		})
+		if err != nil {
+			serverError(w, r, "Failed to delete folder", err)
+			return
+		}
+		if deleted == 0 {
+			problem.Error(w, "Folder not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Folder deleted successfully",
+		})
+	}
+}
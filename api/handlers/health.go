@@ -6,7 +6,7 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	"github.com/yeboahd24/url-shortener/queries/sqlc"
 )
@@ -19,7 +19,7 @@ import (
 // @Success 200 {object} map[string]interface{} "Service is healthy"
 // @Failure 503 {object} map[string]interface{} "Service is unhealthy"
 // @Router /health [get]
-func HealthCheck(db *pgx.Conn, redisClient *redis.Client) http.HandlerFunc {
+func HealthCheck(db *pgxpool.Pool, redisClient *redis.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
@@ -64,7 +64,7 @@ func HealthCheck(db *pgx.Conn, redisClient *redis.Client) http.HandlerFunc {
 // @Tags statistics
 // @Produce json
 // @Success 200 {object} map[string]interface{} "Global statistics"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /stats [get]
 func GetStats(db *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -73,21 +73,21 @@ func GetStats(db *sqlc.Queries) http.HandlerFunc {
 		// Get total URLs
 		totalURLs, err := db.GetTotalURLs(ctx)
 		if err != nil {
-			http.Error(w, "Failed to get URL statistics", http.StatusInternalServerError)
+			serverError(w, r, "Failed to get URL statistics", err)
 			return
 		}
 
 		// Get total clicks
 		totalClicks, err := db.GetTotalClicks(ctx)
 		if err != nil {
-			http.Error(w, "Failed to get click statistics", http.StatusInternalServerError)
+			serverError(w, r, "Failed to get click statistics", err)
 			return
 		}
 
 		// Get total users
 		totalUsers, err := db.GetTotalUsers(ctx)
 		if err != nil {
-			http.Error(w, "Failed to get user statistics", http.StatusInternalServerError)
+			serverError(w, r, "Failed to get user statistics", err)
 			return
 		}
 
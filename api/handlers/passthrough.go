@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Query modes control how the query string of a short link visit is forwarded
+// to the destination. Links without a query mode drop it.
+const (
+	// QueryModeMerge adds visit parameters the destination does not set
+	QueryModeMerge = "merge"
+	// QueryModeOverride adds visit parameters, replacing those of the destination
+	QueryModeOverride = "override"
+)
+
+// Destination placeholders:
+//
+//	{short_id}    the short ID of the link
+//	{path}        the path suffix after /{shortID}/
+//	{query}       the raw query string of the visit
+//	{param:name}  the value of one query parameter of the visit
+const pathPlaceholder = "{path}"
+
+var paramPlaceholder = regexp.MustCompile(`\{param:([A-Za-z0-9_.\-]+)\}`)
+
+// ValidQueryMode reports whether m is a supported query mode
+func ValidQueryMode(m string) bool {
+	return m == QueryModeMerge || m == QueryModeOverride
+}
+
+// pathSuffix returns the escaped path a visitor appended to the short link,
+// e.g. "docs/intro" for /{shortID}/docs/intro.
+func pathSuffix(r *http.Request, shortID string) string {
+	suffix, ok := strings.CutPrefix(r.URL.EscapedPath(), "/"+url.PathEscape(shortID)+"/")
+	if !ok {
+		return ""
+	}
+	return suffix
+}
+
+// cleanSuffix cleans a path suffix as a rooted path, so ".." cannot climb
+// above the destination path and leading slashes cannot start a new host.
+// The result has no leading slash.
+func cleanSuffix(suffix string) string {
+	return strings.TrimPrefix(path.Clean("/"+suffix), "/")
+}
+
+// validPathTemplate reports whether every {path} placeholder of a destination
+// sits in its path right after a slash, where a cleaned suffix can only add
+// path segments.
+func validPathTemplate(u string) bool {
+	count := strings.Count(u, pathPlaceholder)
+	if count == 0 {
+		return true
+	}
+
+	parsed, err := url.Parse(paramPlaceholder.ReplaceAllString(u, "x"))
+	if err != nil || strings.Count(parsed.Path, pathPlaceholder) != count {
+		return false
+	}
+	return strings.Count(parsed.Path, "/"+pathPlaceholder) == count
+}
+
+// acceptsPathSuffix reports whether a link takes a path suffix, either by
+// forwarding it or through a {path} placeholder in its destination.
+func acceptsPathSuffix(entry cachedURL, target string) bool {
+	return entry.ForwardPath || strings.Contains(target, pathPlaceholder)
+}
+
+// expandDestination fills in the placeholders of a templated destination.
+// Values taken from the visit are escaped for the part of the URL they are
+// substituted into; the path suffix is cleaned.
+func expandDestination(target string, r *http.Request, shortID, suffix string) string {
+	if !strings.Contains(target, "{") {
+		return target
+	}
+
+	query := r.URL.Query()
+	target = paramPlaceholder.ReplaceAllStringFunc(target, func(m string) string {
+		name := paramPlaceholder.FindStringSubmatch(m)[1]
+		return url.QueryEscape(query.Get(name))
+	})
+
+	return strings.NewReplacer(
+		"{short_id}", url.PathEscape(shortID),
+		pathPlaceholder, cleanSuffix(suffix),
+		"{query}", r.URL.RawQuery,
+	).Replace(target)
+}
+
+// buildDestination turns the resolved destination of a link into the URL the
+// visitor is sent to, applying placeholders and path and query forwarding.
+// It returns "" when the path suffix would change the destination host.
+func buildDestination(target string, r *http.Request, shortID string, entry cachedURL) string {
+	suffix := pathSuffix(r, shortID)
+	templatedPath := strings.Contains(target, pathPlaceholder)
+	expanded := expandDestination(target, r, shortID, suffix)
+	// The suffix may only add to the path, never change the host
+	if templatedPath && suffix != "" && !sameHost(expanded, expandDestination(target, r, shortID, "")) {
+		return ""
+	}
+	target = expanded
+
+	forwardPath := entry.ForwardPath && !templatedPath && suffix != ""
+	forwardQuery := ValidQueryMode(entry.QueryMode) && r.URL.RawQuery != ""
+	if !forwardPath && !forwardQuery {
+		return target
+	}
+
+	dest, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	if forwardPath {
+		dest = dest.JoinPath(cleanSuffix(suffix))
+	}
+
+	if forwardQuery {
+		params := dest.Query()
+		for key, values := range r.URL.Query() {
+			if entry.QueryMode == QueryModeMerge && params.Has(key) {
+				continue
+			}
+			params[key] = values
+		}
+		dest.RawQuery = params.Encode()
+	}
+
+	return dest.String()
+}
+
+// sameHost reports whether two URLs parse and point at the same host
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	return err == nil && ua.Host == ub.Host
+}
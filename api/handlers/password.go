@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	unlockCookiePrefix  = "unlock_"
+	maxUnlockAttempts   = 5
+	unlockAttemptWindow = 15 * time.Minute
+)
+
+var unlockTemplate = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 24rem; margin: 4rem auto; padding: 0 1rem; color: #222; }
+input, button { font-size: 1rem; padding: .5rem; width: 100%; box-sizing: border-box; margin-top: .5rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>Password required</h1>
+<p>This link is protected. Enter the password to continue.</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/{{.ShortID}}">
+<input type="password" name="password" autocomplete="current-password" autofocus required>
+<button type="submit">Unlock</button>
+</form>
+</body>
+</html>
+`))
+
+// maxLinkPasswordLength is the longest password bcrypt accepts, in bytes
+const maxLinkPasswordLength = 72
+
+// validLinkPassword reports whether a link password can be hashed
+func validLinkPassword(password string) bool {
+	return len(password) <= maxLinkPasswordLength
+}
+
+// hashLinkPassword hashes a link password for storage. An empty password
+// removes the protection.
+func hashLinkPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// unlockSignature signs a short ID and expiry. The password hash is part of
+// the signed message so that changing the password revokes issued cookies.
+func unlockSignature(secret []byte, shortID, passwordHash string, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(shortID + "|" + passwordHash + "|" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// setUnlockCookie issues the signed cookie that lets a visitor skip the
+// password prompt until it expires.
+func setUnlockCookie(w http.ResponseWriter, r *http.Request, shortID, passwordHash string, opts RedirectOptions) {
+	expires := time.Now().Add(opts.UnlockTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     unlockCookiePrefix + shortID,
+		Value:    strconv.FormatInt(expires.Unix(), 10) + "." + unlockSignature(opts.CookieSecret, shortID, passwordHash, expires.Unix()),
+		Path:     "/" + shortID,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// isUnlocked reports whether the request carries a valid unlock cookie
+func isUnlocked(r *http.Request, shortID, passwordHash string, opts RedirectOptions) bool {
+	cookie, err := r.Cookie(unlockCookiePrefix + shortID)
+	if err != nil {
+		return false
+	}
+
+	expiresStr, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	expected := unlockSignature(opts.CookieSecret, shortID, passwordHash, expires)
+	return hmac.Equal([]byte(sig), []byte(expected))
+}
+
+func renderUnlockForm(w http.ResponseWriter, status int, shortID, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", noCacheControl)
+	w.WriteHeader(status)
+	unlockTemplate.Execute(w, map[string]string{"ShortID": shortID, "Error": message})
+}
+
+// UnlockURL verifies the password of a protected short link
+// @Summary Unlock Password-Protected URL
+// @Description Verify the password submitted from the unlock form. On success a signed, short-lived cookie is set and the visitor is sent back to the short link. Attempts are limited per IP address.
+// @Tags urls
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param shortID path string true "Short URL ID"
+// @Param password formData string true "Link password"
+// @Success 303 "Password accepted, redirect to the short link"
+// @Failure 401 "Wrong password, unlock form shown again"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 429 "Too many attempts"
+// @Router /{shortID} [post]
+func UnlockURL(db *sqlc.Queries, redisClient *redis.Client, opts RedirectOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortID := chi.URLParam(r, "shortID")
+		ctx := r.Context()
+
+		url, err := db.GetURL(ctx, shortID)
+		if err != nil {
+			rowError(w, r, "URL not found", "Failed to fetch URL", err)
+			return
+		}
+
+		if !url.PasswordHash.Valid {
+			http.Redirect(w, r, "/"+shortID, http.StatusSeeOther)
+			return
+		}
+
+		attemptsKey := "unlock:" + shortID + ":" + clientIP(r)
+		attempts, _ := redisClient.Incr(ctx, attemptsKey).Result()
+		if attempts == 1 {
+			redisClient.Expire(ctx, attemptsKey, unlockAttemptWindow)
+		}
+		if attempts > maxUnlockAttempts {
+			renderUnlockForm(w, http.StatusTooManyRequests, shortID, "Too many attempts. Please try again later.")
+			return
+		}
+
+		password := r.PostFormValue("password")
+		if bcrypt.CompareHashAndPassword([]byte(url.PasswordHash.String), []byte(password)) != nil {
+			renderUnlockForm(w, http.StatusUnauthorized, shortID, "Incorrect password.")
+			return
+		}
+
+		redisClient.Del(ctx, attemptsKey)
+		setUnlockCookie(w, r, shortID, url.PasswordHash.String, opts)
+		http.Redirect(w, r, "/"+shortID, http.StatusSeeOther)
+	}
+}
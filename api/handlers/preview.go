@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// Link status values reported by the preview
+const (
+	linkStatusScheduled    = "scheduled"
+	linkStatusActive       = "active"
+	linkStatusExpired      = "expired"
+	linkStatusLimitReached = "limit_reached"
+)
+
+// SafetyInfo describes basic checks run against a destination URL
+type SafetyInfo struct {
+	Safe     bool     `json:"safe" example:"true"`
+	Warnings []string `json:"warnings"`
+}
+
+// PreviewResponse represents the JSON preview of a short link
+type PreviewResponse struct {
+	ShortID     string     `json:"short_id" example:"abc123"`
+	LongURL     string     `json:"long_url,omitempty" example:"https://example.com"`
+	Title       string     `json:"title,omitempty" example:"Example Domain"`
+	Description string     `json:"description,omitempty" example:"Landing page for the spring campaign"`
+	FaviconURL  string     `json:"favicon_url,omitempty" example:"https://example.com/favicon.ico"`
+	CreatedAt   time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	ActivatesAt *time.Time `json:"activates_at,omitempty" example:"2024-03-01T09:00:00Z"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	Status      string     `json:"status" example:"active" enums:"scheduled,active,expired,limit_reached"`
+	Safety      SafetyInfo `json:"safety"`
+	Protected   bool       `json:"password_protected" example:"false"`
+}
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Preview of /{{.ShortID}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 40rem; margin: 3rem auto; padding: 0 1rem; color: #222; }
+.card { border: 1px solid #ddd; border-radius: 8px; padding: 1.5rem; }
+.url { word-break: break-all; font-family: monospace; }
+.warn { color: #a15c00; }
+.bad { color: #b00020; }
+.ok { color: #1b7f3b; }
+a.button { display: inline-block; margin-top: 1rem; padding: .6rem 1.2rem; background: #2457d6; color: #fff; border-radius: 6px; text-decoration: none; }
+</style>
+</head>
+<body>
+<div class="card">
+{{if .FaviconURL}}<img src="{{.FaviconURL}}" alt="" width="16" height="16"> {{end}}<strong>{{if .Title}}{{.Title}}{{else}}Untitled link{{end}}</strong>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Protected}}<p>This short link is password protected, so its destination is hidden.</p>{{else}}<p>This short link goes to:</p>
+<p class="url">{{.LongURL}}</p>{{end}}
+<p>Created {{.CreatedAt.Format "January 2, 2006"}}{{if .ActivatesAt}}, goes live {{.ActivatesAt.Format "January 2, 2006 15:04 MST"}}{{end}}{{if .ExpiresAt}}, expires {{.ExpiresAt.Format "January 2, 2006 15:04 MST"}}{{end}}</p>
+{{if eq .Status "active"}}<p class="ok">This link is active.</p>{{else if eq .Status "scheduled"}}<p class="bad">This link is not active yet.</p>{{else if eq .Status "expired"}}<p class="bad">This link has expired.</p>{{else}}<p class="bad">This link has reached its click limit.</p>{{end}}
+{{if not .Protected}}{{if .Safety.Safe}}<p class="ok">No problems were found with the destination.</p>{{else}}<ul class="warn">{{range .Safety.Warnings}}<li>{{.}}</li>{{end}}</ul>{{end}}{{end}}
+{{if eq .Status "active"}}<a class="button" href="/{{.ShortID}}" rel="nofollow">Continue to destination</a>{{end}}
+</div>
+</body>
+</html>
+`))
+
+// checkDestinationSafety runs simple heuristics against a destination URL
+// that flag common signs of phishing or insecure links.
+func checkDestinationSafety(longURL string) SafetyInfo {
+	warnings := []string{}
+
+	u, err := url.Parse(longURL)
+	if err != nil || u.Host == "" {
+		return SafetyInfo{Safe: false, Warnings: []string{"Destination is not a valid URL"}}
+	}
+
+	if u.Scheme != "https" {
+		warnings = append(warnings, "Destination does not use HTTPS")
+	}
+	if u.User != nil {
+		warnings = append(warnings, "Destination contains embedded credentials")
+	}
+	if net.ParseIP(u.Hostname()) != nil {
+		warnings = append(warnings, "Destination is a raw IP address")
+	}
+	for _, label := range strings.Split(u.Hostname(), ".") {
+		if strings.HasPrefix(label, "xn--") {
+			warnings = append(warnings, "Destination uses an internationalized domain that may imitate another site")
+			break
+		}
+	}
+
+	return SafetyInfo{Safe: len(warnings) == 0, Warnings: warnings}
+}
+
+// linkStatus reports whether a URL is still usable
+func linkStatus(link sqlc.Url) string {
+	if link.ActivatesAt.Valid && link.ActivatesAt.Time.After(time.Now()) {
+		return linkStatusScheduled
+	}
+
+	if link.ExpiresAt.Valid && link.ExpiresAt.Time.Before(time.Now()) {
+		return linkStatusExpired
+	}
+
+	if link.ClickLimit.Valid && link.TotalClicks >= int64(link.ClickLimit.Int32) {
+		return linkStatusLimitReached
+	}
+	return linkStatusActive
+}
+
+// wantsJSON reports whether the client asked for the JSON variant
+func wantsJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// servePreview writes the HTML or JSON preview for a short link
+func servePreview(w http.ResponseWriter, r *http.Request, db *sqlc.Queries, shortID string) {
+	link, err := db.GetURL(r.Context(), shortID)
+	if err != nil {
+		rowError(w, r, "URL not found", "Failed to fetch URL", err)
+		return
+	}
+
+	preview := PreviewResponse{
+		ShortID:     link.ShortID,
+		LongURL:     link.LongUrl,
+		Title:       link.Title.String,
+		Description: link.Description.String,
+		FaviconURL:  link.FaviconUrl.String,
+		CreatedAt:   link.CreatedAt.Time,
+		Status:      linkStatus(link),
+		Safety:      checkDestinationSafety(link.LongUrl),
+	}
+	if link.ActivatesAt.Valid {
+		preview.ActivatesAt = &link.ActivatesAt.Time
+	}
+	if link.ExpiresAt.Valid {
+		preview.ExpiresAt = &link.ExpiresAt.Time
+	}
+
+	// Don't reveal where a password-protected link goes
+	if link.PasswordHash.Valid {
+		preview.LongURL = ""
+		preview.Safety = SafetyInfo{Warnings: []string{}}
+		preview.Protected = true
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(preview)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	previewTemplate.Execute(w, preview)
+}
+
+// PreviewURL shows where a short link goes without following it
+// @Summary Preview Short URL
+// @Description Show the destination, title, creation date and safety status of a short link without redirecting. Returns JSON when format=json is set or the Accept header asks for application/json. The same preview is available as GET /{shortID}?preview=1.
+// @Tags urls
+// @Param shortID path string true "Short URL ID"
+// @Param format query string false "Set to json for the JSON variant"
+// @Produce html
+// @Produce json
+// @Success 200 {object} PreviewResponse "Link preview"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Router /{shortID}+ [get]
+func PreviewURL(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		servePreview(w, r, db, chi.URLParam(r, "shortID"))
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"image"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/qr"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+const (
+	// SourceQR is the click source of visits from a scanned QR code
+	SourceQR = "qr"
+	// sourceParam is the query parameter QR codes add to the short link
+	sourceParam = "src"
+)
+
+// QROptions holds the service-wide QR code settings
+type QROptions struct {
+	// BaseURL is the public address short links are encoded with. When empty
+	// it is derived from the request.
+	BaseURL string
+	// Logo is the image drawn in the center of codes requested with logo=true
+	Logo image.Image
+}
+
+// baseURL returns the public address of the service
+func baseURL(r *http.Request, configured string) string {
+	if configured != "" {
+		return strings.TrimSuffix(configured, "/")
+	}
+	scheme := "http"
+	if isHTTPS(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// clickSource returns the attribution source of a visit and removes the
+// source parameter from the request so it is not forwarded to the destination.
+func clickSource(r *http.Request) string {
+	query := r.URL.Query()
+	if query.Get(sourceParam) != SourceQR {
+		return ""
+	}
+	query.Del(sourceParam)
+	r.URL.RawQuery = query.Encode()
+	return SourceQR
+}
+
+// parseQROptions reads the rendering options of a QR code request
+func parseQROptions(r *http.Request, opts QROptions) (qr.Options, error) {
+	query := r.URL.Query()
+	options := qr.DefaultOptions()
+
+	if v := query.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < qr.MinSize || size > qr.MaxSize {
+			return options, errors.New("size must be between 64 and 2048")
+		}
+		options.Size = size
+	}
+
+	if v := query.Get("level"); v != "" {
+		switch strings.ToUpper(v) {
+		case "L", "M", "Q", "H":
+			options.Level = strings.ToUpper(v)
+		default:
+			return options, errors.New("level must be L, M, Q or H")
+		}
+	}
+
+	if v := query.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > qr.MaxMargin {
+			return options, errors.New("margin must be between 0 and 16")
+		}
+		options.Margin = margin
+	}
+
+	if v := query.Get("fg"); v != "" {
+		c, err := qr.ParseColor(v)
+		if err != nil {
+			return options, errors.New("fg must be a hex color")
+		}
+		options.Foreground = c
+	}
+
+	if v := query.Get("bg"); v != "" {
+		c, err := qr.ParseColor(v)
+		if err != nil {
+			return options, errors.New("bg must be a hex color")
+		}
+		options.Background = c
+	}
+
+	if logo, _ := strconv.ParseBool(query.Get("logo")); logo {
+		if opts.Logo == nil {
+			return options, errors.New("no logo is configured")
+		}
+		options.Logo = opts.Logo
+	}
+
+	return options, nil
+}
+
+// writeQRCode renders the QR code of a short link in the requested format
+func writeQRCode(w http.ResponseWriter, r *http.Request, shortID string, opts QROptions) {
+	options, err := parseQROptions(r, opts)
+	if err != nil {
+		problem.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content := baseURL(r, opts.BaseURL) + "/" + url.PathEscape(shortID) + "?" + sourceParam + "=" + SourceQR
+
+	var (
+		data        []byte
+		contentType string
+	)
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "png":
+		data, err = qr.PNG(content, options)
+		contentType = "image/png"
+	case "svg":
+		data, err = qr.SVG(content, options)
+		contentType = "image/svg+xml"
+	default:
+		problem.Error(w, "format must be png or svg", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to generate QR code", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	// Codes derived from the Host header must not reach shared caches, where
+	// a spoofed host would be served to everyone
+	if opts.BaseURL != "" {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	} else {
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+	}
+	w.Write(data)
+}
+
+// GetQRCode serves the QR code of a short link
+// @Summary Get QR Code
+// @Description Render a QR code for a short link as PNG or SVG. The code encodes the short link with src=qr so that scans are reported separately in click analytics.
+// @Tags qr
+// @Produce png
+// @Produce image/svg+xml
+// @Param shortID path string true "Short URL ID"
+// @Param format query string false "Image format" Enums(png, svg) default(png)
+// @Param size query int false "Width and height in pixels (64-2048)" default(256)
+// @Param level query string false "Error correction level" Enums(L, M, Q, H) default(M)
+// @Param margin query int false "Quiet zone in modules (0-16)" default(4)
+// @Param fg query string false "Foreground hex color" default(000000)
+// @Param bg query string false "Background hex color" default(ffffff)
+// @Param logo query bool false "Draw the configured logo in the center"
+// @Success 200 {file} file "QR code image"
+// @Failure 400 {object} problem.Problem "Invalid option"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Router /{shortID}/qr [get]
+func GetQRCode(db *sqlc.Queries, opts QROptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortID := chi.URLParam(r, "shortID")
+		if _, err := db.GetURL(r.Context(), shortID); err != nil {
+			rowError(w, r, "URL not found", "Failed to fetch URL", err)
+			return
+		}
+		writeQRCode(w, r, shortID, opts)
+	}
+}
+
+// GetURLQRCode serves the QR code of a short link owned by the authenticated user
+// @Summary Get QR Code (Authenticated)
+// @Description Render a QR code for one of your short links as PNG or SVG. Accepts the same options as /{shortID}/qr.
+// @Tags qr
+// @Security ApiKeyAuth
+// @Produce png
+// @Produce image/svg+xml
+// @Param shortID path string true "Short URL ID"
+// @Param format query string false "Image format" Enums(png, svg) default(png)
+// @Param size query int false "Width and height in pixels (64-2048)" default(256)
+// @Param level query string false "Error correction level" Enums(L, M, Q, H) default(M)
+// @Param margin query int false "Quiet zone in modules (0-16)" default(4)
+// @Param fg query string false "Foreground hex color" default(000000)
+// @Param bg query string false "Background hex color" default(ffffff)
+// @Param logo query bool false "Draw the configured logo in the center"
+// @Success 200 {file} file "QR code image"
+// @Failure 400 {object} problem.Problem "Invalid option"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Router /api/urls/{shortID}/qr [get]
+func GetURLQRCode(db *sqlc.Queries, opts QROptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+		writeQRCode(w, r, link.ShortID, opts)
+	}
+}
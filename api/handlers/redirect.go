@@ -2,53 +2,460 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/counters"
+	"github.com/yeboahd24/url-shortener/logging"
+	"github.com/yeboahd24/url-shortener/metrics"
 	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/tracing"
+	"github.com/yeboahd24/url-shortener/useragent"
+	"github.com/yeboahd24/url-shortener/visitorid"
+	"github.com/yeboahd24/url-shortener/webhooks"
 )
 
+// Redirect types a link can use. The numeric types answer with the matching
+// HTTP status; the interstitial type serves an HTML page that forwards the
+// visitor with a meta refresh and JavaScript.
+const (
+	RedirectMovedPermanently = "301"
+	RedirectFound            = "302"
+	RedirectTemporary        = "307"
+	RedirectPermanent        = "308"
+	RedirectInterstitial     = "interstitial"
+)
+
+const (
+	urlCacheTTL    = 24 * time.Hour
+	noCacheControl = "private, no-cache, no-store, max-age=0, must-revalidate"
+)
+
+// RedirectOptions holds the service-wide redirect settings
+type RedirectOptions struct {
+	// DefaultType is used for links that do not set their own redirect type
+	DefaultType string
+	// PermanentMaxAge is how long browsers may cache 301/308 redirects of
+	// links without an expiry or click limit. Zero disables caching.
+	PermanentMaxAge time.Duration
+	// CookieSecret signs the cookies issued when a protected link is unlocked
+	CookieSecret []byte
+	// UnlockTTL is how long an unlocked link skips the password prompt
+	UnlockTTL time.Duration
+	// StoreIPs keeps the raw IP address of each click. Unique visitors are
+	// counted from hashed visitor IDs either way.
+	StoreIPs bool
+	// Visitors derives the daily visitor IDs of clicks
+	Visitors *visitorid.Hasher
+	// ClickIDParam is the query parameter that passes click IDs to
+	// destinations of links that use click ID mode "query"
+	ClickIDParam string
+	// ConversionWindow is how long after a click conversions are attributed
+	// to it, and how long the click ID cookie lasts
+	ConversionWindow time.Duration
+}
+
+// cachedURL is the redirect information stored in Redis under url:{shortID}
+type cachedURL struct {
+	LongURL       string          `json:"long_url"`
+	RedirectType  string          `json:"redirect_type,omitempty"`
+	ActivatesAt   *time.Time      `json:"activates_at,omitempty"`
+	ExpiresAt     *time.Time      `json:"expires_at,omitempty"`
+	ClickLimit    *int32          `json:"click_limit,omitempty"`
+	Protected     bool            `json:"protected,omitempty"`
+	Rules         []cachedRule    `json:"rules,omitempty"`
+	Variants      []cachedVariant `json:"variants,omitempty"`
+	QueryMode     string          `json:"query_mode,omitempty"`
+	ForwardPath   bool            `json:"forward_path,omitempty"`
+	IOSAppURL     string          `json:"ios_app_url,omitempty"`
+	AndroidAppURL string          `json:"android_app_url,omitempty"`
+	ExpiredURL    string          `json:"expired_redirect_url,omitempty"`
+	ClickIDMode   string          `json:"click_id_mode,omitempty"`
+	// LegacyPasswordHash is only read, to drop entries cached while password
+	// hashes were still copied into Redis
+	LegacyPasswordHash string `json:"password_hash,omitempty"`
+}
+
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url={{.}}">
+<title>Redirecting…</title>
+<script>window.location.replace({{.}});</script>
+</head>
+<body>
+<p>Redirecting to <a href="{{.}}">{{.}}</a>…</p>
+</body>
+</html>
+`))
+
+// ValidRedirectType reports whether t is a supported redirect type
+func ValidRedirectType(t string) bool {
+	switch t {
+	case RedirectMovedPermanently, RedirectFound, RedirectTemporary, RedirectPermanent, RedirectInterstitial:
+		return true
+	}
+	return false
+}
+
+// clientIP returns the visitor's IP address. Behind a trusted proxy the
+// RealIP middleware has already replaced the remote address with the one
+// the proxy reported.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isHTTPS reports whether the visitor connected over HTTPS. The
+// X-Forwarded-Proto header only reaches handlers from trusted proxies; the
+// RealIP middleware drops it for everyone else.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// maxReferrerLength matches the referrer column of the clicks table
+const maxReferrerLength = 255
+
+// referrerHost returns the host of the page that linked to the short link,
+// or an empty string for direct visits.
+func referrerHost(r *http.Request) string {
+	ref, err := url.Parse(r.Referer())
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(ref.Hostname())
+	if len(host) > maxReferrerLength {
+		host = host[:maxReferrerLength]
+	}
+	return host
+}
+
+func urlCacheKey(shortID string) string {
+	return "url:" + shortID
+}
+
+// invalidateURLCache drops the cached redirect for a short ID so edits take
+// effect on the next visit.
+func invalidateURLCache(ctx context.Context, redisClient *redis.Client, shortID string) {
+	redisClient.Del(ctx, urlCacheKey(shortID))
+}
+
+// lookupURL resolves a short ID from Redis, falling back to Postgres and
+// repopulating the cache on a miss.
+func lookupURL(ctx context.Context, db *sqlc.Queries, redisClient *redis.Client, shortID string) (cachedURL, error) {
+	var entry cachedURL
+	if data, err := redisClient.Get(ctx, urlCacheKey(shortID)).Bytes(); err == nil {
+		if err := json.Unmarshal(data, &entry); err == nil && entry.LongURL != "" && entry.LegacyPasswordHash == "" {
+			metrics.RedirectCache.WithLabelValues("hit").Inc()
+			return entry, nil
+		}
+	}
+	metrics.RedirectCache.WithLabelValues("miss").Inc()
+
+	url, err := db.GetURL(ctx, shortID)
+	if err != nil {
+		return cachedURL{}, err
+	}
+
+	entry = cachedURL{
+		LongURL:       url.LongUrl,
+		RedirectType:  url.RedirectType.String,
+		Protected:     url.PasswordHash.Valid,
+		QueryMode:     url.QueryMode.String,
+		ForwardPath:   url.ForwardPath,
+		IOSAppURL:     url.IosAppUrl.String,
+		AndroidAppURL: url.AndroidAppUrl.String,
+		ExpiredURL:    url.ExpiredRedirectUrl.String,
+		ClickIDMode:   url.ClickIDMode.String,
+	}
+	if url.ActivatesAt.Valid {
+		entry.ActivatesAt = &url.ActivatesAt.Time
+	}
+	if url.ExpiresAt.Valid {
+		entry.ExpiresAt = &url.ExpiresAt.Time
+	}
+	if url.ClickLimit.Valid {
+		entry.ClickLimit = &url.ClickLimit.Int32
+	}
+
+	entry.Rules, err = loadCachedRules(ctx, db, shortID)
+	if err != nil {
+		return cachedURL{}, err
+	}
+
+	entry.Variants, err = loadCachedVariants(ctx, db, shortID)
+	if err != nil {
+		return cachedURL{}, err
+	}
+
+	if data, err := json.Marshal(entry); err == nil {
+		redisClient.Set(ctx, urlCacheKey(shortID), data, urlCacheTTL)
+	}
+	return entry, nil
+}
+
+// writeRedirect sends the visitor to target using the given redirect type.
+// Only permanent redirects of links that can never change state are allowed
+// to be cached by browsers, so that edits, expiry and click limits apply to
+// returning visitors and every click reaches the server. Targets other than
+// http and https URLs are refused, as the interstitial page would run them.
+func writeRedirect(w http.ResponseWriter, r *http.Request, target, redirectType string, cacheable bool, opts RedirectOptions) {
+	if !validWebURL(target) {
+		logging.FromContext(r.Context()).Warn("Refused redirect to non-web destination", "target", target)
+		w.Header().Set("Cache-Control", noCacheControl)
+		problem.Error(w, "URL not found", http.StatusNotFound)
+		return
+	}
+
+	permanent := redirectType == RedirectMovedPermanently || redirectType == RedirectPermanent
+	if permanent && cacheable && opts.PermanentMaxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(opts.PermanentMaxAge.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", noCacheControl)
+	}
+
+	switch redirectType {
+	case RedirectMovedPermanently:
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	case RedirectTemporary:
+		http.Redirect(w, r, target, http.StatusTemporaryRedirect)
+	case RedirectPermanent:
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	case RedirectInterstitial:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		interstitialTemplate.Execute(w, target)
+	default:
+		http.Redirect(w, r, target, http.StatusFound)
+	}
+}
+
+// writeExpired answers a visit to a link that is no longer usable, sending the
+// visitor to the link's expired redirect URL when it has one.
+func writeExpired(w http.ResponseWriter, r *http.Request, entry cachedURL, code, message string) {
+	w.Header().Set("Cache-Control", noCacheControl)
+	if entry.ExpiredURL != "" {
+		http.Redirect(w, r, entry.ExpiredURL, http.StatusFound)
+		return
+	}
+	problem.Write(w, http.StatusGone, code, message)
+}
+
 // RedirectURL redirects to the original URL
 // @Summary Redirect to Original URL
-// @Description Redirect to the original URL using the short ID and log the click
+// @Description Redirect to the original URL using the short ID and log the click. Conditional redirect rules of the URL are evaluated first, then any A/B split. Depending on the link, the visit's query string and the path after /{shortID}/ are forwarded, and placeholders such as {path}, {query} and {param:name} in the destination are filled in. Links before their activation time answer 404; expired links and links that reached their click limit go to their expired redirect URL, or answer 410. iOS and Android visitors of links with a deep link are sent to the app, falling back to the web destination when it is not installed. The status code depends on the link's redirect type (301, 302, 307, 308, or an HTML interstitial answering 200).
 // @Tags urls
 // @Param shortID path string true "Short URL ID"
-// @Success 301 "Redirect to original URL"
-// @Failure 404 {object} map[string]string "URL not found"
+// @Param preview query string false "Set to 1 to show the link preview instead of redirecting"
+// @Success 301 "Permanent redirect to original URL"
+// @Success 302 "Temporary redirect to original URL, or to the expired redirect URL of an expired link"
+// @Success 307 "Temporary redirect to original URL"
+// @Success 308 "Permanent redirect to original URL"
+// @Failure 401 "Password-protected URL, unlock form shown"
+// @Failure 404 {object} problem.Problem "URL not found or not active yet"
+// @Failure 410 {object} problem.Problem "URL expired or click limit reached, and no expired redirect URL is set"
 // @Router /{shortID} [get]
-func RedirectURL(db *sqlc.Queries, redisClient *redis.Client, geoAPIURL string) http.HandlerFunc {
+func RedirectURL(db *sqlc.Queries, redisClient *redis.Client, geoAPIURL string, opts RedirectOptions) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		shortID := chi.URLParam(r, "shortID")
 		ctx := r.Context()
 
-		// Check Redis cache
-		longURL, err := redisClient.Get(ctx, "url:"+shortID).Result()
-		if err == redis.Nil {
-			url, err := db.GetURL(ctx, shortID)
+		if r.URL.Query().Get("preview") == "1" {
+			servePreview(w, r, db, shortID)
+			return
+		}
+
+		entry, err := lookupURL(ctx, db, redisClient, shortID)
+		if err != nil {
+			rowError(w, r, "URL not found", "Failed to fetch URL", err)
+			return
+		}
+
+		if entry.ActivatesAt != nil && entry.ActivatesAt.After(time.Now()) {
+			w.Header().Set("Cache-Control", noCacheControl)
+			problem.Write(w, http.StatusNotFound, problem.CodeLinkNotActive, "URL is not active yet")
+			return
+		}
+
+		if entry.ExpiresAt != nil && entry.ExpiresAt.Before(time.Now()) {
+			writeExpired(w, r, entry, problem.CodeLinkExpired, "URL has expired")
+			return
+		}
+
+		if entry.ClickLimit != nil {
+			// The running total survives click retention, unlike the click rows
+			clicks, err := db.GetURLClickTotal(ctx, shortID)
+			if err == nil && clicks >= int64(*entry.ClickLimit) {
+				writeExpired(w, r, entry, problem.CodeClickLimitReached, "URL click limit reached")
+				return
+			}
+		}
+
+		// The password hash is kept out of the cache, so protected links
+		// read it from Postgres
+		if entry.Protected {
+			passwordHash, err := db.GetURLPasswordHash(ctx, shortID)
 			if err != nil {
-				http.Error(w, "URL not found", http.StatusNotFound)
+				rowError(w, r, "URL not found", "Failed to fetch URL", err)
+				return
+			}
+			if passwordHash.Valid && !isUnlocked(r, shortID, passwordHash.String, opts) {
+				renderUnlockForm(w, http.StatusUnauthorized, shortID, "")
 				return
 			}
-			longURL = url.LongUrl
-			redisClient.Set(ctx, "url:"+shortID, longURL, 24*60*60)
 		}
 
+		source := clickSource(r)
+
+		target, matched := resolveDestination(ctx, r, redisClient, geoAPIURL, entry)
+
+		variantID := pgtype.UUID{Valid: false}
+		if !matched && len(entry.Variants) > 0 {
+			variant := pickVariant(w, r, shortID, entry.Variants)
+			target = variant.Destination
+			if id, err := uuid.Parse(variant.ID); err == nil {
+				variantID = sqlc.UUIDToNullable(&id)
+			}
+		}
+
+		// The link may take path suffixes later, so the 404 must not be cached
+		if pathSuffix(r, shortID) != "" && !acceptsPathSuffix(entry, target) {
+			w.Header().Set("Cache-Control", noCacheControl)
+			problem.Error(w, "URL not found", http.StatusNotFound)
+			return
+		}
+		target = buildDestination(target, r, shortID, entry)
+		if target == "" {
+			w.Header().Set("Cache-Control", noCacheControl)
+			problem.Error(w, "URL not found", http.StatusNotFound)
+			return
+		}
+
+		// Every click gets an ID that conversions can be recorded against.
+		// IDs passed on to the destination are recorded before the redirect,
+		// so that a postback sent right away finds them.
+		clickID := uuid.New()
+		clickedAt := time.Now()
+		clickIDParams := sqlc.RecordClickIDParams{
+			ClickID:   clickID,
+			ShortID:   shortID,
+			ClickedAt: pgtype.Timestamp{Time: clickedAt, Valid: true},
+		}
+		clickIDRecorded := false
+		if entry.ClickIDMode != "" {
+			if err := db.RecordClickID(ctx, clickIDParams); err != nil {
+				logging.FromContext(ctx).Error("Failed to record click ID", "short_id", shortID, "error", err)
+			} else {
+				clickIDRecorded = true
+				switch entry.ClickIDMode {
+				case ClickIDModeQuery:
+					target = appendClickID(target, opts.ClickIDParam, clickID)
+				case ClickIDModeCookie:
+					setClickIDCookie(w, r, clickID, opts.ConversionWindow)
+				}
+			}
+		}
+
+		remoteAddr, ip, userAgent, referrer := r.RemoteAddr, clientIP(r), r.UserAgent(), referrerHost(r)
+
 		// Async click logging with background context
+		metrics.ClickQueue.Inc()
 		go func() {
-			// Use background context to avoid cancellation when request completes
-			bgCtx := context.Background()
-			db.LogClick(bgCtx, sqlc.LogClickParams{
-				ShortID:   pgtype.Text{String: shortID, Valid: true},
-				IpAddress: pgtype.Text{String: r.RemoteAddr, Valid: true},
-				UserAgent: pgtype.Text{String: r.UserAgent(), Valid: true},
-				ClickedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+			defer metrics.ClickQueue.Dec()
+			// Detach from the request to avoid cancellation when it completes,
+			// keeping the click's queries and geo lookup in its trace
+			bgCtx, span := tracing.Tracer().Start(context.WithoutCancel(ctx), "log click")
+			defer span.End()
+			logger := logging.FromContext(bgCtx).With("short_id", shortID)
+			// Without a visitor ID the click still counts, just not as a visitor
+			visitorID, err := opts.Visitors.ID(bgCtx, ip, userAgent, clickedAt)
+			if err != nil {
+				logger.Warn("Failed to identify visitor", "error", err)
+			}
+			if err := counters.Record(bgCtx, redisClient, db, shortID, visitorID, clickedAt); err != nil {
+				logger.Error("Failed to count click", "error", err)
+			}
+
+			ipAddress := pgtype.Text{Valid: false}
+			if opts.StoreIPs {
+				ipAddress = pgtype.Text{String: remoteAddr, Valid: true}
+			}
+
+			geo := lookupGeo(bgCtx, redisClient, ip, geoAPIURL)
+			device := useragent.Parse(userAgent).Device
+			err = db.LogClick(bgCtx, sqlc.LogClickParams{
+				ShortID:     pgtype.Text{String: shortID, Valid: true},
+				IpAddress:   ipAddress,
+				UserAgent:   pgtype.Text{String: userAgent, Valid: true},
+				ClickedAt:   pgtype.Timestamp{Time: clickedAt, Valid: true},
+				VariantID:   variantID,
+				Source:      stringToNullable(source),
+				Country:     stringToNullable(geo.CountryCode),
+				Location:    stringToNullable(geo.Location),
+				Device:      stringToNullable(device),
+				Referrer:    stringToNullable(referrer),
+				VisitorHash: stringToNullable(visitorID),
+				ClickID:     pgtype.UUID{Bytes: clickID, Valid: true},
 			})
+			if err != nil {
+				logger.Error("Failed to log click", "error", err)
+			}
+			if !clickIDRecorded {
+				if err := db.RecordClickID(bgCtx, clickIDParams); err != nil {
+					logger.Error("Failed to record click ID", "error", err)
+				}
+			}
+
+			event := ClickEvent{
+				ShortID:  shortID,
+				Time:     clickedAt.UTC(),
+				Country:  geo.CountryCode,
+				Device:   device,
+				Referrer: referrer,
+				Source:   source,
+			}
+			publishClick(bgCtx, redisClient, event)
+			if err := webhooks.EnqueueForLink(bgCtx, db, shortID, webhooks.EventLinkClicked, event); err != nil {
+				logger.Error("Failed to queue webhooks", "event", webhooks.EventLinkClicked, "error", err)
+			}
 		}()
 
-		http.Redirect(w, r, longURL, http.StatusMovedPermanently)
+		// Mobile visitors are handed to the app when the link has a deep link
+		// for their platform; the resolved destination is the web fallback.
+		// The deep-link page is only served with an http(s) fallback, which
+		// it runs from JavaScript.
+		if appURL := appURLFor(r, entry); appURL != "" && validWebURL(target) {
+			appURL = expandDestination(appURL, r, shortID, pathSuffix(r, shortID))
+			if !isWebURL(appURL) {
+				serveDeepLink(w, appURL, target)
+				return
+			}
+			target = appURL
+		}
+
+		redirectType := entry.RedirectType
+		if redirectType == "" {
+			redirectType = opts.DefaultType
+		}
+		cacheable := entry.ActivatesAt == nil && entry.ExpiresAt == nil && entry.ClickLimit == nil && !entry.Protected &&
+			len(entry.Rules) == 0 && len(entry.Variants) == 0 && entry.IOSAppURL == "" && entry.AndroidAppURL == "" &&
+			entry.ClickIDMode == ""
+		writeRedirect(w, r, target, redirectType, cacheable, opts)
 	}
 }
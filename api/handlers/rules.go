@@ -0,0 +1,532 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/useragent"
+)
+
+// Redirect rule match types
+const (
+	MatchDevice   = "device"
+	MatchOS       = "os"
+	MatchCountry  = "country"
+	MatchLanguage = "language"
+	MatchTime     = "time"
+)
+
+// RedirectRuleRequest represents a single conditional redirect rule
+type RedirectRuleRequest struct {
+	MatchType   string `json:"match_type" enums:"device,os,country,language,time" example:"os" binding:"required"`
+	MatchValue  string `json:"match_value" example:"ios" binding:"required"`
+	Destination string `json:"destination" example:"https://apps.apple.com/app/id123456789" binding:"required"`
+}
+
+// ReplaceRedirectRulesRequest represents the full, ordered list of rules for a URL
+type ReplaceRedirectRulesRequest struct {
+	Rules []RedirectRuleRequest `json:"rules"`
+}
+
+// RedirectRuleInfo represents redirect rule information
+type RedirectRuleInfo struct {
+	RuleID      string    `json:"rule_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Position    int32     `json:"position" example:"1"`
+	MatchType   string    `json:"match_type" example:"os"`
+	MatchValue  string    `json:"match_value" example:"ios"`
+	Destination string    `json:"destination" example:"https://apps.apple.com/app/id123456789"`
+	CreatedAt   time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// ListRedirectRulesResponse represents the response for listing redirect rules
+type ListRedirectRulesResponse struct {
+	Rules []RedirectRuleInfo `json:"rules"`
+}
+
+// cachedRule is a redirect rule as stored in the Redis URL cache
+type cachedRule struct {
+	MatchType   string `json:"match_type"`
+	MatchValue  string `json:"match_value"`
+	Destination string `json:"destination"`
+}
+
+// visitor holds the request attributes rules are matched against. The
+// country needs a geo lookup, so it is only resolved when a rule asks for it.
+type visitor struct {
+	agent    useragent.Info
+	language string
+	now      time.Time
+	country  func() string
+}
+
+func ruleToInfo(rule sqlc.RedirectRule) RedirectRuleInfo {
+	return RedirectRuleInfo{
+		RuleID:      rule.RuleID.String(),
+		Position:    rule.Position,
+		MatchType:   rule.MatchType,
+		MatchValue:  rule.MatchValue,
+		Destination: rule.Destination,
+		CreatedAt:   rule.CreatedAt.Time,
+	}
+}
+
+// splitMatchValues splits a comma-separated match value into normalized entries
+func splitMatchValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseTimeWindow parses either a daily UTC window ("09:00-17:00", which may
+// wrap past midnight) or an absolute range of two RFC 3339 timestamps
+// separated by a slash.
+func parseTimeWindow(value string) (func(time.Time) bool, error) {
+	if start, end, ok := strings.Cut(value, "/"); ok {
+		from, err := time.Parse(time.RFC3339, strings.TrimSpace(start))
+		if err != nil {
+			return nil, err
+		}
+		to, err := time.Parse(time.RFC3339, strings.TrimSpace(end))
+		if err != nil {
+			return nil, err
+		}
+		return func(t time.Time) bool { return !t.Before(from) && t.Before(to) }, nil
+	}
+
+	start, end, ok := strings.Cut(value, "-")
+	if !ok {
+		return nil, fmt.Errorf("time window must be HH:MM-HH:MM or START/END")
+	}
+	from, err := time.Parse("15:04", strings.TrimSpace(start))
+	if err != nil {
+		return nil, err
+	}
+	to, err := time.Parse("15:04", strings.TrimSpace(end))
+	if err != nil {
+		return nil, err
+	}
+
+	fromMin := from.Hour()*60 + from.Minute()
+	toMin := to.Hour()*60 + to.Minute()
+	return func(t time.Time) bool {
+		t = t.UTC()
+		m := t.Hour()*60 + t.Minute()
+		if fromMin <= toMin {
+			return m >= fromMin && m < toMin
+		}
+		return m >= fromMin || m < toMin
+	}, nil
+}
+
+// validateRedirectRule checks a rule before it is stored
+func validateRedirectRule(rule RedirectRuleRequest) error {
+	if strings.TrimSpace(rule.Destination) == "" {
+		return fmt.Errorf("destination is required")
+	}
+	if !validDestination(rule.Destination) {
+		return fmt.Errorf("destination must be an http or https URL")
+	}
+	if !validPathTemplate(rule.Destination) {
+		return fmt.Errorf("{path} must come right after a / in the destination path")
+	}
+
+	values := splitMatchValues(rule.MatchValue)
+	if len(values) == 0 {
+		return fmt.Errorf("match_value is required")
+	}
+
+	switch rule.MatchType {
+	case MatchDevice:
+		for _, v := range values {
+			switch v {
+			case useragent.DeviceDesktop, useragent.DeviceMobile, useragent.DeviceTablet, useragent.DeviceBot:
+			default:
+				return fmt.Errorf("unknown device %q", v)
+			}
+		}
+	case MatchOS:
+		for _, v := range values {
+			switch v {
+			case useragent.OSIOS, useragent.OSAndroid, useragent.OSWindows, useragent.OSMacOS, useragent.OSLinux:
+			default:
+				return fmt.Errorf("unknown os %q", v)
+			}
+		}
+	case MatchCountry:
+		for _, v := range values {
+			if len(v) != 2 {
+				return fmt.Errorf("country %q must be a two-letter ISO code", v)
+			}
+		}
+	case MatchLanguage:
+	case MatchTime:
+		if _, err := parseTimeWindow(rule.MatchValue); err != nil {
+			return fmt.Errorf("invalid time window: %v", err)
+		}
+	default:
+		return fmt.Errorf("unknown match_type %q", rule.MatchType)
+	}
+	return nil
+}
+
+// preferredLanguage returns the most preferred language tag from an
+// Accept-Language header, lowercased.
+func preferredLanguage(header string) string {
+	best, bestQ := "", -1.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > bestQ {
+			best, bestQ = strings.ToLower(tag), q
+		}
+	}
+	return best
+}
+
+// matches reports whether a rule applies to the visitor
+func (rule cachedRule) matches(v *visitor) bool {
+	if rule.MatchType == MatchTime {
+		inWindow, err := parseTimeWindow(rule.MatchValue)
+		return err == nil && inWindow(v.now)
+	}
+
+	var actual string
+	switch rule.MatchType {
+	case MatchDevice:
+		actual = v.agent.Device
+	case MatchOS:
+		actual = v.agent.OS
+	case MatchCountry:
+		actual = strings.ToLower(v.country())
+	case MatchLanguage:
+		actual = v.language
+	}
+	if actual == "" {
+		return false
+	}
+
+	for _, want := range splitMatchValues(rule.MatchValue) {
+		if actual == want {
+			return true
+		}
+		// A rule for "en" also matches "en-us"
+		if rule.MatchType == MatchLanguage && strings.HasPrefix(actual, want+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveDestination evaluates the rules of a URL in order and returns the
+// destination of the first match, or the URL's default destination and false
+// when no rule matches.
+func resolveDestination(ctx context.Context, r *http.Request, redisClient *redis.Client, geoAPIURL string, entry cachedURL) (string, bool) {
+	if len(entry.Rules) == 0 {
+		return entry.LongURL, false
+	}
+
+	var country *string
+	v := &visitor{
+		agent:    useragent.Parse(r.UserAgent()),
+		language: preferredLanguage(r.Header.Get("Accept-Language")),
+		now:      time.Now(),
+		country: func() string {
+			if country == nil {
+				c := lookupCountryCode(ctx, redisClient, clientIP(r), geoAPIURL)
+				country = &c
+			}
+			return *country
+		},
+	}
+
+	for _, rule := range entry.Rules {
+		if rule.matches(v) {
+			return rule.Destination, true
+		}
+	}
+	return entry.LongURL, false
+}
+
+// ownedURL loads a URL and checks it belongs to the authenticated user
+func ownedURL(w http.ResponseWriter, r *http.Request, db *sqlc.Queries) (sqlc.Url, bool) {
+	shortID := chi.URLParam(r, "shortID")
+
+	userIDStr, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return sqlc.Url{}, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return sqlc.Url{}, false
+	}
+
+	url, err := db.GetOwnedURL(r.Context(), shortID, userID)
+	if err != nil {
+		dbError(w, r, "Failed to fetch URL", err)
+		return sqlc.Url{}, false
+	}
+	return url, true
+}
+
+func writeRedirectRules(w http.ResponseWriter, r *http.Request, db *sqlc.Queries, shortID string) {
+	rules, err := db.ListRedirectRules(r.Context(), shortID)
+	if err != nil {
+		serverError(w, r, "Failed to fetch redirect rules", err)
+		return
+	}
+
+	response := ListRedirectRulesResponse{Rules: []RedirectRuleInfo{}}
+	for _, rule := range rules {
+		response.Rules = append(response.Rules, ruleToInfo(rule))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListRedirectRules lists the conditional redirect rules of a URL
+// @Summary List Redirect Rules
+// @Description List the conditional redirect rules of a URL in evaluation order
+// @Tags rules
+// @Security ApiKeyAuth
+// @Param shortID path string true "Short URL ID"
+// @Produce json
+// @Success 200 {object} ListRedirectRulesResponse "Redirect rules"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/urls/{shortID}/rules [get]
+func ListRedirectRules(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		url, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+		writeRedirectRules(w, r, db, url.ShortID)
+	}
+}
+
+// AddRedirectRule appends a conditional redirect rule to a URL
+// @Summary Add Redirect Rule
+// @Description Append a rule that sends matching visitors to a different destination. Rules are evaluated in order and the first match wins; visitors matching no rule go to the URL's long_url. Match values may list several comma-separated options. Time windows are either a daily UTC range ("09:00-17:00") or two RFC 3339 timestamps separated by a slash.
+// @Tags rules
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param shortID path string true "Short URL ID"
+// @Param rule body RedirectRuleRequest true "Rule to add"
+// @Success 200 {object} RedirectRuleInfo "Rule added"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/urls/{shortID}/rules [post]
+func AddRedirectRule(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		url, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		var input RedirectRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := validateRedirectRule(input); err != nil {
+			problem.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		position, err := db.GetNextRulePosition(r.Context(), url.ShortID)
+		if err != nil {
+			dbError(w, r, "Failed to add redirect rule", err)
+			return
+		}
+
+		rule, err := db.CreateRedirectRule(r.Context(), sqlc.CreateRedirectRuleParams{
+			RuleID:      uuid.New(),
+			ShortID:     url.ShortID,
+			Position:    position,
+			MatchType:   input.MatchType,
+			MatchValue:  input.MatchValue,
+			Destination: input.Destination,
+			CreatedAt:   pgtype.Timestamp{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			dbError(w, r, "Failed to add redirect rule", err)
+			return
+		}
+
+		invalidateURLCache(r.Context(), redisClient, url.ShortID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ruleToInfo(rule))
+	}
+}
+
+// ReplaceRedirectRules replaces all conditional redirect rules of a URL
+// @Summary Replace Redirect Rules
+// @Description Replace the rules of a URL with the given ordered list. An empty list removes all rules. The list is replaced as a whole or not at all.
+// @Tags rules
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param shortID path string true "Short URL ID"
+// @Param rules body ReplaceRedirectRulesRequest true "Ordered list of rules"
+// @Success 200 {object} ListRedirectRulesResponse "Rules replaced"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/urls/{shortID}/rules [put]
+func ReplaceRedirectRules(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		url, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		var input ReplaceRedirectRulesRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		for i, rule := range input.Rules {
+			if err := validateRedirectRule(rule); err != nil {
+				problem.Error(w, fmt.Sprintf("Rule %d: %v", i+1, err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Rules are replaced in one transaction so a failure keeps the
+		// previous rules
+		err := db.InTx(r.Context(), func(tx *sqlc.Queries) error {
+			if err := tx.DeleteRedirectRules(r.Context(), url.ShortID); err != nil {
+				return err
+			}
+			for i, rule := range input.Rules {
+				_, err := tx.CreateRedirectRule(r.Context(), sqlc.CreateRedirectRuleParams{
+					RuleID:      uuid.New(),
+					ShortID:     url.ShortID,
+					Position:    int32(i + 1),
+					MatchType:   rule.MatchType,
+					MatchValue:  rule.MatchValue,
+					Destination: rule.Destination,
+					CreatedAt:   pgtype.Timestamp{Time: time.Now(), Valid: true},
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			dbError(w, r, "Failed to replace redirect rules", err)
+			return
+		}
+
+		invalidateURLCache(r.Context(), redisClient, url.ShortID)
+		writeRedirectRules(w, r, db, url.ShortID)
+	}
+}
+
+// DeleteRedirectRule removes a conditional redirect rule from a URL
+// @Summary Delete Redirect Rule
+// @Description Remove a single redirect rule from a URL
+// @Tags rules
+// @Security ApiKeyAuth
+// @Param shortID path string true "Short URL ID"
+// @Param ruleID path string true "Rule ID"
+// @Produce json
+// @Success 200 {object} map[string]string "Rule deleted successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL or rule not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/urls/{shortID}/rules/{ruleID} [delete]
+func DeleteRedirectRule(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		url, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		ruleID, err := uuid.Parse(chi.URLParam(r, "ruleID"))
+		if err != nil {
+			problem.Error(w, "Invalid rule ID", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := db.DeleteRedirectRule(r.Context(), sqlc.DeleteRedirectRuleParams{
+			RuleID:  ruleID,
+			ShortID: url.ShortID,
+		})
+		if err != nil {
+			serverError(w, r, "Failed to delete redirect rule", err)
+			return
+		}
+		if deleted == 0 {
+			problem.Error(w, "Rule not found", http.StatusNotFound)
+			return
+		}
+
+		invalidateURLCache(r.Context(), redisClient, url.ShortID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Rule deleted successfully",
+		})
+	}
+}
+
+// loadCachedRules converts the stored rules of a URL into cache entries
+func loadCachedRules(ctx context.Context, db *sqlc.Queries, shortID string) ([]cachedRule, error) {
+	rules, err := db.ListRedirectRules(ctx, shortID)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := make([]cachedRule, 0, len(rules))
+	for _, rule := range rules {
+		cached = append(cached, cachedRule{
+			MatchType:   rule.MatchType,
+			MatchValue:  rule.MatchValue,
+			Destination: rule.Destination,
+		})
+	}
+	return cached, nil
+}
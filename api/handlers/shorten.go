@@ -1,21 +1,43 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/metadata"
 	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/webhooks"
 )
 
 // ShortenURLRequest represents the request body for shortening a URL
 type ShortenURLRequest struct {
-	LongURL    string     `json:"long_url" example:"https://example.com" binding:"required"`
-	CustomID   string     `json:"custom_id,omitempty" example:"my-custom-url"`
-	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
-	ClickLimit *int       `json:"click_limit,omitempty" example:"100"`
+	LongURL       string     `json:"long_url" example:"https://example.com" binding:"required"`
+	CustomID      string     `json:"custom_id,omitempty" example:"my-custom-url"`
+	ActivatesAt   *time.Time `json:"activates_at,omitempty" example:"2024-03-01T09:00:00Z"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	ClickLimit    *int       `json:"click_limit,omitempty" example:"100"`
+	Tags          []string   `json:"tags,omitempty" example:"marketing,spring"`
+	FolderID      string     `json:"folder_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Title         string     `json:"title,omitempty" example:"Example Domain"`
+	Description   string     `json:"description,omitempty" example:"Landing page for the spring campaign"`
+	Notes         string     `json:"notes,omitempty" example:"Shared in the March newsletter"`
+	RedirectType  string     `json:"redirect_type,omitempty" enums:"301,302,307,308,interstitial" example:"302"`
+	Password      string     `json:"password,omitempty" example:"s3cret"`
+	QueryMode     string     `json:"query_mode,omitempty" enums:"merge,override" example:"merge"`
+	ForwardPath   bool       `json:"forward_path,omitempty" example:"false"`
+	IOSAppURL     string     `json:"ios_app_url,omitempty" example:"myapp://product/42"`
+	AndroidAppURL string     `json:"android_app_url,omitempty" example:"intent://product/42#Intent;scheme=myapp;package=com.example.app;end"`
+	ExpiredURL    string     `json:"expired_redirect_url,omitempty" example:"https://example.com/campaign-ended"`
+	ClickIDMode   string     `json:"click_id_mode,omitempty" enums:"query,cookie" example:"query"`
 }
 
 // ShortenURLResponse represents the response for shortening a URL
@@ -30,6 +52,63 @@ func timeToNullable(t *time.Time) pgtype.Timestamp {
 	return pgtype.Timestamp{Time: *t, Valid: true}
 }
 
+func stringToNullable(s string) pgtype.Text {
+	if s == "" {
+		return pgtype.Text{Valid: false}
+	}
+	return pgtype.Text{String: s, Valid: true}
+}
+
+// fetchMetadataAsync fills in the title, description and favicon of a URL from
+// its destination page. It is a no-op when metadata fetching is disabled.
+func fetchMetadataAsync(db *sqlc.Queries, fetcher *metadata.Fetcher, shortID, longURL string) {
+	if fetcher == nil {
+		return
+	}
+
+	go func() {
+		// Use background context to avoid cancellation when request completes
+		bgCtx := context.Background()
+		meta, err := fetcher.Fetch(bgCtx, longURL)
+		if err != nil {
+			slog.Warn("Failed to fetch metadata", "short_id", shortID, "error", err)
+			return
+		}
+
+		err = db.UpdateURLMetadata(bgCtx, sqlc.UpdateURLMetadataParams{
+			ShortID:     shortID,
+			Title:       stringToNullable(meta.Title),
+			Description: stringToNullable(meta.Description),
+			FaviconUrl:  stringToNullable(meta.FaviconURL),
+		})
+		if err != nil {
+			slog.Error("Failed to save metadata", "short_id", shortID, "error", err)
+		}
+	}()
+}
+
+// validSchedule reports whether a link's activation time, if any, comes
+// before its expiry.
+func validSchedule(activatesAt, expiresAt pgtype.Timestamp) bool {
+	return !activatesAt.Valid || !expiresAt.Valid || activatesAt.Time.Before(expiresAt.Time)
+}
+
+// validWebURL reports whether u is an absolute http or https URL
+func validWebURL(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// destinationPlaceholder matches the placeholders of templated destinations
+var destinationPlaceholder = regexp.MustCompile(`\{(short_id|path|query|param:[A-Za-z0-9_.\-]+)\}`)
+
+// validDestination reports whether u is an http or https URL once its
+// placeholders are filled in. Other schemes, such as javascript:, would run
+// on this origin from the interstitial page.
+func validDestination(u string) bool {
+	return validWebURL(destinationPlaceholder.ReplaceAllString(u, "x"))
+}
+
 func intToNullable(i *int) pgtype.Int4 {
 	if i == nil {
 		return pgtype.Int4{Valid: false}
@@ -39,63 +118,175 @@ func intToNullable(i *int) pgtype.Int4 {
 
 // ShortenURL creates a shortened URL
 // @Summary Shorten URL
-// @Description Create a shortened URL. Custom IDs require authentication.
+// @Description Create a shortened URL. Custom IDs, tags and folders require authentication.
 // @Tags urls
 // @Accept json
 // @Produce json
 // @Param url body ShortenURLRequest true "URL to shorten"
 // @Success 200 {object} ShortenURLResponse "URL shortened successfully"
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Authentication required for custom URLs"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Authentication required for custom URLs, tags or folders"
+// @Failure 409 {object} problem.Problem "Custom ID already taken"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /shorten [post]
 // @Router /api/shorten [post]
-func ShortenURL(db *sqlc.Queries) http.HandlerFunc {
+func ShortenURL(db *sqlc.Queries, fetcher *metadata.Fetcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var input struct {
-			LongURL    string     `json:"long_url"`
-			CustomID   string     `json:"custom_id"`
-			ExpiresAt  *time.Time `json:"expires_at"`
-			ClickLimit *int       `json:"click_limit"`
+			LongURL       string     `json:"long_url"`
+			CustomID      string     `json:"custom_id"`
+			ActivatesAt   *time.Time `json:"activates_at"`
+			ExpiresAt     *time.Time `json:"expires_at"`
+			ClickLimit    *int       `json:"click_limit"`
+			Tags          []string   `json:"tags"`
+			FolderID      string     `json:"folder_id"`
+			Title         string     `json:"title"`
+			Description   string     `json:"description"`
+			Notes         string     `json:"notes"`
+			RedirectType  string     `json:"redirect_type"`
+			Password      string     `json:"password"`
+			QueryMode     string     `json:"query_mode"`
+			ForwardPath   bool       `json:"forward_path"`
+			IOSAppURL     string     `json:"ios_app_url"`
+			AndroidAppURL string     `json:"android_app_url"`
+			ExpiredURL    string     `json:"expired_redirect_url"`
+			ClickIDMode   string     `json:"click_id_mode"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			http.Error(w, "Invalid request", http.StatusBadRequest)
+			problem.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		if !validDestination(input.LongURL) {
+			problem.Error(w, "long_url must be an http or https URL", http.StatusBadRequest)
+			return
+		}
+		if !validPathTemplate(input.LongURL) {
+			problem.Error(w, "{path} must come right after a / in the path of long_url", http.StatusBadRequest)
+			return
+		}
+
+		if input.RedirectType != "" && !ValidRedirectType(input.RedirectType) {
+			problem.Error(w, "Invalid redirect type", http.StatusBadRequest)
+			return
+		}
+
+		if input.QueryMode != "" && !ValidQueryMode(input.QueryMode) {
+			problem.Error(w, "Invalid query mode", http.StatusBadRequest)
+			return
+		}
+
+		if input.ClickIDMode != "" && !ValidClickIDMode(input.ClickIDMode) {
+			problem.Error(w, "Invalid click ID mode", http.StatusBadRequest)
+			return
+		}
+
+		if (input.IOSAppURL != "" && !ValidAppURL(input.IOSAppURL)) ||
+			(input.AndroidAppURL != "" && !ValidAppURL(input.AndroidAppURL)) {
+			problem.Error(w, "Invalid app URL", http.StatusBadRequest)
+			return
+		}
+
+		if !validSchedule(timeToNullable(input.ActivatesAt), timeToNullable(input.ExpiresAt)) {
+			problem.Error(w, "activates_at must be before expires_at", http.StatusBadRequest)
+			return
+		}
+
+		if input.ExpiredURL != "" && !validWebURL(input.ExpiredURL) {
+			problem.Error(w, "Invalid expired redirect URL", http.StatusBadRequest)
 			return
 		}
 
 		var userID *uuid.UUID
-		if input.CustomID != "" {
-			uidStr, ok := r.Context().Value("user_id").(string)
-			if !ok {
-				http.Error(w, "Authentication required for custom URLs", http.StatusUnauthorized)
-				return
-			}
+		if uidStr, ok := r.Context().Value("user_id").(string); ok {
 			uid, err := uuid.Parse(uidStr)
 			if err != nil {
-				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+				problem.Error(w, "Invalid user ID", http.StatusBadRequest)
 				return
 			}
 			userID = &uid
 		}
 
+		if userID == nil && input.CustomID != "" {
+			problem.Error(w, "Authentication required for custom URLs", http.StatusUnauthorized)
+			return
+		}
+		if userID == nil && (len(input.Tags) > 0 || input.FolderID != "") {
+			problem.Error(w, "Authentication required for tags and folders", http.StatusUnauthorized)
+			return
+		}
+
+		folderID := pgtype.UUID{Valid: false}
+		if userID != nil {
+			var err error
+			folderID, err = resolveFolderID(r.Context(), db, *userID, input.FolderID)
+			if errors.Is(err, sqlc.ErrNotFound) {
+				problem.Error(w, "Folder not found", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				serverError(w, r, "Failed to fetch folder", err)
+				return
+			}
+		}
+
 		shortID := input.CustomID
 		if shortID == "" {
 			shortID = generateShortID()
 		}
 
-		_, err := db.CreateURL(r.Context(), sqlc.CreateURLParams{
-			ShortID:    shortID,
-			LongUrl:    input.LongURL,
-			UserID:     sqlc.UUIDToNullable(userID),
-			CreatedAt:  pgtype.Timestamp{Time: time.Now(), Valid: true},
-			ExpiresAt:  timeToNullable(input.ExpiresAt),
-			ClickLimit: intToNullable(input.ClickLimit),
+		if !validLinkPassword(input.Password) {
+			problem.Error(w, "password must be at most 72 bytes", http.StatusBadRequest)
+			return
+		}
+		passwordHash, err := hashLinkPassword(input.Password)
+		if err != nil {
+			serverError(w, r, "Failed to hash password", err)
+			return
+		}
+
+		// The link and its tags are created together, so a failure leaves
+		// no untagged link behind
+		var created sqlc.Url
+		err = db.InTx(r.Context(), func(tx *sqlc.Queries) error {
+			var err error
+			created, err = tx.CreateURL(r.Context(), sqlc.CreateURLParams{
+				ShortID:            shortID,
+				LongUrl:            input.LongURL,
+				UserID:             sqlc.UUIDToNullable(userID),
+				CreatedAt:          pgtype.Timestamp{Time: time.Now(), Valid: true},
+				ExpiresAt:          timeToNullable(input.ExpiresAt),
+				ClickLimit:         intToNullable(input.ClickLimit),
+				FolderID:           folderID,
+				Title:              stringToNullable(input.Title),
+				Description:        stringToNullable(input.Description),
+				Notes:              stringToNullable(input.Notes),
+				RedirectType:       stringToNullable(input.RedirectType),
+				PasswordHash:       stringToNullable(passwordHash),
+				QueryMode:          stringToNullable(input.QueryMode),
+				ForwardPath:        input.ForwardPath,
+				IosAppUrl:          stringToNullable(input.IOSAppURL),
+				AndroidAppUrl:      stringToNullable(input.AndroidAppURL),
+				ActivatesAt:        timeToNullable(input.ActivatesAt),
+				ExpiredRedirectUrl: stringToNullable(input.ExpiredURL),
+				ClickIDMode:        stringToNullable(input.ClickIDMode),
+			})
+			if err != nil || len(input.Tags) == 0 {
+				return err
+			}
+			return replaceURLTags(r.Context(), tx, *userID, shortID, normalizeTagNames(input.Tags))
 		})
 		if err != nil {
-			http.Error(w, "Failed to create URL", http.StatusInternalServerError)
+			dbError(w, r, "Failed to create URL", err)
 			return
 		}
 
+		fetchMetadataAsync(db, fetcher, shortID, input.LongURL)
+
+		if userID != nil {
+			notifyWebhooks(r.Context(), db, *userID, webhooks.EventLinkCreated, webhooks.LinkFromURL(created))
+		}
+
 		json.NewEncoder(w).Encode(map[string]string{"short_url": shortID})
 	}
 }
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// TagRequest represents the request body for creating or renaming a tag
+type TagRequest struct {
+	Name string `json:"name" example:"marketing" binding:"required"`
+}
+
+// TagInfo represents tag information
+type TagInfo struct {
+	TagID     string    `json:"tag_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name      string    `json:"name" example:"marketing"`
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// ListTagsResponse represents the response for listing tags
+type ListTagsResponse struct {
+	Tags []TagInfo `json:"tags"`
+}
+
+// TagAnalytics represents click totals rolled up for a single tag
+type TagAnalytics struct {
+	TagID      string `json:"tag_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name       string `json:"name" example:"marketing"`
+	URLCount   int64  `json:"url_count" example:"12"`
+	ClickCount int64  `json:"click_count" example:"3400"`
+}
+
+// TagAnalyticsResponse represents the per-tag analytics rollup
+type TagAnalyticsResponse struct {
+	Tags []TagAnalytics `json:"tags"`
+}
+
+// TagURLAnalyticsResponse represents click counts for the URLs in one tag
+type TagURLAnalyticsResponse struct {
+	TagID       string           `json:"tag_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name        string           `json:"name" example:"marketing"`
+	TotalClicks int64            `json:"total_clicks" example:"3400"`
+	URLs        map[string]int64 `json:"urls"`
+}
+
+func tagToInfo(tag sqlc.Tag) TagInfo {
+	return TagInfo{
+		TagID:     tag.TagID.String(),
+		Name:      tag.Name,
+		CreatedAt: tag.CreatedAt.Time,
+	}
+}
+
+// normalizeTagNames trims, lowercases and de-duplicates tag names, dropping
+// empty entries.
+func normalizeTagNames(names []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// setURLTags replaces the tags on a URL, creating any tags the user does not
+// have yet, and returns the resulting tag names.
+func setURLTags(ctx context.Context, db *sqlc.Queries, userID uuid.UUID, shortID string, names []string) ([]string, error) {
+	names = normalizeTagNames(names)
+
+	// Tags are replaced in one transaction so a failure keeps the old ones
+	err := db.InTx(ctx, func(tx *sqlc.Queries) error {
+		return replaceURLTags(ctx, tx, userID, shortID, names)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// replaceURLTags swaps the tags on a URL for the given normalized names. Run
+// it inside a transaction.
+func replaceURLTags(ctx context.Context, tx *sqlc.Queries, userID uuid.UUID, shortID string, names []string) error {
+	if err := tx.ClearURLTags(ctx, shortID); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		tag, err := tx.GetOrCreateTag(ctx, sqlc.GetOrCreateTagParams{
+			TagID:     uuid.New(),
+			UserID:    userID,
+			Name:      name,
+			CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.AddURLTag(ctx, sqlc.AddURLTagParams{ShortID: shortID, TagID: tag.TagID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addURLTags copies the tag names of URLs into their response maps, keyed by
+// short ID, with one query for all of them
+func addURLTags(ctx context.Context, db *sqlc.Queries, urls map[string]map[string]interface{}) error {
+	shortIDs := make([]string, 0, len(urls))
+	tags := make(map[string][]string, len(urls))
+	for shortID := range urls {
+		shortIDs = append(shortIDs, shortID)
+		tags[shortID] = []string{}
+	}
+	if len(shortIDs) == 0 {
+		return nil
+	}
+
+	rows, err := db.ListTagNamesForURLs(ctx, shortIDs)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		tags[row.ShortID] = append(tags[row.ShortID], row.Name)
+	}
+	for shortID, data := range urls {
+		data["tags"] = tags[shortID]
+	}
+	return nil
+}
+
+// CreateTag creates a tag for the authenticated user
+// @Summary Create Tag
+// @Description Create a tag for organizing URLs
+// @Tags tags
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param tag body TagRequest true "Tag to create"
+// @Success 200 {object} TagInfo "Tag created successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 409 {object} problem.Problem "A tag with this name already exists"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/tags [post]
+func CreateTag(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var input TagRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		names := normalizeTagNames([]string{input.Name})
+		if len(names) == 0 {
+			problem.Error(w, "Tag name is required", http.StatusBadRequest)
+			return
+		}
+
+		tag, err := db.CreateTag(r.Context(), sqlc.CreateTagParams{
+			TagID:     uuid.New(),
+			UserID:    userID,
+			Name:      names[0],
+			CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			dbError(w, r, "Failed to create tag", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tagToInfo(tag))
+	}
+}
+
+// ListTags lists all tags for the authenticated user
+// @Summary List Tags
+// @Description List all tags created by the authenticated user
+// @Tags tags
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} ListTagsResponse "Tags retrieved successfully"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/tags [get]
+func ListTags(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		tags, err := db.ListUserTags(r.Context(), userID)
+		if err != nil {
+			serverError(w, r, "Failed to fetch tags", err)
+			return
+		}
+
+		response := ListTagsResponse{Tags: []TagInfo{}}
+		for _, tag := range tags {
+			response.Tags = append(response.Tags, tagToInfo(tag))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UpdateTag renames a tag for the authenticated user
+// @Summary Update Tag
+// @Description Rename a tag owned by the authenticated user
+// @Tags tags
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param tagID path string true "Tag ID"
+// @Param tag body TagRequest true "New tag name"
+// @Success 200 {object} TagInfo "Tag updated successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Tag not found"
+// @Failure 409 {object} problem.Problem "A tag with this name already exists"
+// @Router /api/tags/{tagID} [put]
+func UpdateTag(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		tagID, err := uuid.Parse(chi.URLParam(r, "tagID"))
+		if err != nil {
+			problem.Error(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+
+		var input TagRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		names := normalizeTagNames([]string{input.Name})
+		if len(names) == 0 {
+			problem.Error(w, "Tag name is required", http.StatusBadRequest)
+			return
+		}
+
+		tag, err := db.UpdateTag(r.Context(), sqlc.UpdateTagParams{
+			TagID:  tagID,
+			UserID: userID,
+			Name:   names[0],
+		})
+		if err != nil {
+			rowError(w, r, "Tag not found", "Failed to update tag", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tagToInfo(tag))
+	}
+}
+
+// DeleteTag deletes a tag for the authenticated user
+// @Summary Delete Tag
+// @Description Delete a tag owned by the authenticated user. Tagged URLs are kept.
+// @Tags tags
+// @Security ApiKeyAuth
+// @Param tagID path string true "Tag ID"
+// @Produce json
+// @Success 200 {object} map[string]string "Tag deleted successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Tag not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/tags/{tagID} [delete]
+func DeleteTag(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		tagID, err := uuid.Parse(chi.URLParam(r, "tagID"))
+		if err != nil {
+			problem.Error(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := db.DeleteTag(r.Context(), sqlc.DeleteTagParams{
+			TagID:  tagID,
+			UserID: userID,
+		})
+		if err != nil {
+			serverError(w, r, "Failed to delete tag", err)
+			return
+		}
+		if deleted == 0 {
+			problem.Error(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Tag deleted successfully",
+		})
+	}
+}
+
+// GetTagAnalytics gets click totals rolled up per tag
+// @Summary Get Tag Analytics
+// @Description Get URL and click counts for every tag owned by the authenticated user
+// @Tags tags
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} TagAnalyticsResponse "Analytics rolled up per tag"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/tags/analytics [get]
+func GetTagAnalytics(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		rows, err := db.GetTagAnalytics(r.Context(), userID)
+		if err != nil {
+			serverError(w, r, "Failed to fetch tag analytics", err)
+			return
+		}
+
+		response := TagAnalyticsResponse{Tags: []TagAnalytics{}}
+		for _, row := range rows {
+			response.Tags = append(response.Tags, TagAnalytics{
+				TagID:      row.TagID.String(),
+				Name:       row.Name,
+				URLCount:   row.UrlCount,
+				ClickCount: row.ClickCount,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetTagURLAnalytics gets click counts for each URL in a tag
+// @Summary Get Tag URL Analytics
+// @Description Get click counts for every URL carrying a tag owned by the authenticated user
+// @Tags tags
+// @Security ApiKeyAuth
+// @Param tagID path string true "Tag ID"
+// @Produce json
+// @Success 200 {object} TagURLAnalyticsResponse "Click counts by short ID"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Tag not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/tags/{tagID}/analytics [get]
+func GetTagURLAnalytics(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		tagID, err := uuid.Parse(chi.URLParam(r, "tagID"))
+		if err != nil {
+			problem.Error(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+
+		tag, err := db.GetTag(r.Context(), sqlc.GetTagParams{TagID: tagID, UserID: userID})
+		if err != nil {
+			rowError(w, r, "Tag not found", "Failed to fetch tag", err)
+			return
+		}
+
+		rows, err := db.GetTagURLClickCounts(r.Context(), tag.TagID)
+		if err != nil {
+			serverError(w, r, "Failed to fetch tag analytics", err)
+			return
+		}
+
+		response := TagURLAnalyticsResponse{
+			TagID: tag.TagID.String(),
+			Name:  tag.Name,
+			URLs:  map[string]int64{},
+		}
+		for _, row := range rows {
+			response.URLs[row.ShortID] = row.ClickCount
+			response.TotalClicks += row.ClickCount
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
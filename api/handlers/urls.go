@@ -1,23 +1,50 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/counters"
+	"github.com/yeboahd24/url-shortener/logging"
+	"github.com/yeboahd24/url-shortener/metadata"
 	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/webhooks"
 )
 
 // URLInfo represents URL information
 type URLInfo struct {
-	ShortID    string     `json:"short_id" example:"abc123"`
-	LongURL    string     `json:"long_url" example:"https://example.com"`
-	CreatedAt  time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
-	ClickLimit *int32     `json:"click_limit,omitempty" example:"100"`
+	ShortID           string     `json:"short_id" example:"abc123"`
+	LongURL           string     `json:"long_url" example:"https://example.com"`
+	CreatedAt         time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	ActivatesAt       *time.Time `json:"activates_at,omitempty" example:"2024-03-01T09:00:00Z"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	ClickLimit        *int32     `json:"click_limit,omitempty" example:"100"`
+	FolderID          string     `json:"folder_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Tags              []string   `json:"tags" example:"marketing,spring"`
+	Title             string     `json:"title,omitempty" example:"Example Domain"`
+	Description       string     `json:"description,omitempty" example:"Landing page for the spring campaign"`
+	Notes             string     `json:"notes,omitempty" example:"Shared in the March newsletter"`
+	FaviconURL        string     `json:"favicon_url,omitempty" example:"https://example.com/favicon.ico"`
+	RedirectType      string     `json:"redirect_type,omitempty" example:"302"`
+	PasswordProtected bool       `json:"password_protected" example:"false"`
+	QueryMode         string     `json:"query_mode,omitempty" example:"merge"`
+	ForwardPath       bool       `json:"forward_path" example:"false"`
+	IOSAppURL         string     `json:"ios_app_url,omitempty" example:"myapp://product/42"`
+	AndroidAppURL     string     `json:"android_app_url,omitempty" example:"intent://product/42#Intent;scheme=myapp;package=com.example.app;end"`
+	ExpiredURL        string     `json:"expired_redirect_url,omitempty" example:"https://example.com/campaign-ended"`
+	ClickIDMode       string     `json:"click_id_mode,omitempty" example:"query"`
+	ClickCount        int64      `json:"click_count" example:"1520"`
+	UniqueVisitors    int64      `json:"unique_visitors" example:"1184"`
+	ClicksToday       int64      `json:"clicks_today" example:"37"`
 }
 
 // ListURLsResponse represents the response for listing URLs
@@ -27,43 +54,160 @@ type ListURLsResponse struct {
 
 // UpdateURLRequest represents the request body for updating a URL
 type UpdateURLRequest struct {
-	LongURL    *string    `json:"long_url,omitempty" example:"https://new-example.com"`
-	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
-	ClickLimit *int       `json:"click_limit,omitempty" example:"200"`
+	LongURL       *string    `json:"long_url,omitempty" example:"https://new-example.com"`
+	ActivatesAt   *time.Time `json:"activates_at,omitempty" example:"2024-03-01T09:00:00Z"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	ClickLimit    *int       `json:"click_limit,omitempty" example:"200"`
+	Tags          *[]string  `json:"tags,omitempty" example:"marketing,spring"`
+	FolderID      *string    `json:"folder_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Title         *string    `json:"title,omitempty" example:"Example Domain"`
+	Description   *string    `json:"description,omitempty" example:"Landing page for the spring campaign"`
+	Notes         *string    `json:"notes,omitempty" example:"Shared in the March newsletter"`
+	RedirectType  *string    `json:"redirect_type,omitempty" enums:"301,302,307,308,interstitial" example:"307"`
+	Password      *string    `json:"password,omitempty" example:"s3cret"`
+	QueryMode     *string    `json:"query_mode,omitempty" enums:"merge,override" example:"override"`
+	ForwardPath   *bool      `json:"forward_path,omitempty" example:"true"`
+	IOSAppURL     *string    `json:"ios_app_url,omitempty" example:"https://app.example.com/product/42"`
+	AndroidAppURL *string    `json:"android_app_url,omitempty" example:"myapp://product/42"`
+	ExpiredURL    *string    `json:"expired_redirect_url,omitempty" example:"https://example.com/campaign-ended"`
+	ClickIDMode   *string    `json:"click_id_mode,omitempty" enums:"query,cookie" example:"cookie"`
+}
+
+// addURLMetadata copies the metadata and redirect settings of a URL into its
+// response map.
+func addURLMetadata(data map[string]interface{}, url sqlc.Url) {
+	if url.Title.Valid {
+		data["title"] = url.Title.String
+	}
+
+	if url.Description.Valid {
+		data["description"] = url.Description.String
+	}
+
+	if url.Notes.Valid {
+		data["notes"] = url.Notes.String
+	}
+
+	if url.FaviconUrl.Valid {
+		data["favicon_url"] = url.FaviconUrl.String
+	}
+
+	if url.RedirectType.Valid {
+		data["redirect_type"] = url.RedirectType.String
+	}
+
+	data["password_protected"] = url.PasswordHash.Valid
+
+	if url.QueryMode.Valid {
+		data["query_mode"] = url.QueryMode.String
+	}
+
+	data["forward_path"] = url.ForwardPath
+
+	if url.IosAppUrl.Valid {
+		data["ios_app_url"] = url.IosAppUrl.String
+	}
+
+	if url.AndroidAppUrl.Valid {
+		data["android_app_url"] = url.AndroidAppUrl.String
+	}
+
+	if url.ActivatesAt.Valid {
+		data["activates_at"] = url.ActivatesAt.Time
+	}
+
+	if url.ExpiredRedirectUrl.Valid {
+		data["expired_redirect_url"] = url.ExpiredRedirectUrl.String
+	}
+
+	if url.ClickIDMode.Valid {
+		data["click_id_mode"] = url.ClickIDMode.String
+	}
+}
+
+// addURLCounters copies live click counters into URL response maps, keyed by
+// short ID. Links without counters report zero.
+func addURLCounters(ctx context.Context, db *sqlc.Queries, redisClient *redis.Client, urls map[string]map[string]interface{}) error {
+	shortIDs := make([]string, 0, len(urls))
+	for shortID := range urls {
+		shortIDs = append(shortIDs, shortID)
+	}
+
+	counts, err := counters.Get(ctx, redisClient, db, shortIDs)
+	if err != nil {
+		return err
+	}
+	for shortID, data := range urls {
+		c := counts[shortID]
+		data["click_count"] = c.Clicks
+		data["unique_visitors"] = c.UniqueVisitors
+		data["clicks_today"] = c.ClicksToday
+	}
+	return nil
 }
 
 // ListUserURLs lists all URLs for the authenticated user
 // @Summary List User URLs
-// @Description List all URLs created by the authenticated user
+// @Description List all URLs created by the authenticated user, optionally filtered by tag name or folder. Each URL includes live click counters: click_count, unique_visitors (approximate) and clicks_today (UTC).
 // @Tags urls
 // @Security ApiKeyAuth
+// @Param tag query string false "Only list URLs carrying this tag"
+// @Param folder_id query string false "Only list URLs in this folder"
 // @Produce json
 // @Success 200 {object} ListURLsResponse "URLs retrieved successfully"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/urls [get]
-func ListUserURLs(db *sqlc.Queries) http.HandlerFunc {
+func ListUserURLs(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userIDStr, ok := r.Context().Value("user_id").(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
 			return
 		}
 
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		urls, err := db.ListUserURLs(r.Context(), sqlc.UUIDToNullable(&userID))
+		var urls []sqlc.Url
+		switch {
+		case r.URL.Query().Get("tag") != "":
+			tag, tagErr := db.GetTagByName(r.Context(), sqlc.GetTagByNameParams{
+				UserID: userID,
+				Name:   strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag"))),
+			})
+			if tagErr != nil {
+				rowError(w, r, "Tag not found", "Failed to fetch tag", tagErr)
+				return
+			}
+			urls, err = db.ListTagURLs(r.Context(), sqlc.ListTagURLsParams{
+				UserID: sqlc.UUIDToNullable(&userID),
+				TagID:  tag.TagID,
+			})
+		case r.URL.Query().Get("folder_id") != "":
+			folderID, folderErr := resolveFolderID(r.Context(), db, userID, r.URL.Query().Get("folder_id"))
+			if folderErr != nil {
+				dbError(w, r, "Failed to fetch folder", folderErr)
+				return
+			}
+			urls, err = db.ListFolderURLs(r.Context(), sqlc.ListFolderURLsParams{
+				UserID:   sqlc.UUIDToNullable(&userID),
+				FolderID: folderID,
+			})
+		default:
+			urls, err = db.ListUserURLs(r.Context(), sqlc.UUIDToNullable(&userID))
+		}
 		if err != nil {
-			http.Error(w, "Failed to fetch URLs", http.StatusInternalServerError)
+			serverError(w, r, "Failed to fetch URLs", err)
 			return
 		}
 
 		// Convert to a more user-friendly format
 		var response []map[string]interface{}
+		byShortID := make(map[string]map[string]interface{}, len(urls))
 		for _, url := range urls {
 			urlData := map[string]interface{}{
 				"short_id":   url.ShortID,
@@ -79,7 +223,24 @@ func ListUserURLs(db *sqlc.Queries) http.HandlerFunc {
 				urlData["click_limit"] = url.ClickLimit.Int32
 			}
 
+			if url.FolderID.Valid {
+				urlData["folder_id"] = uuid.UUID(url.FolderID.Bytes).String()
+			}
+
+			addURLMetadata(urlData, url)
+
 			response = append(response, urlData)
+			byShortID[url.ShortID] = urlData
+		}
+
+		if err := addURLTags(r.Context(), db, byShortID); err != nil {
+			serverError(w, r, "Failed to fetch tags", err)
+			return
+		}
+
+		if err := addURLCounters(r.Context(), db, redisClient, byShortID); err != nil {
+			serverError(w, r, "Failed to fetch click counters", err)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -97,39 +258,56 @@ func ListUserURLs(db *sqlc.Queries) http.HandlerFunc {
 // @Param shortID path string true "Short URL ID"
 // @Produce json
 // @Success 200 {object} map[string]string "URL deleted successfully"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Failed to delete URL or URL not found"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Failed to delete URL or URL not found"
 // @Router /api/urls/{shortID} [delete]
-func DeleteURL(db *sqlc.Queries) http.HandlerFunc {
+func DeleteURL(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		shortID := chi.URLParam(r, "shortID")
 		if shortID == "" {
-			http.Error(w, "Short ID is required", http.StatusBadRequest)
+			problem.Error(w, "Short ID is required", http.StatusBadRequest)
 			return
 		}
 
 		userIDStr, ok := r.Context().Value("user_id").(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
 			return
 		}
 
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		err = db.DeleteURL(r.Context(), sqlc.DeleteURLParams{
+		// Looked up first so the webhook event can describe the deleted link
+		link, err := db.GetOwnedURL(r.Context(), shortID, userID)
+		if err != nil {
+			dbError(w, r, "Failed to delete URL", err)
+			return
+		}
+
+		deleted, err := db.DeleteURL(r.Context(), sqlc.DeleteURLParams{
 			ShortID: shortID,
 			UserID:  sqlc.UUIDToNullable(&userID),
 		})
-
 		if err != nil {
-			http.Error(w, "Failed to delete URL or URL not found", http.StatusInternalServerError)
+			serverError(w, r, "Failed to delete URL", err)
+			return
+		}
+		if deleted == 0 {
+			problem.Error(w, "URL not found", http.StatusNotFound)
 			return
 		}
 
+		invalidateURLCache(r.Context(), redisClient, shortID)
+		redisClient.Del(r.Context(), counters.Keys(shortID)...)
+
+		notifyWebhooks(r.Context(), db, userID, webhooks.EventLinkDeleted, webhooks.LinkFromURL(link))
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"message": "URL deleted successfully",
@@ -147,58 +325,75 @@ func DeleteURL(db *sqlc.Queries) http.HandlerFunc {
 // @Param shortID path string true "Short URL ID"
 // @Param url body UpdateURLRequest true "URL update information"
 // @Success 200 {object} URLInfo "URL updated successfully"
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 404 {object} map[string]string "URL not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/urls/{shortID} [put]
-func UpdateURL(db *sqlc.Queries) http.HandlerFunc {
+func UpdateURL(db *sqlc.Queries, redisClient *redis.Client, fetcher *metadata.Fetcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		shortID := chi.URLParam(r, "shortID")
 		if shortID == "" {
-			http.Error(w, "Short ID is required", http.StatusBadRequest)
+			problem.Error(w, "Short ID is required", http.StatusBadRequest)
 			return
 		}
 
 		userIDStr, ok := r.Context().Value("user_id").(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
 			return
 		}
 
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
 		var input struct {
-			LongURL    *string    `json:"long_url,omitempty"`
-			ExpiresAt  *time.Time `json:"expires_at,omitempty"`
-			ClickLimit *int       `json:"click_limit,omitempty"`
+			LongURL       *string    `json:"long_url,omitempty"`
+			ActivatesAt   *time.Time `json:"activates_at,omitempty"`
+			ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+			ClickLimit    *int       `json:"click_limit,omitempty"`
+			Tags          *[]string  `json:"tags,omitempty"`
+			FolderID      *string    `json:"folder_id,omitempty"`
+			Title         *string    `json:"title,omitempty"`
+			Description   *string    `json:"description,omitempty"`
+			Notes         *string    `json:"notes,omitempty"`
+			RedirectType  *string    `json:"redirect_type,omitempty"`
+			Password      *string    `json:"password,omitempty"`
+			QueryMode     *string    `json:"query_mode,omitempty"`
+			ForwardPath   *bool      `json:"forward_path,omitempty"`
+			IOSAppURL     *string    `json:"ios_app_url,omitempty"`
+			AndroidAppURL *string    `json:"android_app_url,omitempty"`
+			ExpiredURL    *string    `json:"expired_redirect_url,omitempty"`
+			ClickIDMode   *string    `json:"click_id_mode,omitempty"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
 		// Get current URL to use as defaults for unspecified fields
-		currentURL, err := db.GetURL(r.Context(), shortID)
+		currentURL, err := db.GetOwnedURL(r.Context(), shortID, userID)
 		if err != nil {
-			http.Error(w, "URL not found", http.StatusNotFound)
-			return
-		}
-
-		// Check if user owns the URL
-		if !currentURL.UserID.Valid || currentURL.UserID.Bytes != userID {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			dbError(w, r, "Failed to fetch URL", err)
 			return
 		}
 
 		// Use current values as defaults, override with input if provided
 		longURL := currentURL.LongUrl
 		if input.LongURL != nil {
+			if !validDestination(*input.LongURL) {
+				problem.Error(w, "long_url must be an http or https URL", http.StatusBadRequest)
+				return
+			}
+			if !validPathTemplate(*input.LongURL) {
+				problem.Error(w, "{path} must come right after a / in the path of long_url", http.StatusBadRequest)
+				return
+			}
 			longURL = *input.LongURL
 		}
 
@@ -207,24 +402,187 @@ func UpdateURL(db *sqlc.Queries) http.HandlerFunc {
 			expiresAt = pgtype.Timestamp{Time: *input.ExpiresAt, Valid: true}
 		}
 
+		activatesAt := currentURL.ActivatesAt
+		if input.ActivatesAt != nil {
+			activatesAt = pgtype.Timestamp{Time: *input.ActivatesAt, Valid: true}
+		}
+
+		if !validSchedule(activatesAt, expiresAt) {
+			problem.Error(w, "activates_at must be before expires_at", http.StatusBadRequest)
+			return
+		}
+
 		clickLimit := currentURL.ClickLimit
 		if input.ClickLimit != nil {
 			clickLimit = pgtype.Int4{Int32: int32(*input.ClickLimit), Valid: true}
 		}
 
+		// An empty folder_id moves the URL out of its folder
+		folderID := currentURL.FolderID
+		if input.FolderID != nil {
+			folderID, err = resolveFolderID(r.Context(), db, userID, *input.FolderID)
+			if errors.Is(err, sqlc.ErrNotFound) {
+				problem.Error(w, "Folder not found", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				serverError(w, r, "Failed to fetch folder", err)
+				return
+			}
+		}
+
+		// An empty string clears the corresponding metadata field
+		title := currentURL.Title
+		if input.Title != nil {
+			title = stringToNullable(*input.Title)
+		}
+
+		description := currentURL.Description
+		if input.Description != nil {
+			description = stringToNullable(*input.Description)
+		}
+
+		// Metadata of the old page does not describe the new destination;
+		// unless the update sets it, it is cleared for the refetch to fill in
+		if longURL != currentURL.LongUrl {
+			if input.Title == nil {
+				title = pgtype.Text{Valid: false}
+			}
+			if input.Description == nil {
+				description = pgtype.Text{Valid: false}
+			}
+		}
+
+		notes := currentURL.Notes
+		if input.Notes != nil {
+			notes = stringToNullable(*input.Notes)
+		}
+
+		redirectType := currentURL.RedirectType
+		if input.RedirectType != nil {
+			if *input.RedirectType != "" && !ValidRedirectType(*input.RedirectType) {
+				problem.Error(w, "Invalid redirect type", http.StatusBadRequest)
+				return
+			}
+			redirectType = stringToNullable(*input.RedirectType)
+		}
+
+		// An empty password removes the protection
+		passwordHash := currentURL.PasswordHash
+		if input.Password != nil {
+			if !validLinkPassword(*input.Password) {
+				problem.Error(w, "password must be at most 72 bytes", http.StatusBadRequest)
+				return
+			}
+			hash, err := hashLinkPassword(*input.Password)
+			if err != nil {
+				serverError(w, r, "Failed to hash password", err)
+				return
+			}
+			passwordHash = stringToNullable(hash)
+		}
+
+		// An empty query mode stops forwarding the query string
+		queryMode := currentURL.QueryMode
+		if input.QueryMode != nil {
+			if *input.QueryMode != "" && !ValidQueryMode(*input.QueryMode) {
+				problem.Error(w, "Invalid query mode", http.StatusBadRequest)
+				return
+			}
+			queryMode = stringToNullable(*input.QueryMode)
+		}
+
+		forwardPath := currentURL.ForwardPath
+		if input.ForwardPath != nil {
+			forwardPath = *input.ForwardPath
+		}
+
+		// An empty app URL removes the deep link for that platform
+		iosAppURL := currentURL.IosAppUrl
+		if input.IOSAppURL != nil {
+			if *input.IOSAppURL != "" && !ValidAppURL(*input.IOSAppURL) {
+				problem.Error(w, "Invalid app URL", http.StatusBadRequest)
+				return
+			}
+			iosAppURL = stringToNullable(*input.IOSAppURL)
+		}
+
+		androidAppURL := currentURL.AndroidAppUrl
+		if input.AndroidAppURL != nil {
+			if *input.AndroidAppURL != "" && !ValidAppURL(*input.AndroidAppURL) {
+				problem.Error(w, "Invalid app URL", http.StatusBadRequest)
+				return
+			}
+			androidAppURL = stringToNullable(*input.AndroidAppURL)
+		}
+
+		// An empty expired redirect URL makes expired visits answer 410 again
+		expiredURL := currentURL.ExpiredRedirectUrl
+		if input.ExpiredURL != nil {
+			if *input.ExpiredURL != "" && !validWebURL(*input.ExpiredURL) {
+				problem.Error(w, "Invalid expired redirect URL", http.StatusBadRequest)
+				return
+			}
+			expiredURL = stringToNullable(*input.ExpiredURL)
+		}
+
+		// An empty click ID mode stops passing click IDs on
+		clickIDMode := currentURL.ClickIDMode
+		if input.ClickIDMode != nil {
+			if *input.ClickIDMode != "" && !ValidClickIDMode(*input.ClickIDMode) {
+				problem.Error(w, "Invalid click ID mode", http.StatusBadRequest)
+				return
+			}
+			clickIDMode = stringToNullable(*input.ClickIDMode)
+		}
+
 		updatedURL, err := db.UpdateURL(r.Context(), sqlc.UpdateURLParams{
-			ShortID:    shortID,
-			LongUrl:    longURL,
-			ExpiresAt:  expiresAt,
-			ClickLimit: clickLimit,
-			UserID:     sqlc.UUIDToNullable(&userID),
+			ShortID:            shortID,
+			LongUrl:            longURL,
+			ExpiresAt:          expiresAt,
+			ClickLimit:         clickLimit,
+			UserID:             sqlc.UUIDToNullable(&userID),
+			FolderID:           folderID,
+			Title:              title,
+			Description:        description,
+			Notes:              notes,
+			RedirectType:       redirectType,
+			PasswordHash:       passwordHash,
+			QueryMode:          queryMode,
+			ForwardPath:        forwardPath,
+			IosAppUrl:          iosAppURL,
+			AndroidAppUrl:      androidAppURL,
+			ActivatesAt:        activatesAt,
+			ExpiredRedirectUrl: expiredURL,
+			ClickIDMode:        clickIDMode,
 		})
 
 		if err != nil {
-			http.Error(w, "Failed to update URL or URL not found", http.StatusInternalServerError)
+			rowError(w, r, "URL not found", "Failed to update URL", err)
 			return
 		}
 
+		invalidateURLCache(r.Context(), redisClient, shortID)
+
+		// A new expiry or click limit can expire the link again later
+		if input.ExpiresAt != nil || input.ClickLimit != nil {
+			if err := db.ClearExpiryNotification(r.Context(), shortID); err != nil {
+				logging.FromContext(r.Context()).Error("Failed to clear expiry notification", "short_id", shortID, "error", err)
+			}
+		}
+
+		if updatedURL.LongUrl != currentURL.LongUrl {
+			fetchMetadataAsync(db, fetcher, updatedURL.ShortID, updatedURL.LongUrl)
+		}
+
+		// An empty tags list removes all tags from the URL
+		if input.Tags != nil {
+			if _, err := setURLTags(r.Context(), db, userID, shortID, *input.Tags); err != nil {
+				dbError(w, r, "Failed to tag URL", err)
+				return
+			}
+		}
+
 		// Convert response to user-friendly format
 		response := map[string]interface{}{
 			"short_id":   updatedURL.ShortID,
@@ -240,6 +598,20 @@ func UpdateURL(db *sqlc.Queries) http.HandlerFunc {
 			response["click_limit"] = updatedURL.ClickLimit.Int32
 		}
 
+		if updatedURL.FolderID.Valid {
+			response["folder_id"] = uuid.UUID(updatedURL.FolderID.Bytes).String()
+		}
+
+		addURLMetadata(response, updatedURL)
+
+		notifyWebhooks(r.Context(), db, userID, webhooks.EventLinkUpdated, webhooks.LinkFromURL(updatedURL))
+
+		// The update went through, so missing tags or counters don't fail the
+		// request
+		byShortID := map[string]map[string]interface{}{updatedURL.ShortID: response}
+		addURLTags(r.Context(), db, byShortID)
+		addURLCounters(r.Context(), db, redisClient, byShortID)
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/api/problem"
 	"github.com/yeboahd24/url-shortener/queries/sqlc"
 )
 
@@ -32,8 +33,9 @@ type CreateUserResponse struct {
 // @Produce json
 // @Param user body CreateUserRequest true "User information"
 // @Success 200 {object} CreateUserResponse "User created successfully"
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 409 {object} problem.Problem "Username or email already in use"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /users [post]
 func CreateUser(db *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -43,12 +45,12 @@ func CreateUser(db *sqlc.Queries) http.HandlerFunc {
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
 		if input.Username == "" || input.Email == "" {
-			http.Error(w, "Username and email are required", http.StatusBadRequest)
+			problem.Error(w, "Username and email are required", http.StatusBadRequest)
 			return
 		}
 
@@ -61,7 +63,7 @@ func CreateUser(db *sqlc.Queries) http.HandlerFunc {
 		})
 
 		if err != nil {
-			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			dbError(w, r, "Failed to create user", err)
 			return
 		}
 
@@ -89,20 +91,20 @@ type CreateAPIKeyResponse struct {
 // @Security ApiKeyAuth
 // @Produce json
 // @Success 200 {object} CreateAPIKeyResponse "API key created successfully"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/keys [post]
 func CreateAPIKey(db *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userIDStr, ok := r.Context().Value("user_id").(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
 			return
 		}
 
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
@@ -114,7 +116,7 @@ func CreateAPIKey(db *sqlc.Queries) http.HandlerFunc {
 		})
 
 		if err != nil {
-			http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+			dbError(w, r, "Failed to create API key", err)
 			return
 		}
 
@@ -146,26 +148,26 @@ type APIKeyInfo struct {
 // @Security ApiKeyAuth
 // @Produce json
 // @Success 200 {object} ListAPIKeysResponse "API keys retrieved successfully"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/keys [get]
 func ListAPIKeys(db *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userIDStr, ok := r.Context().Value("user_id").(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
 			return
 		}
 
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
 		keys, err := db.ListUserAPIKeys(r.Context(), userID)
 		if err != nil {
-			http.Error(w, "Failed to fetch API keys", http.StatusInternalServerError)
+			serverError(w, r, "Failed to fetch API keys", err)
 			return
 		}
 
@@ -190,21 +192,22 @@ type DeleteAPIKeyRequest struct {
 // @Produce json
 // @Param apikey body DeleteAPIKeyRequest true "API key to delete"
 // @Success 200 {object} map[string]string "API key deleted successfully"
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "API key not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/keys [delete]
 func DeleteAPIKey(db *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userIDStr, ok := r.Context().Value("user_id").(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
 			return
 		}
 
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
@@ -213,23 +216,27 @@ func DeleteAPIKey(db *sqlc.Queries) http.HandlerFunc {
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
 		apiKey, err := uuid.Parse(input.APIKey)
 		if err != nil {
-			http.Error(w, "Invalid API key format", http.StatusBadRequest)
+			problem.Error(w, "Invalid API key format", http.StatusBadRequest)
 			return
 		}
 
-		err = db.DeleteAPIKey(r.Context(), sqlc.DeleteAPIKeyParams{
+		deleted, err := db.DeleteAPIKey(r.Context(), sqlc.DeleteAPIKeyParams{
 			Key:    apiKey,
 			UserID: userID,
 		})
 
 		if err != nil {
-			http.Error(w, "Failed to delete API key", http.StatusInternalServerError)
+			serverError(w, r, "Failed to delete API key", err)
+			return
+		}
+		if deleted == 0 {
+			problem.Error(w, "API key not found", http.StatusNotFound)
 			return
 		}
 
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+const variantCookiePrefix = "ab_"
+
+// maxVariantWeight caps the weight of one variant
+const maxVariantWeight = 10000
+
+// URLVariantRequest represents one weighted destination of an A/B split
+type URLVariantRequest struct {
+	Name        string `json:"name" example:"B" binding:"required"`
+	Destination string `json:"destination" example:"https://example.com/landing-b" binding:"required"`
+	Weight      int32  `json:"weight" example:"30" binding:"required"`
+}
+
+// ReplaceURLVariantsRequest represents the full list of variants for a URL
+type ReplaceURLVariantsRequest struct {
+	Variants []URLVariantRequest `json:"variants"`
+}
+
+// URLVariantInfo represents A/B variant information
+type URLVariantInfo struct {
+	VariantID   string    `json:"variant_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name        string    `json:"name" example:"B"`
+	Destination string    `json:"destination" example:"https://example.com/landing-b"`
+	Weight      int32     `json:"weight" example:"30"`
+	CreatedAt   time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// ListURLVariantsResponse represents the response for listing A/B variants
+type ListURLVariantsResponse struct {
+	Variants []URLVariantInfo `json:"variants"`
+}
+
+// cachedVariant is an A/B variant as stored in the Redis URL cache
+type cachedVariant struct {
+	ID          string `json:"id"`
+	Destination string `json:"destination"`
+	Weight      int32  `json:"weight"`
+}
+
+func variantToInfo(variant sqlc.UrlVariant) URLVariantInfo {
+	return URLVariantInfo{
+		VariantID:   variant.VariantID.String(),
+		Name:        variant.Name,
+		Destination: variant.Destination,
+		Weight:      variant.Weight,
+		CreatedAt:   variant.CreatedAt.Time,
+	}
+}
+
+// loadCachedVariants converts the stored variants of a URL into cache entries
+func loadCachedVariants(ctx context.Context, db *sqlc.Queries, shortID string) ([]cachedVariant, error) {
+	variants, err := db.ListURLVariants(ctx, shortID)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := make([]cachedVariant, 0, len(variants))
+	for _, variant := range variants {
+		cached = append(cached, cachedVariant{
+			ID:          variant.VariantID.String(),
+			Destination: variant.Destination,
+			Weight:      variant.Weight,
+		})
+	}
+	return cached, nil
+}
+
+// pickVariant chooses the variant a visitor is sent to. Returning visitors
+// keep the variant named in their cookie as long as it still exists;
+// everyone else gets a weighted random pick, which is then made sticky.
+func pickVariant(w http.ResponseWriter, r *http.Request, shortID string, variants []cachedVariant) cachedVariant {
+	if cookie, err := r.Cookie(variantCookiePrefix + shortID); err == nil {
+		for _, variant := range variants {
+			if variant.ID == cookie.Value {
+				return variant
+			}
+		}
+	}
+
+	var total int64
+	for _, variant := range variants {
+		total += int64(variant.Weight)
+	}
+
+	chosen := variants[len(variants)-1]
+	n := rand.Int63n(total)
+	for _, variant := range variants {
+		if n < int64(variant.Weight) {
+			chosen = variant
+			break
+		}
+		n -= int64(variant.Weight)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookiePrefix + shortID,
+		Value:    chosen.ID,
+		Path:     "/" + shortID,
+		MaxAge:   int((30 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return chosen
+}
+
+func validateURLVariants(variants []URLVariantRequest) error {
+	seen := map[string]bool{}
+	for i, variant := range variants {
+		name := strings.TrimSpace(variant.Name)
+		if name == "" {
+			return fmt.Errorf("variant %d: name is required", i+1)
+		}
+		if seen[name] {
+			return fmt.Errorf("variant %d: duplicate name %q", i+1, name)
+		}
+		seen[name] = true
+
+		if strings.TrimSpace(variant.Destination) == "" {
+			return fmt.Errorf("variant %d: destination is required", i+1)
+		}
+		if !validDestination(variant.Destination) {
+			return fmt.Errorf("variant %d: destination must be an http or https URL", i+1)
+		}
+		if !validPathTemplate(variant.Destination) {
+			return fmt.Errorf("variant %d: {path} must come right after a / in the destination path", i+1)
+		}
+		if variant.Weight < 1 || variant.Weight > maxVariantWeight {
+			return fmt.Errorf("variant %d: weight must be between 1 and %d", i+1, maxVariantWeight)
+		}
+	}
+	return nil
+}
+
+func writeURLVariants(w http.ResponseWriter, r *http.Request, db *sqlc.Queries, shortID string) {
+	variants, err := db.ListURLVariants(r.Context(), shortID)
+	if err != nil {
+		serverError(w, r, "Failed to fetch variants", err)
+		return
+	}
+
+	response := ListURLVariantsResponse{Variants: []URLVariantInfo{}}
+	for _, variant := range variants {
+		response.Variants = append(response.Variants, variantToInfo(variant))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListURLVariants lists the A/B split destinations of a URL
+// @Summary List A/B Variants
+// @Description List the weighted destinations a URL's traffic is split across
+// @Tags variants
+// @Security ApiKeyAuth
+// @Param shortID path string true "Short URL ID"
+// @Produce json
+// @Success 200 {object} ListURLVariantsResponse "A/B variants"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/urls/{shortID}/variants [get]
+func ListURLVariants(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		url, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+		writeURLVariants(w, r, db, url.ShortID)
+	}
+}
+
+// ReplaceURLVariants sets the A/B split destinations of a URL
+// @Summary Replace A/B Variants
+// @Description Split a URL's traffic across weighted destinations (e.g. 70/30). Weights range from 1 to 10000 and destinations must be http(s) URLs. Variants are matched by name, so keeping a name keeps its click history. Variants missing from the list are removed; an empty list turns the split off. The list is replaced as a whole or not at all. Visitors stick to their variant through a cookie. Conditional redirect rules take precedence over the split.
+// @Tags variants
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param shortID path string true "Short URL ID"
+// @Param variants body ReplaceURLVariantsRequest true "Variants"
+// @Success 200 {object} ListURLVariantsResponse "Variants replaced"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 403 {object} problem.Problem "URL belongs to another user"
+// @Failure 404 {object} problem.Problem "URL not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/urls/{shortID}/variants [put]
+func ReplaceURLVariants(db *sqlc.Queries, redisClient *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		url, ok := ownedURL(w, r, db)
+		if !ok {
+			return
+		}
+
+		var input ReplaceURLVariantsRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := validateURLVariants(input.Variants); err != nil {
+			problem.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Variants are replaced in one transaction so a failure keeps the
+		// previous split
+		err := db.InTx(r.Context(), func(tx *sqlc.Queries) error {
+			existing, err := tx.ListURLVariants(r.Context(), url.ShortID)
+			if err != nil {
+				return err
+			}
+			byName := map[string]sqlc.UrlVariant{}
+			for _, variant := range existing {
+				byName[variant.Name] = variant
+			}
+
+			for _, variant := range input.Variants {
+				name := strings.TrimSpace(variant.Name)
+				if current, ok := byName[name]; ok {
+					_, err = tx.UpdateURLVariant(r.Context(), sqlc.UpdateURLVariantParams{
+						VariantID:   current.VariantID,
+						Destination: variant.Destination,
+						Weight:      variant.Weight,
+					})
+					delete(byName, name)
+				} else {
+					_, err = tx.CreateURLVariant(r.Context(), sqlc.CreateURLVariantParams{
+						VariantID:   uuid.New(),
+						ShortID:     url.ShortID,
+						Name:        name,
+						Destination: variant.Destination,
+						Weight:      variant.Weight,
+						CreatedAt:   pgtype.Timestamp{Time: time.Now(), Valid: true},
+					})
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, removed := range byName {
+				if err := tx.DeleteURLVariant(r.Context(), removed.VariantID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			dbError(w, r, "Failed to save variants", err)
+			return
+		}
+
+		invalidateURLCache(r.Context(), redisClient, url.ShortID)
+		writeURLVariants(w, r, db, url.ShortID)
+	}
+}
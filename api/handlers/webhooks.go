@@ -0,0 +1,572 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/logging"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/webhooks"
+)
+
+// Delivery list limits
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// WebhookRequest represents the request body for creating a webhook
+type WebhookRequest struct {
+	URL    string   `json:"url" example:"https://crm.example.com/hooks/links" binding:"required"`
+	Events []string `json:"events" example:"link.created,link.clicked" binding:"required"`
+}
+
+// UpdateWebhookRequest represents the request body for updating a webhook
+type UpdateWebhookRequest struct {
+	URL    *string   `json:"url,omitempty" example:"https://crm.example.com/hooks/links"`
+	Events *[]string `json:"events,omitempty" example:"link.created,link.deleted"`
+	Active *bool     `json:"active,omitempty" example:"false"`
+}
+
+// WebhookInfo represents webhook information. The secret is only returned
+// when the webhook is created.
+type WebhookInfo struct {
+	WebhookID string    `json:"webhook_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	URL       string    `json:"url" example:"https://crm.example.com/hooks/links"`
+	Events    []string  `json:"events" example:"link.created,link.clicked"`
+	Active    bool      `json:"active" example:"true"`
+	Secret    string    `json:"secret,omitempty" example:"whsec_3f9a..."`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-01T09:00:00Z"`
+}
+
+// ListWebhooksResponse represents the response for listing webhooks
+type ListWebhooksResponse struct {
+	Webhooks []WebhookInfo `json:"webhooks"`
+}
+
+// WebhookDeliveryInfo represents one queued or finished delivery
+type WebhookDeliveryInfo struct {
+	DeliveryID     string          `json:"delivery_id" example:"7d0f1c2e-4b7a-4c55-9a4e-2f6d1e3b8a90"`
+	WebhookID      string          `json:"webhook_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Event          string          `json:"event" example:"link.clicked"`
+	Status         string          `json:"status" enums:"pending,delivered,dead" example:"delivered"`
+	Attempts       int32           `json:"attempts" example:"1"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" example:"2024-03-01T09:05:00Z"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty" example:"2024-03-01T09:00:01Z"`
+	LastStatusCode *int32          `json:"last_status_code,omitempty" example:"200"`
+	LastError      string          `json:"last_error,omitempty" example:"unexpected status 503"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" example:"2024-03-01T09:00:01Z"`
+	CreatedAt      time.Time       `json:"created_at" example:"2024-03-01T09:00:00Z"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+// ListWebhookDeliveriesResponse represents the response for listing deliveries
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryInfo `json:"deliveries"`
+}
+
+func webhookToInfo(hook sqlc.Webhook) WebhookInfo {
+	return WebhookInfo{
+		WebhookID: hook.WebhookID.String(),
+		URL:       hook.Url,
+		Events:    hook.Events,
+		Active:    hook.Active,
+		CreatedAt: hook.CreatedAt.Time,
+	}
+}
+
+func deliveryToInfo(delivery sqlc.WebhookDelivery) WebhookDeliveryInfo {
+	info := WebhookDeliveryInfo{
+		DeliveryID: delivery.DeliveryID.String(),
+		WebhookID:  delivery.WebhookID.String(),
+		Event:      delivery.Event,
+		Status:     delivery.Status,
+		Attempts:   delivery.Attempts,
+		LastError:  delivery.LastError.String,
+		CreatedAt:  delivery.CreatedAt.Time,
+		Payload:    json.RawMessage(delivery.Payload),
+	}
+	if delivery.Status == webhooks.StatusPending {
+		info.NextAttemptAt = &delivery.NextAttemptAt.Time
+	}
+	if delivery.LastAttemptAt.Valid {
+		info.LastAttemptAt = &delivery.LastAttemptAt.Time
+	}
+	if delivery.LastStatusCode.Valid {
+		info.LastStatusCode = &delivery.LastStatusCode.Int32
+	}
+	if delivery.DeliveredAt.Valid {
+		info.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return info
+}
+
+// validWebhookEvents checks that events is a non-empty list of known event
+// types and returns it without duplicates.
+func validWebhookEvents(events []string) ([]string, bool) {
+	seen := map[string]bool{}
+	valid := []string{}
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !webhooks.ValidEvent(event) {
+			return nil, false
+		}
+		if !seen[event] {
+			seen[event] = true
+			valid = append(valid, event)
+		}
+	}
+	return valid, len(valid) > 0
+}
+
+// deliveryLimit parses the limit query parameter of delivery lists
+func deliveryLimit(r *http.Request) (int32, bool) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultDeliveryLimit, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxDeliveryLimit {
+		return 0, false
+	}
+	return int32(n), true
+}
+
+// notifyWebhooks queues an event for the webhooks of a user. The change it
+// reports has already been made, so a failure is only logged.
+func notifyWebhooks(ctx context.Context, db *sqlc.Queries, userID uuid.UUID, event string, data interface{}) {
+	if err := webhooks.Enqueue(ctx, db, userID, event, data); err != nil {
+		logging.FromContext(ctx).Error("Failed to queue webhooks", "event", event, "error", err)
+	}
+}
+
+func writeDeliveries(w http.ResponseWriter, deliveries []sqlc.WebhookDelivery) {
+	response := ListWebhookDeliveriesResponse{Deliveries: []WebhookDeliveryInfo{}}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, deliveryToInfo(delivery))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateWebhook registers a webhook for the authenticated user
+// @Summary Create Webhook
+// @Description Register an endpoint that is notified of events on your links: link.created, link.updated, link.deleted, link.expired and link.clicked. Deliveries are JSON POSTs signed with the returned secret in the X-Webhook-Signature header ("sha256=" + hex HMAC-SHA256 of "{X-Webhook-Timestamp}.{body}"). Failed deliveries are retried with exponential backoff and end up in the dead-letter list. The secret is only shown once.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param webhook body WebhookRequest true "Webhook to register"
+// @Success 200 {object} WebhookInfo "Webhook created successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/webhooks [post]
+func CreateWebhook(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var input WebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if !validWebURL(input.URL) {
+			problem.Error(w, "Invalid webhook URL", http.StatusBadRequest)
+			return
+		}
+
+		events, ok := validWebhookEvents(input.Events)
+		if !ok {
+			problem.Error(w, "Events must list one or more of "+strings.Join(webhooks.Events, ", "), http.StatusBadRequest)
+			return
+		}
+
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			serverError(w, r, "Failed to generate webhook secret", err)
+			return
+		}
+
+		hook, err := db.CreateWebhook(r.Context(), sqlc.CreateWebhookParams{
+			WebhookID: uuid.New(),
+			UserID:    userID,
+			Url:       input.URL,
+			Secret:    secret,
+			Events:    events,
+			CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			dbError(w, r, "Failed to create webhook", err)
+			return
+		}
+
+		info := webhookToInfo(hook)
+		info.Secret = hook.Secret
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}
+}
+
+// ListWebhooks lists the webhooks of the authenticated user
+// @Summary List Webhooks
+// @Description List the webhooks registered by the authenticated user
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} ListWebhooksResponse "Webhooks retrieved successfully"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/webhooks [get]
+func ListWebhooks(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		hooks, err := db.ListWebhooks(r.Context(), userID)
+		if err != nil {
+			serverError(w, r, "Failed to fetch webhooks", err)
+			return
+		}
+
+		response := ListWebhooksResponse{Webhooks: []WebhookInfo{}}
+		for _, hook := range hooks {
+			response.Webhooks = append(response.Webhooks, webhookToInfo(hook))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UpdateWebhook updates a webhook of the authenticated user
+// @Summary Update Webhook
+// @Description Change the URL or events of a webhook, or pause it with active=false. Events of a paused webhook are not queued.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param webhookID path string true "Webhook ID"
+// @Param webhook body UpdateWebhookRequest true "Fields to change"
+// @Success 200 {object} WebhookInfo "Webhook updated successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Webhook not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/webhooks/{webhookID} [put]
+func UpdateWebhook(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		webhookID, err := uuid.Parse(chi.URLParam(r, "webhookID"))
+		if err != nil {
+			problem.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+			return
+		}
+
+		var input UpdateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		hook, err := db.GetWebhook(r.Context(), sqlc.GetWebhookParams{WebhookID: webhookID, UserID: userID})
+		if err != nil {
+			rowError(w, r, "Webhook not found", "Failed to fetch webhook", err)
+			return
+		}
+
+		params := sqlc.UpdateWebhookParams{
+			WebhookID: webhookID,
+			UserID:    userID,
+			Url:       hook.Url,
+			Events:    hook.Events,
+			Active:    hook.Active,
+		}
+		if input.URL != nil {
+			if !validWebURL(*input.URL) {
+				problem.Error(w, "Invalid webhook URL", http.StatusBadRequest)
+				return
+			}
+			params.Url = *input.URL
+		}
+		if input.Events != nil {
+			events, ok := validWebhookEvents(*input.Events)
+			if !ok {
+				problem.Error(w, "Events must list one or more of "+strings.Join(webhooks.Events, ", "), http.StatusBadRequest)
+				return
+			}
+			params.Events = events
+		}
+		if input.Active != nil {
+			params.Active = *input.Active
+		}
+
+		hook, err = db.UpdateWebhook(r.Context(), params)
+		if err != nil {
+			dbError(w, r, "Failed to update webhook", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(webhookToInfo(hook))
+	}
+}
+
+// DeleteWebhook deletes a webhook of the authenticated user
+// @Summary Delete Webhook
+// @Description Delete a webhook along with its queued and past deliveries
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Param webhookID path string true "Webhook ID"
+// @Produce json
+// @Success 200 {object} map[string]string "Webhook deleted successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Webhook not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/webhooks/{webhookID} [delete]
+func DeleteWebhook(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		webhookID, err := uuid.Parse(chi.URLParam(r, "webhookID"))
+		if err != nil {
+			problem.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := db.DeleteWebhook(r.Context(), sqlc.DeleteWebhookParams{WebhookID: webhookID, UserID: userID})
+		if err != nil {
+			serverError(w, r, "Failed to delete webhook", err)
+			return
+		}
+		if deleted == 0 {
+			problem.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Webhook deleted successfully",
+		})
+	}
+}
+
+// ListWebhookDeliveries lists the deliveries of a webhook
+// @Summary List Webhook Deliveries
+// @Description List the most recent deliveries of a webhook with their payload, attempts and the outcome of the last attempt
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param webhookID path string true "Webhook ID"
+// @Param status query string false "Only list deliveries with this status" Enums(pending, delivered, dead)
+// @Param limit query int false "Maximum number of deliveries (1-500)" default(50)
+// @Success 200 {object} ListWebhookDeliveriesResponse "Deliveries retrieved successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Webhook not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/webhooks/{webhookID}/deliveries [get]
+func ListWebhookDeliveries(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		webhookID, err := uuid.Parse(chi.URLParam(r, "webhookID"))
+		if err != nil {
+			problem.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+			return
+		}
+
+		limit, ok := deliveryLimit(r)
+		if !ok {
+			problem.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "", webhooks.StatusPending, webhooks.StatusDelivered, webhooks.StatusDead:
+		default:
+			problem.Error(w, "status must be pending, delivered or dead", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := db.GetWebhook(r.Context(), sqlc.GetWebhookParams{WebhookID: webhookID, UserID: userID}); err != nil {
+			rowError(w, r, "Webhook not found", "Failed to fetch webhook", err)
+			return
+		}
+
+		deliveries, err := db.ListWebhookDeliveries(r.Context(), sqlc.ListWebhookDeliveriesParams{
+			WebhookID:  webhookID,
+			Status:     stringToNullable(status),
+			MaxResults: limit,
+		})
+		if err != nil {
+			serverError(w, r, "Failed to fetch deliveries", err)
+			return
+		}
+
+		writeDeliveries(w, deliveries)
+	}
+}
+
+// ListDeadWebhookDeliveries lists the dead-letter deliveries of the
+// authenticated user
+// @Summary List Dead-Letter Deliveries
+// @Description List deliveries of all your webhooks that ran out of attempts. Retry them once the receiving endpoint is fixed.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param limit query int false "Maximum number of deliveries (1-500)" default(50)
+// @Success 200 {object} ListWebhookDeliveriesResponse "Deliveries retrieved successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/webhooks/dead-letters [get]
+func ListDeadWebhookDeliveries(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		limit, ok := deliveryLimit(r)
+		if !ok {
+			problem.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+
+		deliveries, err := db.ListDeadWebhookDeliveries(r.Context(), sqlc.ListDeadWebhookDeliveriesParams{
+			UserID:     userID,
+			MaxResults: limit,
+		})
+		if err != nil {
+			serverError(w, r, "Failed to fetch deliveries", err)
+			return
+		}
+
+		writeDeliveries(w, deliveries)
+	}
+}
+
+// RetryWebhookDelivery queues a delivery again
+// @Summary Retry Webhook Delivery
+// @Description Queue a delivery for immediate redelivery with a fresh set of attempts, typically one from the dead-letter list
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param webhookID path string true "Webhook ID"
+// @Param deliveryID path string true "Delivery ID"
+// @Success 200 {object} WebhookDeliveryInfo "Delivery queued"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 401 {object} problem.Problem "Unauthorized"
+// @Failure 404 {object} problem.Problem "Delivery not found"
+// @Router /api/webhooks/{webhookID}/deliveries/{deliveryID}/retry [post]
+func RetryWebhookDelivery(db *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value("user_id").(string)
+		if !ok {
+			problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		webhookID, err := uuid.Parse(chi.URLParam(r, "webhookID"))
+		if err != nil {
+			problem.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+			return
+		}
+
+		deliveryID, err := uuid.Parse(chi.URLParam(r, "deliveryID"))
+		if err != nil {
+			problem.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+			return
+		}
+
+		delivery, err := db.RetryWebhookDelivery(r.Context(), sqlc.RetryWebhookDeliveryParams{
+			UserID:     userID,
+			WebhookID:  webhookID,
+			DeliveryID: deliveryID,
+		})
+		if err != nil {
+			rowError(w, r, "Delivery not found", "Failed to retry delivery", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deliveryToInfo(delivery))
+	}
+}
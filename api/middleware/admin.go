@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// AdminOnly restricts a route to administrators. It must run after
+// AuthMiddleware, which puts the user ID in the request context.
+func AdminOnly(db *sqlc.Queries) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userIDStr, ok := r.Context().Value("user_id").(string)
+			if !ok {
+				problem.Error(w, "User ID not found in context", http.StatusUnauthorized)
+				return
+			}
+
+			userID, err := uuid.Parse(userIDStr)
+			if err != nil {
+				problem.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+
+			user, err := db.GetUserByID(r.Context(), userID)
+			if err != nil || !user.IsAdmin {
+				problem.Error(w, "Admin access required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,10 +2,17 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/logging"
 	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/streamtoken"
 )
 
 func AuthMiddleware(db *sqlc.Queries) func(http.Handler) http.Handler {
@@ -14,18 +21,60 @@ func AuthMiddleware(db *sqlc.Queries) func(http.Handler) http.Handler {
 			apiKeyStr := r.Header.Get("X-API-Key")
 			apiKey, err := uuid.Parse(apiKeyStr)
 			if err != nil {
-				http.Error(w, "Invalid API key format", http.StatusUnauthorized)
+				problem.Error(w, "Invalid API key format", http.StatusUnauthorized)
 				return
 			}
 
 			key, err := db.GetAPIKey(r.Context(), apiKey)
+			if errors.Is(err, pgx.ErrNoRows) {
+				problem.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
 			if err != nil {
-				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				logging.FromContext(r.Context()).Error("Failed to look up API key", "error", err)
+				problem.Error(w, "Failed to look up API key", http.StatusInternalServerError)
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), "user_id", key.UserID.String())
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, withUser(r, key.UserID.String()))
 		})
 	}
 }
+
+// StreamAuth authenticates the live click streams of a link. Browsers cannot
+// set the X-API-Key header on EventSource and WebSocket requests, so a
+// stream token issued for the link is also accepted in the token query
+// parameter; requests without one fall back to AuthMiddleware.
+func StreamAuth(db *sqlc.Queries, redisClient *redis.Client) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withAPIKey := AuthMiddleware(db)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				withAPIKey.ServeHTTP(w, r)
+				return
+			}
+
+			userID, err := streamtoken.Redeem(r.Context(), redisClient, token, chi.URLParam(r, "shortID"))
+			if errors.Is(err, streamtoken.ErrInvalid) {
+				problem.Error(w, "Invalid or expired stream token", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Failed to redeem stream token", "error", err)
+				problem.Error(w, "Failed to redeem stream token", http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, withUser(r, userID))
+		})
+	}
+}
+
+// withUser puts the authenticated user in the request context
+func withUser(r *http.Request, userID string) *http.Request {
+	ctx := context.WithValue(r.Context(), "user_id", userID)
+	ctx = logging.With(ctx, "user_id", userID)
+	setRequestUser(ctx, userID)
+	return r.WithContext(ctx)
+}
@@ -1,16 +1,68 @@
 package middleware
 
 import (
-    "log"
-    "net/http"
-    "time"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/yeboahd24/url-shortener/logging"
 )
 
+type requestLogKey struct{}
+
+// requestLog collects fields for the access log that are only known deeper
+// in the middleware chain
+type requestLog struct {
+	userID string
+}
+
+// setRequestUser records the authenticated user of a request in its access
+// log line
+func setRequestUser(ctx context.Context, userID string) {
+	if entry, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		entry.userID = userID
+	}
+}
+
+// Logger writes one access log line per request with its status, size,
+// latency and user. Server errors are logged at error level and client
+// errors at warn level.
 func Logger(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        start := time.Now()
-        log.Printf("Started %s %s", r.Method, r.URL.Path)
-        next.ServeHTTP(w, r)
-        log.Printf("Completed %s %s in %v", r.Method, r.URL.Path, time.Since(start))
-    })
-}
\ No newline at end of file
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &requestLog{}
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), requestLogKey{}, entry)))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			attrs = append(attrs, slog.String("route", rctx.RoutePattern()))
+		}
+		if entry.userID != "" {
+			attrs = append(attrs, slog.String("user_id", entry.userID))
+		}
+		logging.FromContext(r.Context()).LogAttrs(r.Context(), level, "request completed", attrs...)
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/api/problem"
+	"github.com/yeboahd24/url-shortener/metrics"
 )
 
 func RateLimitMiddleware(redisClient *redis.Client) func(http.Handler) http.Handler {
@@ -14,7 +16,9 @@ func RateLimitMiddleware(redisClient *redis.Client) func(http.Handler) http.Hand
 			key := "rate:" + ip
 			count, _ := redisClient.Get(r.Context(), key).Int()
 			if count >= 10 {
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				metrics.RateLimited.Inc()
+				w.Header().Set("Retry-After", "60")
+				problem.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 				return
 			}
 			redisClient.Incr(r.Context(), key)
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// realIPHeader is set to the client address by the nginx reverse proxy
+const realIPHeader = "X-Real-IP"
+
+// forwardedProtoHeader is set to the client's scheme by the nginx reverse proxy
+const forwardedProtoHeader = "X-Forwarded-Proto"
+
+// ParseProxies parses trusted proxy addresses, given as IP addresses or CIDR
+// ranges
+func ParseProxies(values []string) ([]netip.Prefix, error) {
+	proxies := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, err
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// RealIP replaces the remote address of requests that come from a trusted
+// proxy with the client address in their X-Real-IP header. Everyone else
+// keeps their own address, so clients cannot choose the IP that rate limits,
+// unlock attempts and geolocation see. Their X-Forwarded-Proto header is
+// dropped too, so handlers can trust it for the client's scheme.
+func RealIP(proxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if trustedPeer(r.RemoteAddr, proxies) {
+				if ip, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get(realIPHeader))); err == nil {
+					r.RemoteAddr = net.JoinHostPort(ip.Unmap().String(), "0")
+				}
+			} else {
+				r.Header.Del(forwardedProtoHeader)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// trustedPeer reports whether the connection comes from one of the proxies
+func trustedPeer(remoteAddr string, proxies []netip.Prefix) bool {
+	if len(proxies) == 0 {
+		return false
+	}
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
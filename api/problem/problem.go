@@ -0,0 +1,95 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of problem details (RFC 7807)
+const ContentType = "application/problem+json"
+
+// requestIDHeader is set on the response by the request ID middleware before
+// any handler runs
+const requestIDHeader = "X-Request-ID"
+
+// Error codes. Codes are stable: clients may rely on them, while titles and
+// details are meant for people and may change.
+const (
+	CodeInvalidRequest    = "invalid_request"
+	CodeUnauthorized      = "unauthorized"
+	CodeForbidden         = "forbidden"
+	CodeNotFound          = "not_found"
+	CodeMethodNotAllowed  = "method_not_allowed"
+	CodeConflict          = "conflict"
+	CodeGone              = "gone"
+	CodeValidationFailed  = "validation_failed"
+	CodeRateLimited       = "rate_limited"
+	CodeInternal          = "internal_error"
+	CodeUnavailable       = "service_unavailable"
+	CodeLinkNotActive     = "link_not_active"
+	CodeLinkExpired       = "link_expired"
+	CodeClickLimitReached = "click_limit_reached"
+)
+
+// Problem is an RFC 7807 problem details body. Code and RequestID are
+// extension members.
+type Problem struct {
+	Type      string `json:"type" example:"about:blank"`
+	Title     string `json:"title" example:"Not Found"`
+	Status    int    `json:"status" example:"404"`
+	Detail    string `json:"detail,omitempty" example:"URL not found"`
+	Code      string `json:"code" example:"not_found"`
+	RequestID string `json:"request_id,omitempty" example:"6f1c2a4e-8b0d-4c8e-9f4a-2d7f0e3b9c51"`
+}
+
+// CodeFor returns the default code of an HTTP status
+func CodeFor(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
+
+// Write sends a problem with the given status, code and detail
+func Write(w http.ResponseWriter, status int, code, detail string) {
+	p := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestID: w.Header().Get(requestIDHeader),
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error sends a problem with the default code of status. It takes the same
+// arguments as http.Error.
+func Error(w http.ResponseWriter, detail string, status int) {
+	Write(w, status, CodeFor(status), detail)
+}
@@ -1,16 +1,77 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	PostgresDSN  string `mapstructure:"POSTGRES_DSN"`
-	RedisAddr    string `mapstructure:"REDIS_ADDR"`
-	RedisPass    string `mapstructure:"REDIS_PASS"`
-	GeoAPIURL    string `mapstructure:"GEO_API_URL"`
-	Port         string `mapstructure:"PORT"`
-	APIKeyHeader string `mapstructure:"API_KEY_HEADER"`
+	PostgresDSN    string `mapstructure:"POSTGRES_DSN"`
+	RedisAddr      string `mapstructure:"REDIS_ADDR"`
+	RedisPass      string `mapstructure:"REDIS_PASS"`
+	GeoAPIURL      string `mapstructure:"GEO_API_URL"`
+	Port           string `mapstructure:"PORT"`
+	APIKeyHeader   string `mapstructure:"API_KEY_HEADER"`
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+
+	FetchMetadata        bool          `mapstructure:"FETCH_METADATA"`
+	MetadataTimeout      time.Duration `mapstructure:"METADATA_TIMEOUT"`
+	MetadataAllowPrivate bool          `mapstructure:"METADATA_ALLOW_PRIVATE"`
+
+	DefaultRedirectType     string        `mapstructure:"DEFAULT_REDIRECT_TYPE"`
+	PermanentRedirectMaxAge time.Duration `mapstructure:"PERMANENT_REDIRECT_MAX_AGE"`
+
+	LinkCookieSecret string        `mapstructure:"LINK_COOKIE_SECRET"`
+	UnlockTTL        time.Duration `mapstructure:"UNLOCK_TTL"`
+
+	StoreClickIPs bool `mapstructure:"STORE_CLICK_IPS"`
+
+	ClickIDParam     string        `mapstructure:"CLICK_ID_PARAM"`
+	ConversionWindow time.Duration `mapstructure:"CONVERSION_WINDOW"`
+
+	AppleAppIDs         string `mapstructure:"APPLE_APP_IDS"`
+	AppleAppPaths       string `mapstructure:"APPLE_APP_PATHS"`
+	AndroidPackage      string `mapstructure:"ANDROID_PACKAGE"`
+	AndroidFingerprints string `mapstructure:"ANDROID_SHA256_FINGERPRINTS"`
+
+	PublicBaseURL string `mapstructure:"PUBLIC_BASE_URL"`
+	QRLogoFile    string `mapstructure:"QR_LOGO_FILE"`
+
+	JobsEnabled          bool          `mapstructure:"JOBS_ENABLED"`
+	JobTick              time.Duration `mapstructure:"JOB_TICK"`
+	ExpiredLinkRetention time.Duration `mapstructure:"EXPIRED_LINK_RETENTION"`
+	ClickRetentionDays   string        `mapstructure:"CLICK_RETENTION_DAYS"`
+	JobRunRetention      time.Duration `mapstructure:"JOB_RUN_RETENTION"`
+
+	WebhookPollInterval      time.Duration `mapstructure:"WEBHOOK_POLL_INTERVAL"`
+	WebhookTimeout           time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts       int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookAllowPrivate      bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE"`
+	WebhookDeliveryRetention time.Duration `mapstructure:"WEBHOOK_DELIVERY_RETENTION"`
+
+	MetricsAddr  string `mapstructure:"METRICS_ADDR"`
+	MetricsToken string `mapstructure:"METRICS_TOKEN"`
+
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+
+	TracingExporter    string  `mapstructure:"TRACING_EXPORTER"`
+	TracingEndpoint    string  `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	TracingServiceName string  `mapstructure:"TRACING_SERVICE_NAME"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+}
+
+// SplitList splits a comma-separated setting into its trimmed, non-empty values
+func SplitList(s string) []string {
+	values := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
 }
 
 func LoadConfig() (*Config, error) {
@@ -23,6 +84,41 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("GEO_API_URL", "http://ip-api.com/json")
 	viper.SetDefault("PORT", "8080")
 	viper.SetDefault("API_KEY_HEADER", "X-API-Key")
+	viper.SetDefault("TRUSTED_PROXIES", "")
+	viper.SetDefault("FETCH_METADATA", false)
+	viper.SetDefault("METADATA_TIMEOUT", "10s")
+	viper.SetDefault("METADATA_ALLOW_PRIVATE", false)
+	viper.SetDefault("DEFAULT_REDIRECT_TYPE", "302")
+	viper.SetDefault("PERMANENT_REDIRECT_MAX_AGE", "0s")
+	viper.SetDefault("LINK_COOKIE_SECRET", "")
+	viper.SetDefault("UNLOCK_TTL", "1h")
+	viper.SetDefault("STORE_CLICK_IPS", true)
+	viper.SetDefault("CLICK_ID_PARAM", "click_id")
+	viper.SetDefault("CONVERSION_WINDOW", "720h")
+	viper.SetDefault("APPLE_APP_IDS", "")
+	viper.SetDefault("APPLE_APP_PATHS", "NOT /api/*,NOT /swagger/*,*")
+	viper.SetDefault("ANDROID_PACKAGE", "")
+	viper.SetDefault("ANDROID_SHA256_FINGERPRINTS", "")
+	viper.SetDefault("PUBLIC_BASE_URL", "")
+	viper.SetDefault("QR_LOGO_FILE", "")
+	viper.SetDefault("JOBS_ENABLED", true)
+	viper.SetDefault("JOB_TICK", "1m")
+	viper.SetDefault("EXPIRED_LINK_RETENTION", "720h")
+	viper.SetDefault("CLICK_RETENTION_DAYS", "")
+	viper.SetDefault("JOB_RUN_RETENTION", "720h")
+	viper.SetDefault("WEBHOOK_POLL_INTERVAL", "5s")
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 10)
+	viper.SetDefault("WEBHOOK_ALLOW_PRIVATE", false)
+	viper.SetDefault("WEBHOOK_DELIVERY_RETENTION", "720h")
+	viper.SetDefault("METRICS_ADDR", "")
+	viper.SetDefault("METRICS_TOKEN", "")
+	viper.SetDefault("LOG_FORMAT", "json")
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_OTLP_ENDPOINT", "")
+	viper.SetDefault("TRACING_SERVICE_NAME", "url-shortener")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -0,0 +1,183 @@
+package counters
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// Redis keys:
+//
+//	clicks:{shortID}              total clicks
+//	clicks:{shortID}:{YYYY-MM-DD} clicks per UTC day
+//	visitors:{shortID}            HyperLogLog of daily visitor IDs
+//	counters:dirty                short IDs counted since the last reconciliation
+const (
+	clicksPrefix   = "clicks:"
+	visitorsPrefix = "visitors:"
+	dirtyKey       = "counters:dirty"
+)
+
+// dayTTL is how long per-day counters are kept
+const dayTTL = 35 * 24 * time.Hour
+
+// Counts are the live click counters of a short link
+type Counts struct {
+	Clicks         int64 `json:"click_count"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+	ClicksToday    int64 `json:"clicks_today"`
+}
+
+func totalKey(shortID string) string {
+	return clicksPrefix + shortID
+}
+
+func dayKey(shortID string, day time.Time) string {
+	return clicksPrefix + shortID + ":" + day.UTC().Format("2006-01-02")
+}
+
+func visitorsKey(shortID string) string {
+	return visitorsPrefix + shortID
+}
+
+// Keys returns the counter keys of a short link that do not expire on their own
+func Keys(shortID string) []string {
+	return []string{totalKey(shortID), visitorsKey(shortID)}
+}
+
+// Record counts a click by visitor at the given time; an empty visitor ID is
+// left out of the unique visitor count. When the total counter
+// is missing from Redis, e.g. after a flush or for links that predate the
+// counters, it is seeded from Postgres.
+func Record(ctx context.Context, redisClient *redis.Client, db *sqlc.Queries, shortID, visitor string, at time.Time) error {
+	pipe := redisClient.TxPipeline()
+	total := pipe.Incr(ctx, totalKey(shortID))
+	day := dayKey(shortID, at)
+	pipe.Incr(ctx, day)
+	pipe.Expire(ctx, day, dayTTL)
+	if visitor != "" {
+		pipe.PFAdd(ctx, visitorsKey(shortID), visitor)
+	}
+	pipe.SAdd(ctx, dirtyKey, shortID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	// Only the click that created the counter seeds it
+	if total.Val() != 1 {
+		return nil
+	}
+	seed, err := db.GetClickCountSeed(ctx, shortID)
+	if err != nil || seed == 0 {
+		return err
+	}
+	return redisClient.IncrBy(ctx, totalKey(shortID), seed).Err()
+}
+
+// Get returns the counters of the given short links. Redis is authoritative;
+// the reconciled Postgres values cover links whose counters are missing there.
+func Get(ctx context.Context, redisClient *redis.Client, db *sqlc.Queries, shortIDs []string) (map[string]Counts, error) {
+	counts := make(map[string]Counts, len(shortIDs))
+	if len(shortIDs) == 0 {
+		return counts, nil
+	}
+
+	rows, err := db.ListURLCounters(ctx, shortIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ShortID] = Counts{Clicks: row.ClickCount, UniqueVisitors: row.UniqueVisitors}
+	}
+
+	now := time.Now()
+	pipe := redisClient.Pipeline()
+	totals := make([]*redis.StringCmd, len(shortIDs))
+	today := make([]*redis.StringCmd, len(shortIDs))
+	visitors := make([]*redis.IntCmd, len(shortIDs))
+	for i, shortID := range shortIDs {
+		totals[i] = pipe.Get(ctx, totalKey(shortID))
+		today[i] = pipe.Get(ctx, dayKey(shortID, now))
+		visitors[i] = pipe.PFCount(ctx, visitorsKey(shortID))
+	}
+	// Missing keys are reported per command, so only a failed pipeline counts
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return counts, err
+	}
+
+	for i, shortID := range shortIDs {
+		c := counts[shortID]
+		if n, err := totals[i].Int64(); err == nil && n > c.Clicks {
+			c.Clicks = n
+		}
+		if n, err := visitors[i].Result(); err == nil && n > c.UniqueVisitors {
+			c.UniqueVisitors = n
+		}
+		if n, err := today[i].Int64(); err == nil {
+			c.ClicksToday = n
+		}
+		counts[shortID] = c
+	}
+	return counts, nil
+}
+
+// Reconcile copies the counters of links clicked since the last run into
+// Postgres, batch short IDs at a time, so they survive the loss of Redis. It
+// returns the number of links synced.
+func Reconcile(ctx context.Context, redisClient *redis.Client, db *sqlc.Queries, batch int64) (int64, error) {
+	var synced int64
+	for {
+		shortIDs, err := redisClient.SPopN(ctx, dirtyKey, batch).Result()
+		if err != nil {
+			return synced, err
+		}
+		if len(shortIDs) == 0 {
+			return synced, nil
+		}
+
+		pipe := redisClient.Pipeline()
+		totals := make([]*redis.StringCmd, len(shortIDs))
+		visitors := make([]*redis.IntCmd, len(shortIDs))
+		for i, shortID := range shortIDs {
+			totals[i] = pipe.Get(ctx, totalKey(shortID))
+			visitors[i] = pipe.PFCount(ctx, visitorsKey(shortID))
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			redisClient.SAdd(ctx, dirtyKey, toInterfaces(shortIDs)...)
+			return synced, err
+		}
+
+		for i, shortID := range shortIDs {
+			clicks, err := totals[i].Int64()
+			if err != nil {
+				continue
+			}
+			err = db.UpsertURLCounter(ctx, sqlc.UpsertURLCounterParams{
+				ShortID:        shortID,
+				ClickCount:     clicks,
+				UniqueVisitors: visitors[i].Val(),
+			})
+			if err != nil {
+				// Put back what is left so the next run picks it up
+				redisClient.SAdd(ctx, dirtyKey, toInterfaces(shortIDs[i:])...)
+				return synced, err
+			}
+			synced++
+		}
+
+		// Links clicked while this run was going wait for the next one
+		if int64(len(shortIDs)) < batch {
+			return synced, nil
+		}
+	}
+}
+
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
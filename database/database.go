@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yeboahd24/url-shortener/metrics"
+	"github.com/yeboahd24/url-shortener/tracing"
+)
+
+// Connect opens a Postgres connection pool whose queries are measured and
+// traced. Requests, click logging and transactions each take a connection of
+// their own, so a transaction never picks up statements from elsewhere. The
+// pool size can be set with pool_max_conns in the DSN.
+func Connect(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	config.ConnConfig.Tracer = tracer()
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// ConnectSession opens a single Postgres connection, for background workers
+// that keep one of their own, such as the job scheduler whose leader lock
+// lives as long as the session
+func ConnectSession(ctx context.Context, dsn string) (*pgx.Conn, error) {
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	config.Tracer = tracer()
+	return pgx.ConnectConfig(ctx, config)
+}
+
+func tracer() pgx.QueryTracer {
+	return multitracer.New(metrics.QueryTracer{}, tracing.QueryTracer{})
+}
@@ -24,331 +24,387 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/analytics/{shortID}": {
+        "/.well-known/apple-app-site-association": {
             "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "Get click analytics for a specific URL owned by the authenticated user",
+                "description": "Lets the configured iOS apps open short links as universal links. Returns 404 when no iOS app is configured.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "analytics"
-                ],
-                "summary": "Get URL Analytics",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Short URL ID",
-                        "name": "shortID",
-                        "in": "path",
-                        "required": true
-                    }
+                    "deep links"
                 ],
+                "summary": "Apple App Site Association",
                 "responses": {
                     "200": {
-                        "description": "Analytics data by location",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.AnalyticsResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized or URL not found",
+                        "description": "apple-app-site-association file",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "404": {
+                        "description": "No iOS app configured",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/keys": {
+        "/.well-known/assetlinks.json": {
             "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "List all API keys for the authenticated user",
+                "description": "Lets the configured Android app open short links as verified app links. Returns 404 when no Android app is configured.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api-keys"
+                    "deep links"
                 ],
-                "summary": "List API Keys",
+                "summary": "Android Asset Links",
                 "responses": {
                     "200": {
-                        "description": "API keys retrieved successfully",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ListAPIKeysResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
+                        "description": "assetlinks.json file",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "additionalProperties": true
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "404": {
+                        "description": "No Android app configured",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/api/admin/jobs/runs": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Create a new API key for the authenticated user",
+                "description": "List recent runs of the background jobs (expiry sweep, click retention, cache cleanup, ...), newest first. Requires an admin account.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api-keys"
+                    "admin"
+                ],
+                "summary": "List Job Runs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only show runs of this job",
+                        "name": "job",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of runs (1-500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Create API Key",
                 "responses": {
                     "200": {
-                        "description": "API key created successfully",
+                        "description": "Job runs",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateAPIKeyResponse"
+                            "$ref": "#/definitions/handlers.ListJobRunsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/analytics": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete an API key for the authenticated user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Summarize the clicks of all your URLs, the URLs carrying a tag, or a list of short IDs over a range of whole UTC days: total clicks, the most clicked links and the top countries, devices and referrer hosts, each compared with the period of the same length just before. Conversions recorded in the period are reported with their conversion rate and revenue per currency, in total and per link. Counts are served from the click rollups like the analytics of a single URL.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api-keys"
+                    "analytics"
                 ],
-                "summary": "Delete API Key",
+                "summary": "Get Aggregate Analytics",
                 "parameters": [
                     {
-                        "description": "API key to delete",
-                        "name": "apikey",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.DeleteAPIKeyRequest"
-                        }
+                        "type": "string",
+                        "description": "Only URLs carrying this tag",
+                        "name": "tag_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated short IDs of URLs to include",
+                        "name": "short_ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day of the period (YYYY-MM-DD, default 29 days before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day of the period (YYYY-MM-DD, default today)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of links and values per breakdown to return (1-100)",
+                        "name": "top",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "API key deleted successfully",
+                        "description": "Click summary",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.AggregateAnalyticsResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Invalid parameters",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag or URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/shorten": {
-            "post": {
-                "description": "Create a shortened URL. Custom IDs require authentication.",
-                "consumes": [
-                    "application/json"
+        "/api/analytics/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Download the raw clicks of all your URLs as CSV, newline-delimited JSON or Parquet, streamed like the export of a single URL. Use the short_id column to tell links apart.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.apache.parquet"
                 ],
                 "tags": [
-                    "urls"
+                    "analytics"
                 ],
-                "summary": "Shorten URL",
+                "summary": "Export All Clicks",
                 "parameters": [
                     {
-                        "description": "URL to shorten",
-                        "name": "url",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ShortenURLRequest"
-                        }
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "parquet"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated columns to include, in order: id, short_id, clicked_at, ip_address, user_agent, country, location, device, referrer, source, variant_id, visitor_hash, click_id (default all)",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only clicks at or after this RFC 3339 time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only clicks before this RFC 3339 time",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "URL shortened successfully",
+                        "description": "Click export",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ShortenURLResponse"
+                            "type": "file"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Invalid format, columns or time range",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "401": {
-                        "description": "Authentication required for custom URLs",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "Internal server error",
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/urls": {
+        "/api/analytics/{shortID}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "List all URLs created by the authenticated user",
+                "description": "Get click analytics for a specific URL owned by the authenticated user. Clicks can be grouped by visitor location, country, device or referrer host, counted per hour or day, grouped by source (qr for scanned QR codes, direct otherwise) or, for A/B split links, by variant name. Clicks made before a split was set up are reported under an empty variant name. Location, country, device, referrer and time series are served from pre-aggregated rollups plus the clicks of the last hour; hourly series cover the last 90 days. With metric=uniques the same groups report unique visitors instead, counted from a salted visitor hash that rotates daily, so a visitor returning on another day counts again.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "analytics"
+                ],
+                "summary": "Get URL Analytics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "location",
+                            "country",
+                            "device",
+                            "referrer",
+                            "hour",
+                            "day",
+                            "source",
+                            "variant"
+                        ],
+                        "type": "string",
+                        "default": "location",
+                        "description": "Group clicks by",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "clicks",
+                            "uniques"
+                        ],
+                        "type": "string",
+                        "default": "clicks",
+                        "description": "Count clicks or unique visitors",
+                        "name": "metric",
+                        "in": "query"
+                    }
                 ],
-                "summary": "List User URLs",
                 "responses": {
                     "200": {
-                        "description": "URLs retrieved successfully",
+                        "description": "Click or visitor counts per group",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ListURLsResponse"
+                            "$ref": "#/definitions/handlers.AnalyticsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid group_by or metric",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/urls/{shortID}": {
-            "put": {
+        "/api/analytics/{shortID}/conversions": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update URL settings for a URL owned by the authenticated user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get the conversions of one of your URLs: how many were recorded, how many clicks converted, the conversion rate against the total click count, revenue per currency and conversions per event.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "analytics"
                 ],
-                "summary": "Update URL",
+                "summary": "Get Conversion Analytics",
                 "parameters": [
                     {
                         "type": "string",
@@ -356,76 +412,59 @@ const docTemplate = `{
                         "name": "shortID",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Conversion analytics",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ConversionStatsResponse"
+                        }
                     },
-                    {
-                        "description": "URL update information",
-                        "name": "url",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.UpdateURLRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "URL updated successfully",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.URLInfo"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "URL belongs to another user",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "URL not found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/analytics/{shortID}/export": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete a URL owned by the authenticated user",
+                "description": "Download the raw clicks of one of your URLs as CSV, newline-delimited JSON or Parquet. The file is streamed while clicks are read, oldest first, so exports of any size start immediately. A failure part way through ends the download early; a Parquet file cut short has no footer and cannot be read.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.apache.parquet"
                 ],
                 "tags": [
-                    "urls"
+                    "analytics"
                 ],
-                "summary": "Delete URL",
+                "summary": "Export URL Clicks",
                 "parameters": [
                     {
                         "type": "string",
@@ -433,161 +472,257 @@ const docTemplate = `{
                         "name": "shortID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "parquet"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated columns to include, in order: id, short_id, clicked_at, ip_address, user_agent, country, location, device, referrer, source, variant_id, visitor_hash, click_id (default all)",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only clicks at or after this RFC 3339 time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only clicks before this RFC 3339 time",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "URL deleted successfully",
+                        "description": "Click export",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format, columns or time range",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Failed to delete URL or URL not found",
+                    "403": {
+                        "description": "URL belongs to another user",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/health": {
+        "/api/analytics/{shortID}/stream": {
             "get": {
-                "description": "Check the health status of the application and its dependencies",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stream the clicks of one of your URLs as they happen, as Server-Sent Events. Each click is sent as a \"click\" event whose data is a ClickEvent; comment lines are sent every 15 seconds to keep the connection open.",
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "health"
+                    "analytics"
+                ],
+                "summary": "Stream Clicks (SSE)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token, for browsers that cannot send X-API-Key",
+                        "name": "token",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Health Check",
                 "responses": {
                     "200": {
-                        "description": "Service is healthy",
+                        "description": "Stream of click events",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/handlers.ClickEvent"
                         }
                     },
-                    "503": {
-                        "description": "Service is unhealthy",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Streaming unsupported",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/shorten": {
+        "/api/analytics/{shortID}/stream-token": {
             "post": {
-                "description": "Create a shortened URL. Custom IDs require authentication.",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Issue a token for opening one live click stream of one of your URLs from a browser, where EventSource and WebSocket cannot send the X-API-Key header. Pass it as the token query parameter of the stream. Tokens expire after a minute and work once; fetch a new one to reconnect.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "analytics"
                 ],
-                "summary": "Shorten URL",
+                "summary": "Create Stream Token",
                 "parameters": [
                     {
-                        "description": "URL to shorten",
-                        "name": "url",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ShortenURLRequest"
-                        }
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "URL shortened successfully",
+                        "description": "Stream token",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ShortenURLResponse"
+                            "$ref": "#/definitions/handlers.StreamTokenResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "401": {
-                        "description": "Authentication required for custom URLs",
+                    "403": {
+                        "description": "URL belongs to another user",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/stats": {
+        "/api/analytics/{shortID}/ws": {
             "get": {
-                "description": "Get global statistics including total URLs, clicks, and users",
-                "produces": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Stream the clicks of one of your URLs as they happen over a WebSocket. Each click is sent as a JSON text message holding a ClickEvent; messages from the client are ignored.",
                 "tags": [
-                    "statistics"
+                    "analytics"
+                ],
+                "summary": "Stream Clicks (WebSocket)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token, for browsers that cannot send X-API-Key",
+                        "name": "token",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get Global Statistics",
                 "responses": {
-                    "200": {
-                        "description": "Global statistics",
+                    "101": {
+                        "description": "Switching to the WebSocket protocol",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/handlers.ClickEvent"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/users": {
+        "/api/conversions": {
             "post": {
-                "description": "Create a new user account",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Record a conversion against the click ID of a redirect to one of your URLs, optionally with its value and ISO 4217 currency. A click converts once per event: reporting the same click and event again returns the stored conversion with 200, so postbacks can be retried safely. Clicks older than the conversion window are rejected.",
                 "consumes": [
                     "application/json"
                 ],
@@ -595,266 +730,3682 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "conversions"
                 ],
-                "summary": "Create User",
+                "summary": "Record Conversion",
                 "parameters": [
                     {
-                        "description": "User information",
-                        "name": "user",
+                        "description": "Conversion",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateUserRequest"
+                            "$ref": "#/definitions/handlers.ConversionRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User created successfully",
+                        "description": "Conversion already recorded",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateUserResponse"
+                            "$ref": "#/definitions/handlers.ConversionInfo"
+                        }
+                    },
+                    "201": {
+                        "description": "Conversion recorded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ConversionInfo"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Invalid event, value or currency",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Click not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Click is outside the conversion window",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/{shortID}": {
+        "/api/folders": {
             "get": {
-                "description": "Redirect to the original URL using the short ID and log the click",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List all folders created by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "urls"
+                    "folders"
                 ],
-                "summary": "Redirect to Original URL",
+                "summary": "List Folders",
+                "responses": {
+                    "200": {
+                        "description": "Folders retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListFoldersResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create a folder for organizing URLs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "folders"
+                ],
+                "summary": "Create Folder",
+                "parameters": [
+                    {
+                        "description": "Folder to create",
+                        "name": "folder",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FolderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Folder created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FolderInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A folder with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/folders/{folderID}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Rename a folder owned by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "folders"
+                ],
+                "summary": "Update Folder",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Short URL ID",
-                        "name": "shortID",
+                        "description": "Folder ID",
+                        "name": "folderID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "New folder name",
+                        "name": "folder",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FolderRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "301": {
-                        "description": "Redirect to original URL"
+                    "200": {
+                        "description": "Folder updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FolderInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "404": {
-                        "description": "URL not found",
+                        "description": "Folder not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A folder with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a folder owned by the authenticated user. URLs in the folder are kept and become unfiled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "folders"
+                ],
+                "summary": "Delete Folder",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Folder ID",
+                        "name": "folderID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Folder deleted successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Folder not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List all API keys for the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "List API Keys",
+                "responses": {
+                    "200": {
+                        "description": "API keys retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListAPIKeysResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create a new API key for the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Create API Key",
+                "responses": {
+                    "200": {
+                        "description": "API key created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateAPIKeyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete an API key for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Delete API Key",
+                "parameters": [
+                    {
+                        "description": "API key to delete",
+                        "name": "apikey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeleteAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API key deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/shorten": {
+            "post": {
+                "description": "Create a shortened URL. Custom IDs, tags and folders require authentication.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Shorten URL",
+                "parameters": [
+                    {
+                        "description": "URL to shorten",
+                        "name": "url",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShortenURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL shortened successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShortenURLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Authentication required for custom URLs, tags or folders",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Custom ID already taken",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List all tags created by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "List Tags",
+                "responses": {
+                    "200": {
+                        "description": "Tags retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListTagsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create a tag for organizing URLs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Create Tag",
+                "parameters": [
+                    {
+                        "description": "Tag to create",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tag created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TagInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A tag with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/analytics": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get URL and click counts for every tag owned by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Get Tag Analytics",
+                "responses": {
+                    "200": {
+                        "description": "Analytics rolled up per tag",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TagAnalyticsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/{tagID}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Rename a tag owned by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Update Tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New tag name",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tag updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TagInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A tag with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a tag owned by the authenticated user. Tagged URLs are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Delete Tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tag deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/{tagID}/analytics": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get click counts for every URL carrying a tag owned by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Get Tag URL Analytics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click counts by short ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TagURLAnalyticsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List all URLs created by the authenticated user, optionally filtered by tag name or folder. Each URL includes live click counters: click_count, unique_visitors (approximate) and clicks_today (UTC).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "List User URLs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list URLs carrying this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list URLs in this folder",
+                        "name": "folder_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URLs retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListURLsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{shortID}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update URL settings for a URL owned by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Update URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "URL update information",
+                        "name": "url",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.URLInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a URL owned by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Delete URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete URL or URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{shortID}/qr": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Render a QR code for one of your short links as PNG or SVG. Accepts the same options as /{shortID}/qr.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "qr"
+                ],
+                "summary": "Get QR Code (Authenticated)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "svg"
+                        ],
+                        "type": "string",
+                        "default": "png",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Width and height in pixels (64-2048)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "L",
+                            "M",
+                            "Q",
+                            "H"
+                        ],
+                        "type": "string",
+                        "default": "M",
+                        "description": "Error correction level",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 4,
+                        "description": "Quiet zone in modules (0-16)",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "000000",
+                        "description": "Foreground hex color",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ffffff",
+                        "description": "Background hex color",
+                        "name": "bg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Draw the configured logo in the center",
+                        "name": "logo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid option",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{shortID}/rules": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the conditional redirect rules of a URL in evaluation order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "List Redirect Rules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Redirect rules",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListRedirectRulesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replace the rules of a URL with the given ordered list. An empty list removes all rules. The list is replaced as a whole or not at all.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Replace Redirect Rules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ordered list of rules",
+                        "name": "rules",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReplaceRedirectRulesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rules replaced",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListRedirectRulesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Append a rule that sends matching visitors to a different destination. Rules are evaluated in order and the first match wins; visitors matching no rule go to the URL's long_url. Match values may list several comma-separated options. Time windows are either a daily UTC range (\"09:00-17:00\") or two RFC 3339 timestamps separated by a slash.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Add Redirect Rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rule to add",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RedirectRuleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rule added",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RedirectRuleInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{shortID}/rules/{ruleID}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove a single redirect rule from a URL",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Delete Redirect Rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Rule ID",
+                        "name": "ruleID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rule deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL or rule not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{shortID}/variants": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the weighted destinations a URL's traffic is split across",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "variants"
+                ],
+                "summary": "List A/B Variants",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "A/B variants",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListURLVariantsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Split a URL's traffic across weighted destinations (e.g. 70/30). Weights range from 1 to 10000 and destinations must be http(s) URLs. Variants are matched by name, so keeping a name keeps its click history. Variants missing from the list are removed; an empty list turns the split off. The list is replaced as a whole or not at all. Visitors stick to their variant through a cookie. Conditional redirect rules take precedence over the split.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "variants"
+                ],
+                "summary": "Replace A/B Variants",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variants",
+                        "name": "variants",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReplaceURLVariantsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Variants replaced",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListURLVariantsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the webhooks registered by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List Webhooks",
+                "responses": {
+                    "200": {
+                        "description": "Webhooks retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListWebhooksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Register an endpoint that is notified of events on your links: link.created, link.updated, link.deleted, link.expired and link.clicked. Deliveries are JSON POSTs signed with the returned secret in the X-Webhook-Signature header (\"sha256=\" + hex HMAC-SHA256 of \"{X-Webhook-Timestamp}.{body}\"). Failed deliveries are retried with exponential backoff and end up in the dead-letter list. The secret is only shown once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create Webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook to register",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List deliveries of all your webhooks that ran out of attempts. Retry them once the receiving endpoint is fixed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List Dead-Letter Deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of deliveries (1-500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deliveries retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListWebhookDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{webhookID}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the URL or events of a webhook, or pause it with active=false. Events of a paused webhook are not queued.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Update Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a webhook along with its queued and past deliveries",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{webhookID}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the most recent deliveries of a webhook with their payload, attempts and the outcome of the last attempt",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List Webhook Deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "Only list deliveries with this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of deliveries (1-500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deliveries retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListWebhookDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{webhookID}/deliveries/{deliveryID}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Queue a delivery for immediate redelivery with a fresh set of attempts, typically one from the dead-letter list",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Retry Webhook Delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "deliveryID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delivery queued",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookDeliveryInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/conversions/pixel.gif": {
+            "get": {
+                "description": "A 1x1 GIF to embed on the page shown after a conversion. The click ID is taken from the click_id parameter or, for links with click ID mode \"cookie\", from the cookie set by the redirect. The image is served whether or not a conversion was recorded.",
+                "produces": [
+                    "image/gif"
+                ],
+                "tags": [
+                    "conversions"
+                ],
+                "summary": "Conversion Pixel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Click ID (defaults to the click ID cookie)",
+                        "name": "click_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "conversion",
+                        "description": "Conversion event",
+                        "name": "event",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Conversion value",
+                        "name": "value",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 4217 currency of the value",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transparent 1x1 GIF",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Check the health status of the application and its dependencies",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health Check",
+                "responses": {
+                    "200": {
+                        "description": "Service is healthy",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Service is unhealthy",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten": {
+            "post": {
+                "description": "Create a shortened URL. Custom IDs, tags and folders require authentication.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Shorten URL",
+                "parameters": [
+                    {
+                        "description": "URL to shorten",
+                        "name": "url",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShortenURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL shortened successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShortenURLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Authentication required for custom URLs, tags or folders",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Custom ID already taken",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "description": "Get global statistics including total URLs, clicks, and users",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "statistics"
+                ],
+                "summary": "Get Global Statistics",
+                "responses": {
+                    "200": {
+                        "description": "Global statistics",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "post": {
+                "description": "Create a new user account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create User",
+                "parameters": [
+                    {
+                        "description": "User information",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Username or email already in use",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/{shortID}": {
+            "get": {
+                "description": "Redirect to the original URL using the short ID and log the click. Conditional redirect rules of the URL are evaluated first, then any A/B split. Depending on the link, the visit's query string and the path after /{shortID}/ are forwarded, and placeholders such as {path}, {query} and {param:name} in the destination are filled in. Links before their activation time answer 404; expired links and links that reached their click limit go to their expired redirect URL, or answer 410. iOS and Android visitors of links with a deep link are sent to the app, falling back to the web destination when it is not installed. The status code depends on the link's redirect type (301, 302, 307, 308, or an HTML interstitial answering 200).",
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Redirect to Original URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to 1 to show the link preview instead of redirecting",
+                        "name": "preview",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "301": {
+                        "description": "Permanent redirect to original URL"
+                    },
+                    "302": {
+                        "description": "Temporary redirect to original URL, or to the expired redirect URL of an expired link"
+                    },
+                    "307": {
+                        "description": "Temporary redirect to original URL"
+                    },
+                    "308": {
+                        "description": "Permanent redirect to original URL"
+                    },
+                    "401": {
+                        "description": "Password-protected URL, unlock form shown"
+                    },
+                    "404": {
+                        "description": "URL not found or not active yet",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "URL expired or click limit reached, and no expired redirect URL is set",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Verify the password submitted from the unlock form. On success a signed, short-lived cookie is set and the visitor is sent back to the short link. Attempts are limited per IP address.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Unlock Password-Protected URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Link password",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "Password accepted, redirect to the short link"
+                    },
+                    "401": {
+                        "description": "Wrong password, unlock form shown again"
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many attempts"
+                    }
+                }
+            }
+        },
+        "/{shortID}+": {
+            "get": {
+                "description": "Show the destination, title, creation date and safety status of a short link without redirecting. Returns JSON when format=json is set or the Accept header asks for application/json. The same preview is available as GET /{shortID}?preview=1.",
+                "produces": [
+                    "text/html",
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Preview Short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to json for the JSON variant",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Link preview",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PreviewResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/{shortID}/qr": {
+            "get": {
+                "description": "Render a QR code for a short link as PNG or SVG. The code encodes the short link with src=qr so that scans are reported separately in click analytics.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "qr"
+                ],
+                "summary": "Get QR Code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "svg"
+                        ],
+                        "type": "string",
+                        "default": "png",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Width and height in pixels (64-2048)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "L",
+                            "M",
+                            "Q",
+                            "H"
+                        ],
+                        "type": "string",
+                        "default": "M",
+                        "description": "Error correction level",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 4,
+                        "description": "Quiet zone in modules (0-16)",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "000000",
+                        "description": "Foreground hex color",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ffffff",
+                        "description": "Background hex color",
+                        "name": "bg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Draw the configured logo in the center",
+                        "name": "logo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid option",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.APIKeyInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "handlers.AggregateAnalyticsResponse": {
+            "type": "object",
+            "properties": {
+                "change_percent": {
+                    "type": "number",
+                    "example": 20
+                },
+                "clicks": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "conversion_rate": {
+                    "type": "number",
+                    "example": 3.5
+                },
+                "conversions": {
+                    "type": "integer",
+                    "example": 48
+                },
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.AnalyticsCount"
+                    }
+                },
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.AnalyticsCount"
+                    }
+                },
+                "links": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "period": {
+                    "$ref": "#/definitions/handlers.AnalyticsPeriod"
+                },
+                "previous_clicks": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "previous_period": {
+                    "$ref": "#/definitions/handlers.AnalyticsPeriod"
+                },
+                "referrers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.AnalyticsCount"
+                    }
+                },
+                "revenue": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "top_links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.LinkAnalytics"
+                    }
+                }
+            }
+        },
+        "handlers.AnalyticsCount": {
+            "type": "object",
+            "properties": {
+                "change_percent": {
+                    "description": "ChangePercent is null when there were no clicks in the previous period",
+                    "type": "number",
+                    "example": 50
+                },
+                "clicks": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "previous_clicks": {
+                    "type": "integer",
+                    "example": 80
+                },
+                "value": {
+                    "type": "string",
+                    "example": "GH"
+                }
+            }
+        },
+        "handlers.AnalyticsPeriod": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string",
+                    "example": "2024-03-01T00:00:00Z"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2024-03-31T00:00:00Z"
+                }
+            }
+        },
+        "handlers.AnalyticsResponse": {
+            "type": "object",
+            "additionalProperties": {
+                "type": "integer"
+            }
+        },
+        "handlers.ClickEvent": {
+            "type": "object",
+            "properties": {
+                "country": {
+                    "type": "string",
+                    "example": "GH"
+                },
+                "device": {
+                    "type": "string",
+                    "example": "mobile"
+                },
+                "referrer": {
+                    "type": "string",
+                    "example": "twitter.com"
+                },
+                "short_id": {
+                    "type": "string",
+                    "example": "abc123"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "qr"
+                },
+                "time": {
+                    "type": "string",
+                    "example": "2024-03-01T09:00:00Z"
+                }
+            }
+        },
+        "handlers.ConversionInfo": {
+            "type": "object",
+            "properties": {
+                "click_id": {
+                    "type": "string",
+                    "example": "3f2b8c1e-5d4a-4b7e-9c2f-1a2b3c4d5e6f"
+                },
+                "conversion_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-03-01T09:30:00Z"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "purchase"
+                },
+                "short_id": {
+                    "type": "string",
+                    "example": "abc123"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "api"
+                },
+                "value": {
+                    "type": "number",
+                    "example": 49.99
+                }
+            }
+        },
+        "handlers.ConversionRequest": {
+            "type": "object",
+            "required": [
+                "click_id"
+            ],
+            "properties": {
+                "click_id": {
+                    "type": "string",
+                    "example": "3f2b8c1e-5d4a-4b7e-9c2f-1a2b3c4d5e6f"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "purchase"
+                },
+                "value": {
+                    "type": "number",
+                    "example": 49.99
+                }
+            }
+        },
+        "handlers.ConversionStatsResponse": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer",
+                    "example": 1520
+                },
+                "conversion_rate": {
+                    "description": "ConversionRate is the percentage of clicks that converted",
+                    "type": "number",
+                    "example": 3.75
+                },
+                "conversions": {
+                    "type": "integer",
+                    "example": 61
+                },
+                "converted_clicks": {
+                    "type": "integer",
+                    "example": 57
+                },
+                "events": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "revenue": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "short_id": {
+                    "type": "string",
+                    "example": "abc123"
+                }
+            }
+        },
+        "handlers.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "handlers.CreateUserRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "username"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "handlers.CreateUserResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "handlers.DeleteAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "api_key"
+            ],
+            "properties": {
+                "api_key": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "handlers.FolderInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "folder_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Spring Campaign"
+                }
+            }
+        },
+        "handlers.FolderRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Spring Campaign"
+                }
+            }
+        },
+        "handlers.JobRunInfo": {
+            "type": "object",
+            "properties": {
+                "affected": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "error": {
+                    "type": "string",
+                    "example": ""
+                },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2024-01-01T03:00:02Z"
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "url-shortener-7d9f8b6c4-x2k8p"
+                },
+                "job_name": {
+                    "type": "string",
+                    "example": "expiry-sweep"
+                },
+                "run_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2024-01-01T03:00:00Z"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "running",
+                        "succeeded",
+                        "failed"
+                    ],
+                    "example": "succeeded"
+                }
+            }
+        },
+        "handlers.LinkAnalytics": {
+            "type": "object",
+            "properties": {
+                "change_percent": {
+                    "type": "number",
+                    "example": 50
+                },
+                "clicks": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "conversion_rate": {
+                    "type": "number",
+                    "example": 4.17
+                },
+                "conversions": {
+                    "type": "integer",
+                    "example": 6
+                },
+                "long_url": {
+                    "type": "string",
+                    "example": "https://example.com/spring"
+                },
+                "previous_clicks": {
+                    "type": "integer",
+                    "example": 80
+                },
+                "revenue": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "short_id": {
+                    "type": "string",
+                    "example": "abc123"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Spring launch"
+                }
+            }
+        },
+        "handlers.ListAPIKeysResponse": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.APIKeyInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ListFoldersResponse": {
+            "type": "object",
+            "properties": {
+                "folders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.FolderInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ListJobRunsResponse": {
+            "type": "object",
+            "properties": {
+                "runs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.JobRunInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ListRedirectRulesResponse": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.RedirectRuleInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ListTagsResponse": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.TagInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ListURLVariantsResponse": {
+            "type": "object",
+            "properties": {
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.URLVariantInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ListURLsResponse": {
+            "type": "object",
+            "properties": {
+                "urls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.URLInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ListWebhookDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.WebhookDeliveryInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ListWebhooksResponse": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.WebhookInfo"
+                    }
+                }
+            }
+        },
+        "handlers.PreviewResponse": {
+            "type": "object",
+            "properties": {
+                "activates_at": {
+                    "type": "string",
+                    "example": "2024-03-01T09:00:00Z"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Landing page for the spring campaign"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2024-12-31T23:59:59Z"
+                },
+                "favicon_url": {
+                    "type": "string",
+                    "example": "https://example.com/favicon.ico"
+                },
+                "long_url": {
+                    "type": "string",
+                    "example": "https://example.com"
+                },
+                "password_protected": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "safety": {
+                    "$ref": "#/definitions/handlers.SafetyInfo"
+                },
+                "short_id": {
+                    "type": "string",
+                    "example": "abc123"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "scheduled",
+                        "active",
+                        "expired",
+                        "limit_reached"
+                    ],
+                    "example": "active"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Example Domain"
+                }
+            }
+        },
+        "handlers.RedirectRuleInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "destination": {
+                    "type": "string",
+                    "example": "https://apps.apple.com/app/id123456789"
+                },
+                "match_type": {
+                    "type": "string",
+                    "example": "os"
+                },
+                "match_value": {
+                    "type": "string",
+                    "example": "ios"
+                },
+                "position": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "rule_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "handlers.RedirectRuleRequest": {
+            "type": "object",
+            "required": [
+                "destination",
+                "match_type",
+                "match_value"
+            ],
+            "properties": {
+                "destination": {
+                    "type": "string",
+                    "example": "https://apps.apple.com/app/id123456789"
+                },
+                "match_type": {
+                    "type": "string",
+                    "enum": [
+                        "device",
+                        "os",
+                        "country",
+                        "language",
+                        "time"
+                    ],
+                    "example": "os"
+                },
+                "match_value": {
+                    "type": "string",
+                    "example": "ios"
+                }
+            }
+        },
+        "handlers.ReplaceRedirectRulesRequest": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.RedirectRuleRequest"
+                    }
+                }
+            }
+        },
+        "handlers.ReplaceURLVariantsRequest": {
+            "type": "object",
+            "properties": {
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.URLVariantRequest"
+                    }
+                }
+            }
+        },
+        "handlers.SafetyInfo": {
+            "type": "object",
+            "properties": {
+                "safe": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.ShortenURLRequest": {
+            "type": "object",
+            "required": [
+                "long_url"
+            ],
+            "properties": {
+                "activates_at": {
+                    "type": "string",
+                    "example": "2024-03-01T09:00:00Z"
+                },
+                "android_app_url": {
+                    "type": "string",
+                    "example": "intent://product/42#Intent;scheme=myapp;package=com.example.app;end"
+                },
+                "click_id_mode": {
+                    "type": "string",
+                    "enum": [
+                        "query",
+                        "cookie"
+                    ],
+                    "example": "query"
+                },
+                "click_limit": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "custom_id": {
+                    "type": "string",
+                    "example": "my-custom-url"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Landing page for the spring campaign"
+                },
+                "expired_redirect_url": {
+                    "type": "string",
+                    "example": "https://example.com/campaign-ended"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2024-12-31T23:59:59Z"
+                },
+                "folder_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "forward_path": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "ios_app_url": {
+                    "type": "string",
+                    "example": "myapp://product/42"
+                },
+                "long_url": {
+                    "type": "string",
+                    "example": "https://example.com"
+                },
+                "notes": {
+                    "type": "string",
+                    "example": "Shared in the March newsletter"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "s3cret"
+                },
+                "query_mode": {
+                    "type": "string",
+                    "enum": [
+                        "merge",
+                        "override"
+                    ],
+                    "example": "merge"
+                },
+                "redirect_type": {
+                    "type": "string",
+                    "enum": [
+                        "301",
+                        "302",
+                        "307",
+                        "308",
+                        "interstitial"
+                    ],
+                    "example": "302"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "marketing",
+                        "spring"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Example Domain"
+                }
+            }
+        },
+        "handlers.ShortenURLResponse": {
+            "type": "object",
+            "properties": {
+                "short_url": {
+                    "type": "string",
+                    "example": "abc123"
+                }
+            }
+        },
+        "handlers.StreamTokenResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string",
+                    "example": "2024-03-01T09:01:00Z"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "3f2a9c0d1e4b5a6978c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4"
+                }
+            }
+        },
+        "handlers.TagAnalytics": {
+            "type": "object",
+            "properties": {
+                "click_count": {
+                    "type": "integer",
+                    "example": 3400
+                },
+                "name": {
+                    "type": "string",
+                    "example": "marketing"
+                },
+                "tag_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "url_count": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "handlers.TagAnalyticsResponse": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.TagAnalytics"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.APIKeyInfo": {
+        },
+        "handlers.TagInfo": {
             "type": "object",
             "properties": {
                 "created_at": {
                     "type": "string",
                     "example": "2023-01-01T00:00:00Z"
                 },
-                "key": {
+                "name": {
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "marketing"
                 },
-                "user_id": {
+                "tag_id": {
                     "type": "string",
                     "example": "550e8400-e29b-41d4-a716-446655440000"
                 }
             }
         },
-        "handlers.AnalyticsResponse": {
+        "handlers.TagRequest": {
             "type": "object",
-            "additionalProperties": {
-                "type": "integer"
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "marketing"
+                }
             }
         },
-        "handlers.CreateAPIKeyResponse": {
+        "handlers.TagURLAnalyticsResponse": {
             "type": "object",
             "properties": {
-                "api_key": {
-                    "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
-                },
-                "created_at": {
+                "name": {
                     "type": "string",
-                    "example": "2023-01-01T00:00:00Z"
+                    "example": "marketing"
                 },
-                "user_id": {
+                "tag_id": {
                     "type": "string",
                     "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "total_clicks": {
+                    "type": "integer",
+                    "example": 3400
+                },
+                "urls": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
                 }
             }
         },
-        "handlers.CreateUserRequest": {
+        "handlers.URLInfo": {
             "type": "object",
-            "required": [
-                "email",
-                "username"
-            ],
             "properties": {
-                "email": {
+                "activates_at": {
                     "type": "string",
-                    "example": "john@example.com"
+                    "example": "2024-03-01T09:00:00Z"
                 },
-                "username": {
+                "android_app_url": {
                     "type": "string",
-                    "example": "john_doe"
+                    "example": "intent://product/42#Intent;scheme=myapp;package=com.example.app;end"
+                },
+                "click_count": {
+                    "type": "integer",
+                    "example": 1520
+                },
+                "click_id_mode": {
+                    "type": "string",
+                    "example": "query"
+                },
+                "click_limit": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "clicks_today": {
+                    "type": "integer",
+                    "example": 37
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Landing page for the spring campaign"
+                },
+                "expired_redirect_url": {
+                    "type": "string",
+                    "example": "https://example.com/campaign-ended"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2024-12-31T23:59:59Z"
+                },
+                "favicon_url": {
+                    "type": "string",
+                    "example": "https://example.com/favicon.ico"
+                },
+                "folder_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "forward_path": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "ios_app_url": {
+                    "type": "string",
+                    "example": "myapp://product/42"
+                },
+                "long_url": {
+                    "type": "string",
+                    "example": "https://example.com"
+                },
+                "notes": {
+                    "type": "string",
+                    "example": "Shared in the March newsletter"
+                },
+                "password_protected": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "query_mode": {
+                    "type": "string",
+                    "example": "merge"
+                },
+                "redirect_type": {
+                    "type": "string",
+                    "example": "302"
+                },
+                "short_id": {
+                    "type": "string",
+                    "example": "abc123"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "marketing",
+                        "spring"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Example Domain"
+                },
+                "unique_visitors": {
+                    "type": "integer",
+                    "example": 1184
                 }
             }
         },
-        "handlers.CreateUserResponse": {
+        "handlers.URLVariantInfo": {
             "type": "object",
             "properties": {
                 "created_at": {
                     "type": "string",
                     "example": "2023-01-01T00:00:00Z"
                 },
-                "email": {
+                "destination": {
                     "type": "string",
-                    "example": "john@example.com"
+                    "example": "https://example.com/landing-b"
                 },
-                "user_id": {
+                "name": {
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "B"
                 },
-                "username": {
+                "variant_id": {
                     "type": "string",
-                    "example": "john_doe"
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 30
                 }
             }
         },
-        "handlers.DeleteAPIKeyRequest": {
+        "handlers.URLVariantRequest": {
             "type": "object",
             "required": [
-                "api_key"
+                "destination",
+                "name",
+                "weight"
             ],
             "properties": {
-                "api_key": {
+                "destination": {
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "https://example.com/landing-b"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "B"
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 30
                 }
             }
         },
-        "handlers.ListAPIKeysResponse": {
+        "handlers.UpdateURLRequest": {
             "type": "object",
             "properties": {
-                "api_keys": {
+                "activates_at": {
+                    "type": "string",
+                    "example": "2024-03-01T09:00:00Z"
+                },
+                "android_app_url": {
+                    "type": "string",
+                    "example": "myapp://product/42"
+                },
+                "click_id_mode": {
+                    "type": "string",
+                    "enum": [
+                        "query",
+                        "cookie"
+                    ],
+                    "example": "cookie"
+                },
+                "click_limit": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Landing page for the spring campaign"
+                },
+                "expired_redirect_url": {
+                    "type": "string",
+                    "example": "https://example.com/campaign-ended"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2024-12-31T23:59:59Z"
+                },
+                "folder_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "forward_path": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "ios_app_url": {
+                    "type": "string",
+                    "example": "https://app.example.com/product/42"
+                },
+                "long_url": {
+                    "type": "string",
+                    "example": "https://new-example.com"
+                },
+                "notes": {
+                    "type": "string",
+                    "example": "Shared in the March newsletter"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "s3cret"
+                },
+                "query_mode": {
+                    "type": "string",
+                    "enum": [
+                        "merge",
+                        "override"
+                    ],
+                    "example": "override"
+                },
+                "redirect_type": {
+                    "type": "string",
+                    "enum": [
+                        "301",
+                        "302",
+                        "307",
+                        "308",
+                        "interstitial"
+                    ],
+                    "example": "307"
+                },
+                "tags": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.APIKeyInfo"
-                    }
+                        "type": "string"
+                    },
+                    "example": [
+                        "marketing",
+                        "spring"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Example Domain"
                 }
             }
         },
-        "handlers.ListURLsResponse": {
+        "handlers.UpdateWebhookRequest": {
             "type": "object",
             "properties": {
-                "urls": {
+                "active": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "events": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.URLInfo"
-                    }
+                        "type": "string"
+                    },
+                    "example": [
+                        "link.created",
+                        "link.deleted"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://crm.example.com/hooks/links"
                 }
             }
         },
-        "handlers.ShortenURLRequest": {
+        "handlers.WebhookDeliveryInfo": {
             "type": "object",
-            "required": [
-                "long_url"
-            ],
             "properties": {
-                "click_limit": {
+                "attempts": {
                     "type": "integer",
-                    "example": 100
+                    "example": 1
                 },
-                "custom_id": {
+                "created_at": {
                     "type": "string",
-                    "example": "my-custom-url"
+                    "example": "2024-03-01T09:00:00Z"
                 },
-                "expires_at": {
+                "delivered_at": {
                     "type": "string",
-                    "example": "2024-12-31T23:59:59Z"
+                    "example": "2024-03-01T09:00:01Z"
                 },
-                "long_url": {
+                "delivery_id": {
                     "type": "string",
-                    "example": "https://example.com"
-                }
-            }
-        },
-        "handlers.ShortenURLResponse": {
-            "type": "object",
-            "properties": {
-                "short_url": {
+                    "example": "7d0f1c2e-4b7a-4c55-9a4e-2f6d1e3b8a90"
+                },
+                "event": {
                     "type": "string",
-                    "example": "abc123"
+                    "example": "link.clicked"
+                },
+                "last_attempt_at": {
+                    "type": "string",
+                    "example": "2024-03-01T09:00:01Z"
+                },
+                "last_error": {
+                    "type": "string",
+                    "example": "unexpected status 503"
+                },
+                "last_status_code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "next_attempt_at": {
+                    "type": "string",
+                    "example": "2024-03-01T09:05:00Z"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "delivered",
+                        "dead"
+                    ],
+                    "example": "delivered"
+                },
+                "webhook_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
                 }
             }
         },
-        "handlers.URLInfo": {
+        "handlers.WebhookInfo": {
             "type": "object",
             "properties": {
-                "click_limit": {
-                    "type": "integer",
-                    "example": 100
+                "active": {
+                    "type": "boolean",
+                    "example": true
                 },
                 "created_at": {
                     "type": "string",
-                    "example": "2023-01-01T00:00:00Z"
+                    "example": "2024-03-01T09:00:00Z"
                 },
-                "expires_at": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "link.created",
+                        "link.clicked"
+                    ]
+                },
+                "secret": {
                     "type": "string",
-                    "example": "2024-12-31T23:59:59Z"
+                    "example": "whsec_3f9a..."
                 },
-                "long_url": {
+                "url": {
                     "type": "string",
-                    "example": "https://example.com"
+                    "example": "https://crm.example.com/hooks/links"
                 },
-                "short_id": {
+                "webhook_id": {
                     "type": "string",
-                    "example": "abc123"
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
                 }
             }
         },
-        "handlers.UpdateURLRequest": {
+        "handlers.WebhookRequest": {
             "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
             "properties": {
-                "click_limit": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "link.created",
+                        "link.clicked"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://crm.example.com/hooks/links"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "not_found"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "URL not found"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "6f1c2a4e-8b0d-4c8e-9f4a-2d7f0e3b9c51"
+                },
+                "status": {
                     "type": "integer",
-                    "example": 200
+                    "example": 404
                 },
-                "expires_at": {
+                "title": {
                     "type": "string",
-                    "example": "2024-12-31T23:59:59Z"
+                    "example": "Not Found"
                 },
-                "long_url": {
+                "type": {
                     "type": "string",
-                    "example": "https://new-example.com"
+                    "example": "about:blank"
                 }
             }
         }
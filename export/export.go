@@ -0,0 +1,184 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// batchSize is how many clicks are read per query. Clicks are read in
+// batches by id rather than through one long query so an export never holds
+// the database connection for longer than a batch.
+const batchSize = 1000
+
+type kind int
+
+const (
+	kindInt kind = iota
+	kindTime
+	kindString
+)
+
+// column is an exported click column. value returns nil for NULLs.
+type column struct {
+	name     string
+	kind     kind
+	nullable bool
+	value    func(sqlc.ExportClicksRow) interface{}
+}
+
+func text(t pgtype.Text) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.String
+}
+
+func uuidText(id pgtype.UUID) interface{} {
+	if !id.Valid {
+		return nil
+	}
+	return uuid.UUID(id.Bytes).String()
+}
+
+var columns = []column{
+	{"id", kindInt, false, func(c sqlc.ExportClicksRow) interface{} { return c.ID }},
+	{"short_id", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.ShortID) }},
+	{"clicked_at", kindTime, false, func(c sqlc.ExportClicksRow) interface{} { return c.ClickedAt.Time.UTC() }},
+	{"ip_address", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.IpAddress) }},
+	{"user_agent", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.UserAgent) }},
+	{"country", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.Country) }},
+	{"location", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.Location) }},
+	{"device", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.Device) }},
+	{"referrer", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.Referrer) }},
+	{"source", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.Source) }},
+	{"variant_id", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return uuidText(c.VariantID) }},
+	{"visitor_hash", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return text(c.VisitorHash) }},
+	{"click_id", kindString, true, func(c sqlc.ExportClicksRow) interface{} { return uuidText(c.ClickID) }},
+}
+
+// Columns lists the names of every exportable column in their default order
+func Columns() []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// ParseColumns parses a comma-separated column list. An empty list selects
+// every column.
+func ParseColumns(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return Columns(), nil
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := lookup(name); !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func lookup(name string) (column, bool) {
+	for _, c := range columns {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return column{}, false
+}
+
+func selectColumns(names []string) ([]column, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no columns selected")
+	}
+	selected := make([]column, len(names))
+	for i, name := range names {
+		c, ok := lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		selected[i] = c
+	}
+	return selected, nil
+}
+
+// ContentType returns the media type of a format, or "" for unknown formats
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	}
+	return ""
+}
+
+// Filter selects the clicks to export. Clicks of every link of the user are
+// exported unless ShortID is set; From is inclusive and To exclusive.
+type Filter struct {
+	UserID  uuid.UUID
+	ShortID string
+	From    *time.Time
+	To      *time.Time
+}
+
+// Stream writes the clicks matching filter to w batch by batch, flushing
+// after each one, and returns the number written.
+func Stream(ctx context.Context, db *sqlc.Queries, filter Filter, w Writer) (int64, error) {
+	params := sqlc.ExportClicksParams{
+		UserID:    pgtype.UUID{Bytes: filter.UserID, Valid: true},
+		ShortID:   pgtype.Text{String: filter.ShortID, Valid: filter.ShortID != ""},
+		BatchSize: batchSize,
+	}
+	if filter.From != nil {
+		params.FromTime = pgtype.Timestamp{Time: *filter.From, Valid: true}
+	}
+	if filter.To != nil {
+		params.ToTime = pgtype.Timestamp{Time: *filter.To, Valid: true}
+	}
+
+	var written int64
+	for {
+		clicks, err := db.ExportClicks(ctx, params)
+		if err != nil {
+			return written, err
+		}
+		for _, click := range clicks {
+			if err := w.Write(click); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if err := w.Flush(); err != nil {
+			return written, err
+		}
+		if len(clicks) < batchSize {
+			return written, nil
+		}
+		params.AfterID = clicks[len(clicks)-1].ID
+	}
+}
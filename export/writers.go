@@ -0,0 +1,225 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// rowGroupSize is how many rows go in each Parquet row group. Row groups are
+// buffered in memory, so this bounds the memory of a Parquet export.
+const rowGroupSize = 50000
+
+// Writer encodes exported clicks. Flush pushes what has been encoded so far
+// to the output, and Close must be called once every click was written.
+type Writer interface {
+	Write(click sqlc.ExportClicksRow) error
+	Flush() error
+	Close() error
+}
+
+// NewWriter creates a Writer of the named columns in the given format
+func NewWriter(format string, out io.Writer, names []string) (Writer, error) {
+	cols, err := selectColumns(names)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(out, cols)
+	case FormatNDJSON:
+		return &ndjsonWriter{out: out, buf: bufio.NewWriter(out), cols: cols}, nil
+	case FormatParquet:
+		return newParquetWriter(out, cols), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// flushOutput flushes out if it buffers, like an http.ResponseWriter does
+func flushOutput(out io.Writer) {
+	if f, ok := out.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+type csvWriter struct {
+	out    io.Writer
+	csv    *csv.Writer
+	cols   []column
+	record []string
+}
+
+func newCSVWriter(out io.Writer, cols []column) (*csvWriter, error) {
+	w := &csvWriter{out: out, csv: csv.NewWriter(out), cols: cols, record: make([]string, len(cols))}
+	for i, c := range cols {
+		w.record[i] = c.name
+	}
+	return w, w.csv.Write(w.record)
+}
+
+func (w *csvWriter) Write(click sqlc.ExportClicksRow) error {
+	for i, c := range w.cols {
+		switch v := c.value(click).(type) {
+		case nil:
+			w.record[i] = ""
+		case int32:
+			w.record[i] = strconv.FormatInt(int64(v), 10)
+		case time.Time:
+			w.record[i] = v.Format(time.RFC3339)
+		case string:
+			w.record[i] = v
+		}
+	}
+	return w.csv.Write(w.record)
+}
+
+func (w *csvWriter) Flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	return w.Flush()
+}
+
+type ndjsonWriter struct {
+	out  io.Writer
+	buf  *bufio.Writer
+	cols []column
+}
+
+// Write encodes a click as one JSON object with its keys in column order
+func (w *ndjsonWriter) Write(click sqlc.ExportClicksRow) error {
+	w.buf.WriteByte('{')
+	for i, c := range w.cols {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(c.name)
+		value, err := json.Marshal(c.value(click))
+		if err != nil {
+			return err
+		}
+		w.buf.Write(key)
+		w.buf.WriteByte(':')
+		w.buf.Write(value)
+	}
+	w.buf.WriteString("}\n")
+	return nil
+}
+
+func (w *ndjsonWriter) Flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.Flush()
+}
+
+type parquetWriter struct {
+	out      io.Writer
+	pq       *parquet.Writer
+	cols     []column
+	index    []int // position of each column in the schema
+	buffered int
+	row      parquet.Row
+}
+
+func newParquetWriter(out io.Writer, cols []column) *parquetWriter {
+	group := parquet.Group{}
+	for _, c := range cols {
+		var node parquet.Node
+		switch c.kind {
+		case kindInt:
+			node = parquet.Int(32)
+		case kindTime:
+			node = parquet.Timestamp(parquet.Millisecond)
+		default:
+			node = parquet.String()
+		}
+		if c.nullable {
+			node = parquet.Optional(node)
+		}
+		group[c.name] = node
+	}
+	schema := parquet.NewSchema("click", group)
+
+	// Groups order their fields by name, which need not be the column order
+	positions := make(map[string]int)
+	for i, path := range schema.Columns() {
+		positions[path[0]] = i
+	}
+	index := make([]int, len(cols))
+	for i, c := range cols {
+		index[i] = positions[c.name]
+	}
+
+	return &parquetWriter{
+		out:   out,
+		pq:    parquet.NewWriter(out, schema, parquet.Compression(&parquet.Snappy)),
+		cols:  cols,
+		index: index,
+		row:   make(parquet.Row, len(cols)),
+	}
+}
+
+func (w *parquetWriter) Write(click sqlc.ExportClicksRow) error {
+	for i, c := range w.cols {
+		var value parquet.Value
+		switch v := c.value(click).(type) {
+		case int32:
+			value = parquet.Int32Value(v)
+		case time.Time:
+			value = parquet.Int64Value(v.UnixMilli())
+		case string:
+			value = parquet.ByteArrayValue([]byte(v))
+		}
+		definition := 0
+		if c.nullable && !value.IsNull() {
+			definition = 1
+		}
+		w.row[w.index[i]] = value.Level(0, definition, w.index[i])
+	}
+	if _, err := w.pq.WriteRows([]parquet.Row{w.row}); err != nil {
+		return err
+	}
+	w.buffered++
+	return nil
+}
+
+// Flush only writes a row group once enough rows are buffered; small row
+// groups make Parquet files slow to read.
+func (w *parquetWriter) Flush() error {
+	if w.buffered < rowGroupSize {
+		return nil
+	}
+	if err := w.pq.Flush(); err != nil {
+		return err
+	}
+	w.buffered = 0
+	flushOutput(w.out)
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.pq.Close(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
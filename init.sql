@@ -7,7 +7,18 @@ CREATE TABLE IF NOT EXISTS users (
     username VARCHAR(50) UNIQUE NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMP
+    updated_at TIMESTAMP,
+    plan VARCHAR(20) NOT NULL DEFAULT 'free',
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Create folders table
+CREATE TABLE IF NOT EXISTS folders (
+    folder_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
 );
 
 -- Create urls table
@@ -18,7 +29,40 @@ CREATE TABLE IF NOT EXISTS urls (
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     expires_at TIMESTAMP,
     click_limit INTEGER,
-    CONSTRAINT valid_click_limit CHECK (click_limit IS NULL OR click_limit > 0)
+    folder_id UUID REFERENCES folders(folder_id) ON DELETE SET NULL,
+    title TEXT,
+    description TEXT,
+    notes TEXT,
+    favicon_url TEXT,
+    redirect_type VARCHAR(16),
+    password_hash TEXT,
+    query_mode VARCHAR(16),
+    forward_path BOOLEAN NOT NULL DEFAULT FALSE,
+    ios_app_url TEXT,
+    android_app_url TEXT,
+    activates_at TIMESTAMP,
+    expired_redirect_url TEXT,
+    click_id_mode VARCHAR(16),
+    -- Running click total that click retention does not reduce
+    total_clicks BIGINT NOT NULL DEFAULT 0,
+    last_clicked_at TIMESTAMP,
+    CONSTRAINT valid_click_limit CHECK (click_limit IS NULL OR click_limit > 0),
+    CONSTRAINT valid_schedule CHECK (activates_at IS NULL OR expires_at IS NULL OR activates_at < expires_at),
+    CONSTRAINT valid_redirect_type CHECK (redirect_type IS NULL OR redirect_type IN ('301', '302', '307', '308', 'interstitial')),
+    CONSTRAINT valid_query_mode CHECK (query_mode IS NULL OR query_mode IN ('merge', 'override')),
+    CONSTRAINT valid_click_id_mode CHECK (click_id_mode IS NULL OR click_id_mode IN ('query', 'cookie'))
+);
+
+-- Create url_variants table
+CREATE TABLE IF NOT EXISTS url_variants (
+    variant_id UUID PRIMARY KEY,
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    destination TEXT NOT NULL,
+    weight INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_weight CHECK (weight > 0),
+    UNIQUE (short_id, name)
 );
 
 -- Create clicks table
@@ -27,7 +71,15 @@ CREATE TABLE IF NOT EXISTS clicks (
     short_id VARCHAR(10) REFERENCES urls(short_id) ON DELETE CASCADE,
     ip_address VARCHAR(45),
     user_agent TEXT,
-    clicked_at TIMESTAMP NOT NULL DEFAULT NOW()
+    clicked_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    variant_id UUID REFERENCES url_variants(variant_id) ON DELETE SET NULL,
+    source VARCHAR(16),
+    country VARCHAR(2),
+    location VARCHAR(255),
+    device VARCHAR(16),
+    referrer VARCHAR(255),
+    visitor_hash VARCHAR(64),
+    click_id UUID
 );
 
 -- Create api_keys table
@@ -37,14 +89,237 @@ CREATE TABLE IF NOT EXISTS api_keys (
     created_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
+-- Create tags table
+CREATE TABLE IF NOT EXISTS tags (
+    tag_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+-- Create url_tags join table
+CREATE TABLE IF NOT EXISTS url_tags (
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (short_id, tag_id)
+);
+
+-- Create redirect_rules table
+CREATE TABLE IF NOT EXISTS redirect_rules (
+    rule_id UUID PRIMARY KEY,
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    match_type VARCHAR(16) NOT NULL,
+    match_value TEXT NOT NULL,
+    destination TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_match_type CHECK (match_type IN ('device', 'os', 'country', 'language', 'time'))
+);
+
+-- Create click_rollups_hourly table
+CREATE TABLE IF NOT EXISTS click_rollups_hourly (
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL,
+    country VARCHAR(2) NOT NULL DEFAULT '',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    device VARCHAR(16) NOT NULL DEFAULT '',
+    referrer VARCHAR(255) NOT NULL DEFAULT '',
+    clicks BIGINT NOT NULL,
+    PRIMARY KEY (short_id, bucket, country, location, device, referrer)
+);
+
+-- Create click_rollups_daily table
+CREATE TABLE IF NOT EXISTS click_rollups_daily (
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL,
+    country VARCHAR(2) NOT NULL DEFAULT '',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    device VARCHAR(16) NOT NULL DEFAULT '',
+    referrer VARCHAR(255) NOT NULL DEFAULT '',
+    clicks BIGINT NOT NULL,
+    PRIMARY KEY (short_id, bucket, country, location, device, referrer)
+);
+
+-- Create rollup_state table
+CREATE TABLE IF NOT EXISTS rollup_state (
+    name VARCHAR(50) PRIMARY KEY,
+    rolled_up_to TIMESTAMP NOT NULL
+);
+
+-- Create webhooks table
+CREATE TABLE IF NOT EXISTS webhooks (
+    webhook_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Create webhook_deliveries table (the delivery queue and its history)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id UUID PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+    event VARCHAR(32) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_attempt_at TIMESTAMP,
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_delivery_status CHECK (status IN ('pending', 'delivered', 'dead'))
+);
+
+-- Create expiry_notifications table (links whose expiry was announced)
+CREATE TABLE IF NOT EXISTS expiry_notifications (
+    short_id VARCHAR(10) PRIMARY KEY REFERENCES urls(short_id) ON DELETE CASCADE,
+    notified_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Create url_counters table (reconciled from the Redis click counters)
+CREATE TABLE IF NOT EXISTS url_counters (
+    short_id VARCHAR(10) PRIMARY KEY REFERENCES urls(short_id) ON DELETE CASCADE,
+    click_count BIGINT NOT NULL DEFAULT 0,
+    unique_visitors BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Create click_ids table (the links of issued click IDs, kept for the
+-- conversion window whatever the click retention)
+CREATE TABLE IF NOT EXISTS click_ids (
+    click_id UUID PRIMARY KEY,
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    clicked_at TIMESTAMP NOT NULL
+);
+
+-- Create conversions table (conversions recorded against the click ID of a redirect)
+CREATE TABLE IF NOT EXISTS conversions (
+    conversion_id UUID PRIMARY KEY,
+    click_id UUID NOT NULL,
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    value NUMERIC(18, 4),
+    currency VARCHAR(3),
+    source VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (click_id, event),
+    CONSTRAINT valid_conversion_value CHECK (value IS NULL OR (value >= 0 AND currency IS NOT NULL)),
+    CONSTRAINT valid_conversion_source CHECK (source IN ('api', 'pixel'))
+);
+
+-- Create job_runs table
+CREATE TABLE IF NOT EXISTS job_runs (
+    run_id UUID PRIMARY KEY,
+    job_name VARCHAR(50) NOT NULL,
+    instance VARCHAR(255) NOT NULL,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP,
+    status VARCHAR(16) NOT NULL,
+    affected BIGINT,
+    error TEXT,
+    CONSTRAINT valid_job_status CHECK (status IN ('running', 'succeeded', 'failed'))
+);
+
+-- Add columns introduced after a table was first created, so that existing
+-- databases are brought up to date by running this script again
+ALTER TABLE users ADD COLUMN IF NOT EXISTS plan VARCHAR(20) NOT NULL DEFAULT 'free';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(folder_id) ON DELETE SET NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS notes TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS favicon_url TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_type VARCHAR(16);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS query_mode VARCHAR(16);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS forward_path BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS ios_app_url TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS android_app_url TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS activates_at TIMESTAMP;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS expired_redirect_url TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS click_id_mode VARCHAR(16);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS total_clicks BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_clicked_at TIMESTAMP;
+
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS variant_id UUID REFERENCES url_variants(variant_id) ON DELETE SET NULL;
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS source VARCHAR(16);
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS country VARCHAR(2);
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS location VARCHAR(255);
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS device VARCHAR(16);
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS referrer VARCHAR(255);
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS visitor_hash VARCHAR(64);
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS click_id UUID;
+
+-- Add the checks on those columns; ADD CONSTRAINT has no IF NOT EXISTS
+DO $$
+BEGIN
+    ALTER TABLE urls ADD CONSTRAINT valid_schedule CHECK (activates_at IS NULL OR expires_at IS NULL OR activates_at < expires_at);
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+DO $$
+BEGIN
+    ALTER TABLE urls ADD CONSTRAINT valid_redirect_type CHECK (redirect_type IS NULL OR redirect_type IN ('301', '302', '307', '308', 'interstitial'));
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+DO $$
+BEGIN
+    ALTER TABLE urls ADD CONSTRAINT valid_query_mode CHECK (query_mode IS NULL OR query_mode IN ('merge', 'override'));
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+DO $$
+BEGIN
+    ALTER TABLE urls ADD CONSTRAINT valid_click_id_mode CHECK (click_id_mode IS NULL OR click_id_mode IN ('query', 'cookie'));
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+-- Seed the click totals of links clicked before they were kept
+UPDATE urls SET total_clicks = c.total, last_clicked_at = c.last_clicked_at
+FROM (SELECT short_id, COUNT(*) AS total, MAX(clicked_at) AS last_clicked_at FROM clicks GROUP BY short_id) AS c
+WHERE c.short_id = urls.short_id AND urls.total_clicks < c.total;
+
+-- Seed the click IDs of clicks logged before they were kept separately
+INSERT INTO click_ids (click_id, short_id, clicked_at)
+SELECT click_id, short_id, clicked_at FROM clicks
+WHERE click_id IS NOT NULL AND short_id IS NOT NULL
+ON CONFLICT (click_id) DO NOTHING;
+
 -- Create indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_urls_user_id ON urls(user_id);
 CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls(created_at);
 CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls(expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_urls_folder_id ON urls(folder_id) WHERE folder_id IS NOT NULL;
 
 CREATE INDEX IF NOT EXISTS idx_clicks_short_id ON clicks(short_id);
 CREATE INDEX IF NOT EXISTS idx_clicks_clicked_at ON clicks(clicked_at);
 CREATE INDEX IF NOT EXISTS idx_clicks_ip_address ON clicks(ip_address);
+CREATE INDEX IF NOT EXISTS idx_clicks_visitor_hash ON clicks(short_id, visitor_hash) WHERE visitor_hash IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clicks_click_id ON clicks(click_id) WHERE click_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_click_ids_clicked_at ON click_ids(clicked_at);
+CREATE INDEX IF NOT EXISTS idx_conversions_short_id ON conversions(short_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag_id ON url_tags(tag_id);
+
+CREATE INDEX IF NOT EXISTS idx_redirect_rules_short_id ON redirect_rules(short_id, position);
+CREATE INDEX IF NOT EXISTS idx_url_variants_short_id ON url_variants(short_id);
+
+CREATE INDEX IF NOT EXISTS idx_click_rollups_hourly_bucket ON click_rollups_hourly(bucket);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_name ON job_runs(job_name, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_job_runs_started_at ON job_runs(started_at);
 
 CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
 CREATE INDEX IF NOT EXISTS idx_api_keys_created_at ON api_keys(created_at);
@@ -0,0 +1,295 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/yeboahd24/url-shortener/counters"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/webhooks"
+)
+
+// urlCachePrefix is the prefix of the redirect cache keys written by the
+// redirect handler (url:{shortID}).
+const urlCachePrefix = "url:"
+
+// cacheScanBatch is how many cache keys are checked against the database at once
+const cacheScanBatch = 500
+
+// AnonymousPlan is the retention plan of links created without an account
+const AnonymousPlan = "anonymous"
+
+func timestamp(t time.Time) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: t, Valid: true}
+}
+
+// ParseRetention parses a per-plan retention setting such as "free=90,pro=365"
+// into retention periods. Values are in days; plans that are not listed keep
+// their data forever.
+func ParseRetention(s string) (map[string]time.Duration, error) {
+	retention := map[string]time.Duration{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		plan, daysStr, ok := strings.Cut(entry, "=")
+		days, err := strconv.Atoi(strings.TrimSpace(daysStr))
+		if !ok || err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid retention %q, expected plan=days", entry)
+		}
+		retention[strings.TrimSpace(plan)] = time.Duration(days) * 24 * time.Hour
+	}
+	return retention, nil
+}
+
+// ExpirySweep deletes links that expired or used up their click limit more
+// than grace ago, along with their cached redirects and click counters. Links stay around for
+// the grace period so their expired redirect and analytics keep working.
+func ExpirySweep(redisClient *redis.Client, grace time.Duration) Job {
+	return Job{
+		Name:     "expiry-sweep",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			shortIDs, err := db.DeleteExpiredURLs(ctx, timestamp(time.Now().Add(-grace)))
+			if err != nil {
+				return 0, err
+			}
+			if len(shortIDs) > 0 {
+				keys := make([]string, 0, 3*len(shortIDs))
+				for _, shortID := range shortIDs {
+					keys = append(keys, urlCachePrefix+shortID)
+					keys = append(keys, counters.Keys(shortID)...)
+				}
+				if err := redisClient.Del(ctx, keys...).Err(); err != nil {
+					return int64(len(shortIDs)), err
+				}
+			}
+			return int64(len(shortIDs)), nil
+		},
+	}
+}
+
+// ClickRetention deletes click rows older than the retention period of the
+// plan of the link owner. Rows the rollup has not reached yet are kept.
+func ClickRetention(retention map[string]time.Duration) Job {
+	plans := make([]string, 0, len(retention))
+	for plan := range retention {
+		plans = append(plans, plan)
+	}
+	sort.Strings(plans)
+
+	return Job{
+		Name:     "click-retention",
+		Interval: 6 * time.Hour,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			var total int64
+			for _, plan := range plans {
+				deleted, err := db.DeleteClicksBefore(ctx, sqlc.DeleteClicksBeforeParams{
+					Before: timestamp(time.Now().Add(-retention[plan])),
+					Plan:   plan,
+				})
+				total += deleted
+				if err != nil {
+					return total, fmt.Errorf("plan %s: %w", plan, err)
+				}
+			}
+			return total, nil
+		},
+	}
+}
+
+// ClickIDRetention forgets click IDs once they are too old to convert
+func ClickIDRetention(window time.Duration) Job {
+	return Job{
+		Name:     "click-id-retention",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			return db.DeleteClickIDsBefore(ctx, timestamp(time.Now().Add(-window)))
+		},
+	}
+}
+
+// CacheCleanup removes cached redirects of links that no longer exist, e.g.
+// ones deleted directly in the database.
+func CacheCleanup(redisClient *redis.Client) Job {
+	return Job{
+		Name:     "cache-cleanup",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			var removed int64
+			iter := redisClient.Scan(ctx, 0, urlCachePrefix+"*", cacheScanBatch).Iterator()
+			batch := make([]string, 0, cacheScanBatch)
+
+			flush := func() error {
+				n, err := removeStaleKeys(ctx, db, redisClient, batch)
+				removed += n
+				batch = batch[:0]
+				return err
+			}
+
+			for iter.Next(ctx) {
+				batch = append(batch, strings.TrimPrefix(iter.Val(), urlCachePrefix))
+				if len(batch) == cacheScanBatch {
+					if err := flush(); err != nil {
+						return removed, err
+					}
+				}
+			}
+			if err := iter.Err(); err != nil {
+				return removed, err
+			}
+			return removed, flush()
+		},
+	}
+}
+
+func removeStaleKeys(ctx context.Context, db *sqlc.Queries, redisClient *redis.Client, shortIDs []string) (int64, error) {
+	if len(shortIDs) == 0 {
+		return 0, nil
+	}
+
+	existing, err := db.ListExistingShortIDs(ctx, shortIDs)
+	if err != nil {
+		return 0, err
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, shortID := range existing {
+		exists[shortID] = true
+	}
+
+	var stale []string
+	for _, shortID := range shortIDs {
+		if !exists[shortID] {
+			stale = append(stale, urlCachePrefix+shortID)
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+	return redisClient.Del(ctx, stale...).Result()
+}
+
+// counterBatch is how many links a counter sync writes per round
+const counterBatch = 500
+
+// CounterSync reconciles the Redis click counters into Postgres so they can
+// be restored if Redis loses them.
+func CounterSync(redisClient *redis.Client) Job {
+	return Job{
+		Name:     "counter-sync",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			return counters.Reconcile(ctx, redisClient, db, counterBatch)
+		},
+	}
+}
+
+// ExpiryEvents announces links that expired or reached their click limit to
+// their owner's webhooks. Each expiry is announced once; changing the expiry
+// or click limit of a link makes it eligible again.
+func ExpiryEvents() Job {
+	return Job{
+		Name:     "expiry-events",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			shortIDs, err := db.ClaimExpiredLinks(ctx)
+			if err != nil {
+				return 0, err
+			}
+			for i, shortID := range shortIDs {
+				link, err := db.GetURL(ctx, shortID)
+				if err != nil {
+					return int64(i), err
+				}
+				if err := webhooks.Enqueue(ctx, db, link.UserID.Bytes, webhooks.EventLinkExpired, webhooks.LinkFromURL(link)); err != nil {
+					return int64(i), err
+				}
+			}
+			return int64(len(shortIDs)), nil
+		},
+	}
+}
+
+// WebhookDeliveryRetention prunes finished webhook deliveries
+func WebhookDeliveryRetention(keep time.Duration) Job {
+	return Job{
+		Name:     "webhook-delivery-retention",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			return db.DeleteWebhookDeliveriesBefore(ctx, timestamp(time.Now().Add(-keep)))
+		},
+	}
+}
+
+// JobRunRetention prunes the job run history
+func JobRunRetention(keep time.Duration) Job {
+	return Job{
+		Name:     "job-run-retention",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			return db.DeleteJobRunsBefore(ctx, timestamp(time.Now().Add(-keep)))
+		},
+	}
+}
+
+// rollupLag keeps the rollup window behind the clock so clicks that are
+// logged asynchronously right at the end of an hour still make it in.
+const rollupLag = 5 * time.Minute
+
+// hourlyRollupRetention is how long hourly rollups are kept; daily rollups
+// are kept forever.
+const hourlyRollupRetention = 90 * 24 * time.Hour
+
+// ClickRollup aggregates raw clicks of completed hours into the hourly and
+// daily rollup tables and advances the rollup watermark. Rollups are
+// recomputed rather than incremented, so a run that fails halfway is simply
+// repeated by the next one.
+func ClickRollup() Job {
+	return Job{
+		Name:     "click-rollup",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context, db *sqlc.Queries) (int64, error) {
+			from, err := db.GetRollupWatermark(ctx)
+			if err != nil {
+				return 0, err
+			}
+
+			to := time.Now().Add(-rollupLag).Truncate(time.Hour)
+			if !to.After(from.Time) {
+				return 0, nil
+			}
+
+			hourly, err := db.RollupHourlyClicks(ctx, sqlc.RollupHourlyClicksParams{
+				FromTime: from,
+				ToTime:   timestamp(to),
+			})
+			if err != nil {
+				return 0, fmt.Errorf("hourly rollup: %w", err)
+			}
+
+			_, err = db.RollupDailyClicks(ctx, sqlc.RollupDailyClicksParams{
+				FromTime: from,
+				ToTime:   timestamp(to),
+			})
+			if err != nil {
+				return hourly, fmt.Errorf("daily rollup: %w", err)
+			}
+
+			if err := db.SetRollupWatermark(ctx, timestamp(to)); err != nil {
+				return hourly, err
+			}
+
+			if _, err := db.DeleteHourlyRollupsBefore(ctx, timestamp(to.Add(-hourlyRollupRetention))); err != nil {
+				return hourly, fmt.Errorf("pruning hourly rollups: %w", err)
+			}
+			return hourly, nil
+		},
+	}
+}
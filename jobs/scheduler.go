@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/database"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// Job run statuses recorded in job_runs
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// leaderLockKey is the Postgres advisory lock held by the replica that runs
+// the jobs. Any constant works as long as every replica uses the same one.
+const leaderLockKey int64 = 0x75726c73686f7274 // "urlshort"
+
+// jobTimeout bounds a single job run
+const jobTimeout = 10 * time.Minute
+
+// Job is a unit of background work run on a fixed interval. Run returns the
+// number of rows or keys it affected, which is kept in the run history.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, db *sqlc.Queries) (int64, error)
+}
+
+// Scheduler runs registered jobs on the replica that holds the leader lock.
+// It keeps its own database connection: the advisory lock lives as long as
+// that session, so a crashed or disconnected leader frees it automatically
+// and another replica takes over on its next tick.
+type Scheduler struct {
+	dsn      string
+	tick     time.Duration
+	instance string
+	jobs     []Job
+
+	conn    *pgx.Conn
+	db      *sqlc.Queries
+	leader  bool
+	lastRun map[string]time.Time
+}
+
+// NewScheduler creates a Scheduler that connects with dsn and checks for due
+// jobs every tick.
+func NewScheduler(dsn string, tick time.Duration) *Scheduler {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+	return &Scheduler{
+		dsn:      dsn,
+		tick:     tick,
+		instance: instance,
+		lastRun:  map[string]time.Time{},
+	}
+}
+
+// Register adds jobs to the scheduler. It must be called before Run.
+func (s *Scheduler) Register(jobs ...Job) {
+	s.jobs = append(s.jobs, jobs...)
+}
+
+// Run checks for due jobs until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+	defer s.disconnect()
+
+	for {
+		if s.ensureLeader(ctx) {
+			s.runDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) disconnect() {
+	if s.conn != nil {
+		s.conn.Close(context.Background())
+	}
+	s.conn, s.db, s.leader = nil, nil, false
+}
+
+// ensureLeader (re)connects when needed and tries to take the leader lock.
+// It reports whether this replica currently leads.
+func (s *Scheduler) ensureLeader(ctx context.Context) bool {
+	if s.conn != nil && s.conn.Ping(ctx) != nil {
+		if s.leader {
+			slog.Warn("Job scheduler lost its database connection, giving up leadership")
+		}
+		s.disconnect()
+	}
+
+	if s.conn == nil {
+		conn, err := database.ConnectSession(ctx, s.dsn)
+		if err != nil {
+			slog.Error("Job scheduler failed to connect", "error", err)
+			return false
+		}
+		s.conn, s.db = conn, sqlc.New(conn)
+	}
+
+	if s.leader {
+		return true
+	}
+
+	acquired, err := s.db.TryAdvisoryLock(ctx, leaderLockKey)
+	if err != nil {
+		slog.Error("Job scheduler failed to take the leader lock", "error", err)
+		return false
+	}
+	if !acquired {
+		return false
+	}
+
+	slog.Info("Job scheduler is now the leader", "instance", s.instance)
+	s.leader = true
+	s.loadLastRuns(ctx)
+	return true
+}
+
+// loadLastRuns picks up the schedule of the previous leader so a failover
+// doesn't rerun every job at once.
+func (s *Scheduler) loadLastRuns(ctx context.Context) {
+	runs, err := s.db.LatestJobRuns(ctx)
+	if err != nil {
+		slog.Error("Failed to load job history", "error", err)
+		return
+	}
+	for _, run := range runs {
+		s.lastRun[run.JobName] = run.StartedAt.Time
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context) {
+	for _, job := range s.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(s.lastRun[job.Name]) < job.Interval {
+			continue
+		}
+		s.runJob(ctx, job)
+	}
+}
+
+// runJob runs a single job and records the outcome in job_runs
+func (s *Scheduler) runJob(ctx context.Context, job Job) {
+	started := time.Now()
+	s.lastRun[job.Name] = started
+
+	run, err := s.db.CreateJobRun(ctx, sqlc.CreateJobRunParams{
+		RunID:     uuid.New(),
+		JobName:   job.Name,
+		Instance:  s.instance,
+		StartedAt: pgtype.Timestamp{Time: started, Valid: true},
+	})
+	if err != nil {
+		slog.Error("Failed to record job run", "job", job.Name, "error", err)
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	affected, err := job.Run(jobCtx, s.db)
+	cancel()
+
+	status := StatusSucceeded
+	errText := pgtype.Text{Valid: false}
+	if err != nil {
+		status = StatusFailed
+		errText = pgtype.Text{String: err.Error(), Valid: true}
+		slog.Error("Job failed", "job", job.Name, "error", err)
+	} else {
+		slog.Debug("Job finished", "job", job.Name, "affected", affected)
+	}
+
+	err = s.db.FinishJobRun(ctx, sqlc.FinishJobRunParams{
+		RunID:      run.RunID,
+		FinishedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		Status:     status,
+		Affected:   pgtype.Int8{Int64: affected, Valid: true},
+		Error:      errText,
+	})
+	if err != nil {
+		slog.Error("Failed to record job result", "job", job.Name, "error", err)
+	}
+}
@@ -2,18 +2,30 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5"
 	"github.com/redis/go-redis/v9"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"github.com/yeboahd24/url-shortener/api/handlers"
 	"github.com/yeboahd24/url-shortener/api/middleware"
+	"github.com/yeboahd24/url-shortener/api/problem"
 	"github.com/yeboahd24/url-shortener/config"
+	"github.com/yeboahd24/url-shortener/database"
 	_ "github.com/yeboahd24/url-shortener/docs"
+	"github.com/yeboahd24/url-shortener/jobs"
+	"github.com/yeboahd24/url-shortener/logging"
+	"github.com/yeboahd24/url-shortener/metadata"
+	"github.com/yeboahd24/url-shortener/metrics"
+	"github.com/yeboahd24/url-shortener/qr"
 	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/tracing"
+	"github.com/yeboahd24/url-shortener/visitorid"
+	"github.com/yeboahd24/url-shortener/webhooks"
 )
 
 // @title URL Shortener API
@@ -42,19 +54,127 @@ func main() {
 		log.Fatal(err)
 	}
 
-	db, err := pgx.Connect(context.Background(), cfg.PostgresDSN)
+	logger, err := logging.New(os.Stdout, cfg.LogFormat, cfg.LogLevel)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close(context.Background())
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := database.Connect(context.Background(), cfg.PostgresDSN)
+	if err != nil {
+		fatal("Failed to connect to Postgres", "error", err)
+	}
+	defer db.Close()
 
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddr,
 		Password: cfg.RedisPass,
 	})
+	redisClient.AddHook(metrics.RedisHook{})
+	redisClient.AddHook(tracing.RedisHook{})
 
 	queries := sqlc.New(db)
+
+	var fetcher *metadata.Fetcher
+	if cfg.FetchMetadata {
+		fetcher = metadata.NewFetcher(cfg.MetadataTimeout, cfg.MetadataAllowPrivate)
+	}
+
+	if !handlers.ValidRedirectType(cfg.DefaultRedirectType) {
+		fatal("Invalid DEFAULT_REDIRECT_TYPE", "value", cfg.DefaultRedirectType)
+	}
+	cookieSecret := []byte(cfg.LinkCookieSecret)
+	if len(cookieSecret) == 0 {
+		slog.Warn("LINK_COOKIE_SECRET is not set, generating a random one; unlocked links will not survive restarts")
+		cookieSecret = make([]byte, 32)
+		if _, err := rand.Read(cookieSecret); err != nil {
+			fatal("Failed to generate a cookie secret", "error", err)
+		}
+	}
+	redirectOpts := handlers.RedirectOptions{
+		DefaultType:      cfg.DefaultRedirectType,
+		PermanentMaxAge:  cfg.PermanentRedirectMaxAge,
+		CookieSecret:     cookieSecret,
+		UnlockTTL:        cfg.UnlockTTL,
+		StoreIPs:         cfg.StoreClickIPs,
+		Visitors:         visitorid.NewHasher(redisClient),
+		ClickIDParam:     cfg.ClickIDParam,
+		ConversionWindow: cfg.ConversionWindow,
+	}
+	appLinks := handlers.AppLinksOptions{
+		AppleAppIDs:         config.SplitList(cfg.AppleAppIDs),
+		ApplePaths:          config.SplitList(cfg.AppleAppPaths),
+		AndroidPackage:      cfg.AndroidPackage,
+		AndroidFingerprints: config.SplitList(cfg.AndroidFingerprints),
+	}
+	qrOpts := handlers.QROptions{BaseURL: cfg.PublicBaseURL}
+	if cfg.QRLogoFile != "" {
+		qrOpts.Logo, err = qr.LoadLogo(cfg.QRLogoFile)
+		if err != nil {
+			fatal("Failed to load QR_LOGO_FILE", "error", err)
+		}
+	}
+
+	if cfg.JobsEnabled {
+		clickRetention, err := jobs.ParseRetention(cfg.ClickRetentionDays)
+		if err != nil {
+			fatal("Invalid CLICK_RETENTION_DAYS", "error", err)
+		}
+
+		scheduler := jobs.NewScheduler(cfg.PostgresDSN, cfg.JobTick)
+		// Jobs run in registration order: clicks are rolled up before
+		// retention deletes them
+		scheduler.Register(jobs.ClickRollup())
+		if cfg.ExpiredLinkRetention > 0 {
+			scheduler.Register(jobs.ExpirySweep(redisClient, cfg.ExpiredLinkRetention))
+		}
+		if len(clickRetention) > 0 {
+			scheduler.Register(jobs.ClickRetention(clickRetention))
+		}
+		if cfg.ConversionWindow > 0 {
+			scheduler.Register(jobs.ClickIDRetention(cfg.ConversionWindow))
+		}
+		scheduler.Register(
+			jobs.CounterSync(redisClient),
+			jobs.ExpiryEvents(),
+			jobs.CacheCleanup(redisClient),
+			jobs.JobRunRetention(cfg.JobRunRetention),
+			jobs.WebhookDeliveryRetention(cfg.WebhookDeliveryRetention),
+		)
+		go scheduler.Run(context.Background())
+	}
+
+	dispatcher := webhooks.NewDispatcher(cfg.PostgresDSN, cfg.WebhookPollInterval, cfg.WebhookTimeout,
+		cfg.WebhookMaxAttempts, cfg.WebhookAllowPrivate)
+	go dispatcher.Run(context.Background())
+
+	trustedProxies, err := middleware.ParseProxies(config.SplitList(cfg.TrustedProxies))
+	if err != nil {
+		fatal("Invalid TRUSTED_PROXIES", "error", err)
+	}
+
 	r := chi.NewRouter()
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, "Not found", http.StatusNotFound)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+	r.Use(middleware.RealIP(trustedProxies))
+	r.Use(tracing.Middleware)
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Metrics)
 	r.Use(middleware.Logger)
 	r.Use(middleware.RateLimitMiddleware(redisClient))
 
@@ -63,39 +183,130 @@ func main() {
 		httpSwagger.URL("http://localhost:9000/swagger/doc.json"),
 	))
 
+	// Prometheus metrics, on their own listener or behind a token
+	switch {
+	case cfg.MetricsAddr != "":
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler(cfg.MetricsToken))
+		go func() {
+			fatal("Metrics listener stopped", "error", http.ListenAndServe(cfg.MetricsAddr, metricsMux))
+		}()
+	case cfg.MetricsToken != "":
+		r.Method(http.MethodGet, "/metrics", metrics.Handler(cfg.MetricsToken))
+	default:
+		slog.Info("METRICS_ADDR and METRICS_TOKEN are not set, /metrics is disabled")
+	}
+
 	// Health and stats routes (public)
 	r.Get("/health", handlers.HealthCheck(db, redisClient))
 	r.Get("/stats", handlers.GetStats(queries))
 
+	// Mobile app association files (public)
+	r.Get("/.well-known/apple-app-site-association", handlers.AppleAppSiteAssociation(appLinks))
+	r.Get("/apple-app-site-association", handlers.AppleAppSiteAssociation(appLinks))
+	r.Get("/.well-known/assetlinks.json", handlers.AssetLinks(appLinks))
+
 	// User management routes (public)
 	r.Post("/users", handlers.CreateUser(queries))
 
 	// URL shortening (public)
-	r.Post("/shorten", handlers.ShortenURL(queries))
+	r.Post("/shorten", handlers.ShortenURL(queries, fetcher))
 
 	// Authenticated routes
 	r.Route("/api", func(r chi.Router) {
-		r.Use(middleware.AuthMiddleware(queries))
+		// Live click streams also take a stream token in the query string,
+		// since browsers cannot set headers on EventSource and WebSocket
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.StreamAuth(queries, redisClient))
+
+			r.Get("/analytics/{shortID}/stream", handlers.StreamClicks(queries, redisClient))
+			r.Get("/analytics/{shortID}/ws", handlers.StreamClicksWebSocket(queries, redisClient))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware(queries))
 
-		// URL shortening (authenticated - for custom URLs and advanced features)
-		r.Post("/shorten", handlers.ShortenURL(queries))
+			// URL shortening (authenticated - for custom URLs and advanced features)
+			r.Post("/shorten", handlers.ShortenURL(queries, fetcher))
 
-		// Analytics
-		r.Get("/analytics/{shortID}", handlers.GetAnalytics(queries, cfg.GeoAPIURL))
+			// Analytics
+			r.Get("/analytics", handlers.GetAggregateAnalytics(queries))
+			r.Get("/analytics/export", handlers.ExportAllClicks(queries))
+			r.Get("/analytics/{shortID}", handlers.GetAnalytics(queries))
+			r.Get("/analytics/{shortID}/export", handlers.ExportClicks(queries))
+			r.Get("/analytics/{shortID}/conversions", handlers.GetConversionStats(queries, redisClient))
+			r.Post("/conversions", handlers.CreateConversion(queries, cfg.ConversionWindow))
+			r.Post("/analytics/{shortID}/stream-token", handlers.CreateStreamToken(queries, redisClient))
 
-		// API key management
-		r.Post("/keys", handlers.CreateAPIKey(queries))
-		r.Get("/keys", handlers.ListAPIKeys(queries))
-		r.Delete("/keys", handlers.DeleteAPIKey(queries))
+			// API key management
+			r.Post("/keys", handlers.CreateAPIKey(queries))
+			r.Get("/keys", handlers.ListAPIKeys(queries))
+			r.Delete("/keys", handlers.DeleteAPIKey(queries))
 
-		// URL management
-		r.Get("/urls", handlers.ListUserURLs(queries))
-		r.Delete("/urls/{shortID}", handlers.DeleteURL(queries))
-		r.Put("/urls/{shortID}", handlers.UpdateURL(queries))
+			// URL management
+			r.Get("/urls", handlers.ListUserURLs(queries, redisClient))
+			r.Delete("/urls/{shortID}", handlers.DeleteURL(queries, redisClient))
+			r.Put("/urls/{shortID}", handlers.UpdateURL(queries, redisClient, fetcher))
+
+			// Conditional redirect rules
+			r.Get("/urls/{shortID}/rules", handlers.ListRedirectRules(queries))
+			r.Post("/urls/{shortID}/rules", handlers.AddRedirectRule(queries, redisClient))
+			r.Put("/urls/{shortID}/rules", handlers.ReplaceRedirectRules(queries, redisClient))
+			r.Delete("/urls/{shortID}/rules/{ruleID}", handlers.DeleteRedirectRule(queries, redisClient))
+
+			// A/B split destinations
+			r.Get("/urls/{shortID}/variants", handlers.ListURLVariants(queries))
+			r.Put("/urls/{shortID}/variants", handlers.ReplaceURLVariants(queries, redisClient))
+
+			// QR codes
+			r.Get("/urls/{shortID}/qr", handlers.GetURLQRCode(queries, qrOpts))
+
+			// Tags
+			r.Post("/tags", handlers.CreateTag(queries))
+			r.Get("/tags", handlers.ListTags(queries))
+			r.Get("/tags/analytics", handlers.GetTagAnalytics(queries))
+			r.Put("/tags/{tagID}", handlers.UpdateTag(queries))
+			r.Delete("/tags/{tagID}", handlers.DeleteTag(queries))
+			r.Get("/tags/{tagID}/analytics", handlers.GetTagURLAnalytics(queries))
+
+			// Folders
+			r.Post("/folders", handlers.CreateFolder(queries))
+			r.Get("/folders", handlers.ListFolders(queries))
+			r.Put("/folders/{folderID}", handlers.UpdateFolder(queries))
+			r.Delete("/folders/{folderID}", handlers.DeleteFolder(queries))
+
+			// Webhooks
+			r.Post("/webhooks", handlers.CreateWebhook(queries))
+			r.Get("/webhooks", handlers.ListWebhooks(queries))
+			r.Get("/webhooks/dead-letters", handlers.ListDeadWebhookDeliveries(queries))
+			r.Put("/webhooks/{webhookID}", handlers.UpdateWebhook(queries))
+			r.Delete("/webhooks/{webhookID}", handlers.DeleteWebhook(queries))
+			r.Get("/webhooks/{webhookID}/deliveries", handlers.ListWebhookDeliveries(queries))
+			r.Post("/webhooks/{webhookID}/deliveries/{deliveryID}/retry", handlers.RetryWebhookDelivery(queries))
+
+			// Administration
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(middleware.AdminOnly(queries))
+
+				r.Get("/jobs/runs", handlers.ListJobRuns(queries))
+			})
+		})
 	})
 
-	// Redirect route (must be last to avoid conflicts)
-	r.Get("/{shortID}", handlers.RedirectURL(queries, redisClient, cfg.GeoAPIURL))
+	// Link preview and redirect routes (must be last to avoid conflicts)
+	r.Get("/conversions/pixel.gif", handlers.ConversionPixel(queries, cfg.ConversionWindow))
+	r.Get("/{shortID}+", handlers.PreviewURL(queries))
+	r.Get("/{shortID}/qr", handlers.GetQRCode(queries, qrOpts))
+	r.Get("/{shortID}", handlers.RedirectURL(queries, redisClient, cfg.GeoAPIURL, redirectOpts))
+	r.Get("/{shortID}/*", handlers.RedirectURL(queries, redisClient, cfg.GeoAPIURL, redirectOpts))
+	r.Post("/{shortID}", handlers.UnlockURL(queries, redisClient, redirectOpts))
+
+	slog.Info("Listening", "port", cfg.Port)
+	fatal("Server stopped", "error", http.ListenAndServe(":"+cfg.Port, r))
+}
 
-	log.Fatal(http.ListenAndServe(":"+cfg.Port, r))
+// fatal logs msg at error level and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,155 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yeboahd24/url-shortener/netguard"
+	"github.com/yeboahd24/url-shortener/tracing"
+	"golang.org/x/net/html"
+)
+
+// maxBodySize caps how much of the destination page is read while looking
+// for metadata; everything we need lives in <head>.
+const maxBodySize = 1 << 20
+
+// Metadata holds the page details extracted from a destination URL
+type Metadata struct {
+	Title       string
+	Description string
+	FaviconURL  string
+}
+
+// Fetcher downloads destination pages and extracts their title, description
+// and favicon from the HTML and OpenGraph tags.
+type Fetcher struct {
+	Client    *http.Client
+	UserAgent string
+}
+
+// NewFetcher creates a Fetcher whose requests give up after timeout. Unless
+// allowPrivate is set, pages on private networks are refused: anyone can
+// shorten a URL, and the fetched title is readable through the preview page.
+func NewFetcher(timeout time.Duration, allowPrivate bool) *Fetcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = netguard.RefusePrivate
+	}
+	return &Fetcher{
+		Client: &http.Client{
+			Timeout:   timeout,
+			Transport: tracing.Transport(&http.Transport{DialContext: dialer.DialContext}),
+		},
+		UserAgent: "url-shortener-metadata/1.0",
+	}
+}
+
+// Fetch downloads pageURL and extracts its metadata
+func (f *Fetcher) Fetch(ctx context.Context, pageURL string) (Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Metadata{}, fmt.Errorf("unexpected status %d fetching %s", resp.StatusCode, pageURL)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return Metadata{}, fmt.Errorf("unexpected content type %q fetching %s", ct, pageURL)
+	}
+
+	meta, err := Parse(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	// Resolve the favicon against the final URL after redirects, falling
+	// back to the conventional /favicon.ico location.
+	base := resp.Request.URL
+	if meta.FaviconURL == "" {
+		meta.FaviconURL = "/favicon.ico"
+	}
+	if ref, err := url.Parse(meta.FaviconURL); err == nil {
+		meta.FaviconURL = base.ResolveReference(ref).String()
+	}
+	return meta, nil
+}
+
+// Parse extracts metadata from an HTML document. OpenGraph values win over
+// the plain <title> and description meta tags.
+func Parse(r io.Reader) (Metadata, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var meta, og Metadata
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				if meta.Title == "" && n.FirstChild != nil {
+					meta.Title = strings.TrimSpace(n.FirstChild.Data)
+				}
+			case "meta":
+				key := strings.ToLower(attr(n, "property"))
+				if key == "" {
+					key = strings.ToLower(attr(n, "name"))
+				}
+				content := strings.TrimSpace(attr(n, "content"))
+				switch key {
+				case "og:title":
+					og.Title = content
+				case "og:description":
+					og.Description = content
+				case "description":
+					meta.Description = content
+				}
+			case "link":
+				rel := strings.ToLower(attr(n, "rel"))
+				if meta.FaviconURL == "" && (rel == "icon" || rel == "shortcut icon") {
+					meta.FaviconURL = attr(n, "href")
+				}
+			case "body":
+				// Metadata only lives in <head>
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if og.Title != "" {
+		meta.Title = og.Title
+	}
+	if og.Description != "" {
+		meta.Description = og.Description
+	}
+	return meta, nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
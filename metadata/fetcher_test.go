@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yeboahd24/url-shortener/netguard"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+<title>Plain title</title>
+<meta property="og:title" content="OpenGraph title">
+<meta name="description" content="Plain description">
+<link rel="icon" href="/static/icon.png">
+</head>
+<body><title>Not this one</title></body>
+</html>`
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/page" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(5*time.Second, true)
+	meta, err := fetcher.Fetch(context.Background(), server.URL+"/page")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	want := Metadata{
+		Title:       "OpenGraph title",
+		Description: "Plain description",
+		FaviconURL:  server.URL + "/static/icon.png",
+	}
+	if meta != want {
+		t.Errorf("Fetch = %+v, want %+v", meta, want)
+	}
+}
+
+func TestFetchDefaultFavicon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>No icon</title></head></html>`))
+	}))
+	defer server.Close()
+
+	meta, err := NewFetcher(5*time.Second, true).Fetch(context.Background(), server.URL+"/docs/")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if meta.FaviconURL != server.URL+"/favicon.ico" {
+		t.Errorf("FaviconURL = %q, want %q", meta.FaviconURL, server.URL+"/favicon.ico")
+	}
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"title": "json"}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewFetcher(5*time.Second, true).Fetch(context.Background(), server.URL); err == nil {
+		t.Error("Fetch of a JSON document succeeded, want an error")
+	}
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher(5*time.Second, false).Fetch(context.Background(), server.URL)
+	if !errors.Is(err, netguard.ErrPrivateAddress) {
+		t.Errorf("Fetch error = %v, want %v", err, netguard.ErrPrivateAddress)
+	}
+}
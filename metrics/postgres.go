@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// QueryTracer records the latency of every query run on a connection. Set it
+// as the Tracer of the connection config.
+type QueryTracer struct{}
+
+type queryStart struct {
+	name string
+	at   time.Time
+}
+
+type queryStartKey struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{name: sqlc.QueryName(data.SQL), at: time.Now()})
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	DBDuration.WithLabelValues(start.name).Observe(time.Since(start.at).Seconds())
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		DBErrors.WithLabelValues(start.name).Inc()
+	}
+}
@@ -0,0 +1,28 @@
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned for connections to loopback, private or
+// link-local addresses, which include cloud metadata endpoints.
+var ErrPrivateAddress = errors.New("refusing to connect to a private address")
+
+// RefusePrivate is a net.Dialer Control function that stops connections to
+// addresses inside our own network. It runs after name resolution, so it
+// also covers hosts that resolve to private addresses and redirects to them.
+func RefusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Size limits in pixels
+const (
+	MinSize = 64
+	MaxSize = 2048
+)
+
+// MaxMargin is the largest quiet zone, in modules, around a code
+const MaxMargin = 16
+
+// logoScale is the share of the code width covered by a centered logo. It is
+// kept small enough for level Q error correction to recover the hidden modules.
+const logoScale = 0.2
+
+// Options controls how a QR code is rendered
+type Options struct {
+	// Size is the width and height of the image in pixels
+	Size int
+	// Level is the error correction level: L, M, Q or H
+	Level string
+	// Margin is the quiet zone around the code, in modules
+	Margin int
+	// Foreground and Background are the module and background colors
+	Foreground color.RGBA
+	Background color.RGBA
+	// Logo is drawn in the center of the code when set
+	Logo image.Image
+}
+
+// DefaultOptions returns black-on-white, 256 pixel, level M options with the
+// standard 4 module margin.
+func DefaultOptions() Options {
+	return Options{
+		Size:       256,
+		Level:      "M",
+		Margin:     4,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// LoadLogo reads a PNG or JPEG logo from disk
+func LoadLogo(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// ParseColor parses a hex color such as "1a2b3c" or "#1a2b3c"
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+func recoveryLevel(level string, logo bool) (qrcode.RecoveryLevel, error) {
+	var l qrcode.RecoveryLevel
+	switch strings.ToUpper(level) {
+	case "L":
+		l = qrcode.Low
+	case "M", "":
+		l = qrcode.Medium
+	case "Q":
+		l = qrcode.High
+	case "H":
+		l = qrcode.Highest
+	default:
+		return 0, fmt.Errorf("invalid error correction level %q", level)
+	}
+	// A logo hides part of the code, so it needs at least level Q to scan
+	if logo && l < qrcode.High {
+		l = qrcode.High
+	}
+	return l, nil
+}
+
+// layout is the module grid of a code and where it lands in the image
+type layout struct {
+	bitmap [][]bool
+	module int
+	offset int
+}
+
+func newLayout(content string, opts Options) (layout, error) {
+	level, err := recoveryLevel(opts.Level, opts.Logo != nil)
+	if err != nil {
+		return layout{}, err
+	}
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return layout{}, err
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	modules := len(bitmap) + 2*opts.Margin
+	module := opts.Size / modules
+	if module < 1 {
+		return layout{}, fmt.Errorf("size %d is too small for this code", opts.Size)
+	}
+	// Center the grid; the integer module size leaves a few spare pixels
+	offset := (opts.Size - module*len(bitmap)) / 2
+
+	return layout{bitmap: bitmap, module: module, offset: offset}, nil
+}
+
+// logoRect returns the centered square a logo is drawn into
+func logoRect(size int) image.Rectangle {
+	side := int(float64(size) * logoScale)
+	min := (size - side) / 2
+	return image.Rect(min, min, min+side, min+side)
+}
+
+// scaleImage resizes src into a w×h image using nearest-neighbor sampling
+func scaleImage(src image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	b := src.Bounds()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(x, y, src.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h))
+		}
+	}
+	return dst
+}
+
+// PNG renders content as a PNG QR code
+func PNG(content string, opts Options) ([]byte, error) {
+	l, err := newLayout(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, opts.Size, opts.Size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: opts.Background}, image.Point{}, draw.Src)
+	fg := &image.Uniform{C: opts.Foreground}
+	for y, row := range l.bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			min := image.Pt(l.offset+x*l.module, l.offset+y*l.module)
+			draw.Draw(img, image.Rectangle{Min: min, Max: min.Add(image.Pt(l.module, l.module))}, fg, image.Point{}, draw.Src)
+		}
+	}
+
+	if opts.Logo != nil {
+		rect := logoRect(opts.Size)
+		draw.Draw(img, rect, &image.Uniform{C: opts.Background}, image.Point{}, draw.Src)
+		draw.Draw(img, rect, scaleImage(opts.Logo, rect.Dx(), rect.Dy()), image.Point{}, draw.Over)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// SVG renders content as an SVG QR code. Dark modules are drawn as a single
+// path; a logo is embedded as a PNG data URI.
+func SVG(content string, opts Options) ([]byte, error) {
+	l, err := newLayout(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, opts.Size, opts.Size)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hexColor(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hexColor(opts.Foreground))
+	for y, row := range l.bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh%dv%dh-%dz", l.offset+x*l.module, l.offset+y*l.module, l.module, l.module, l.module)
+			}
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if opts.Logo != nil {
+		rect := logoRect(opts.Size)
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, scaleImage(opts.Logo, rect.Dx(), rect.Dy())); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`,
+			rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy(), hexColor(opts.Background))
+		fmt.Fprintf(&buf, `<image x="%d" y="%d" width="%d" height="%d" href="data:image/png;base64,%s"/>`,
+			rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy(), base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+
+	buf.WriteString("</svg>")
+	return buf.Bytes(), nil
+}
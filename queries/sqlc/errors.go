@@ -0,0 +1,120 @@
+package sqlc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Domain errors. Errors returned by MapError and the hand-written queries
+// below match one of them with errors.Is.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrForbidden  = errors.New("forbidden")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+)
+
+// Error is a domain error with a description fit for API clients
+type Error struct {
+	Kind   error
+	Detail string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Postgres error codes mapped by MapError
+const (
+	codeUniqueViolation     = "23505"
+	codeForeignKeyViolation = "23503"
+	codeCheckViolation      = "23514"
+	codeNotNullViolation    = "23502"
+	codeValueTooLong        = "22001"
+	codeInvalidText         = "22P02"
+)
+
+// constraintDetails describes violations of named constraints; others get a
+// generic description
+var constraintDetails = map[string]string{
+	"users_username_key":             "Username is already taken",
+	"users_email_key":                "Email is already registered",
+	"urls_pkey":                      "Short ID is already taken",
+	"tags_user_id_name_key":          "A tag with this name already exists",
+	"folders_user_id_name_key":       "A folder with this name already exists",
+	"url_variants_short_id_name_key": "A variant with this name already exists",
+	"urls_folder_id_fkey":            "Folder not found",
+	"valid_click_limit":              "click_limit must be positive",
+	"valid_schedule":                 "activates_at must be before expires_at",
+	"valid_redirect_type":            "Invalid redirect_type",
+	"valid_query_mode":               "Invalid query_mode",
+	"valid_click_id_mode":            "Invalid click_id_mode",
+	"valid_weight":                   "weight must be positive",
+}
+
+// MapError turns missing rows and constraint violations into domain errors.
+// Other errors, including nil, are returned unchanged.
+func MapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Detail: "Not found", Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	var kind error
+	var detail string
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		kind, detail = ErrConflict, "Already exists"
+	case codeForeignKeyViolation:
+		kind, detail = ErrValidation, "Refers to a record that does not exist"
+	case codeCheckViolation, codeNotNullViolation, codeInvalidText:
+		kind, detail = ErrValidation, "Invalid value"
+	case codeValueTooLong:
+		kind, detail = ErrValidation, "Value is too long"
+	default:
+		return err
+	}
+	if d, ok := constraintDetails[pgErr.ConstraintName]; ok {
+		detail = d
+	}
+	return &Error{Kind: kind, Detail: detail, Err: err}
+}
+
+// GetOwnedURL loads a URL of a user. It fails with ErrNotFound when the URL
+// does not exist and ErrForbidden when it belongs to someone else.
+func (q *Queries) GetOwnedURL(ctx context.Context, shortID string, userID uuid.UUID) (Url, error) {
+	url, err := q.GetURL(ctx, shortID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Url{}, &Error{Kind: ErrNotFound, Detail: "URL not found", Err: err}
+		}
+		return Url{}, err
+	}
+	if !url.UserID.Valid || url.UserID.Bytes != userID {
+		return Url{}, &Error{Kind: ErrForbidden, Detail: "URL belongs to another user"}
+	}
+	return url, nil
+}
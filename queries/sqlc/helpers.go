@@ -1,7 +1,12 @@
 package sqlc
 
 import (
+    "context"
+    "errors"
+    "strings"
+
     "github.com/google/uuid"
+    "github.com/jackc/pgx/v5"
     "github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -11,4 +16,39 @@ func UUIDToNullable(id *uuid.UUID) pgtype.UUID {
         return pgtype.UUID{Valid: false}
     }
     return pgtype.UUID{Bytes: *id, Valid: true}
-}
\ No newline at end of file
+}
+// QueryName returns the name of a generated query from the "-- name: GetURL :one"
+// comment it starts with, or "other" for SQL that is not one of ours
+func QueryName(sql string) string {
+    rest, ok := strings.CutPrefix(sql, "-- name: ")
+    if !ok {
+        return "other"
+    }
+    if i := strings.IndexAny(rest, " \n"); i > 0 {
+        return rest[:i]
+    }
+    return "other"
+}
+
+// InTx runs fn with queries bound to one transaction, committing when fn
+// returns nil and rolling back otherwise. On a pool the transaction gets a
+// connection of its own for its whole run.
+func (q *Queries) InTx(ctx context.Context, fn func(*Queries) error) error {
+    beginner, ok := q.db.(interface {
+        Begin(context.Context) (pgx.Tx, error)
+    })
+    if !ok {
+        return errors.New("sqlc: connection does not support transactions")
+    }
+
+    tx, err := beginner.Begin(ctx)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback(ctx)
+
+    if err := fn(q.WithTx(tx)); err != nil {
+        return err
+    }
+    return tx.Commit(ctx)
+}
@@ -16,20 +16,147 @@ type ApiKey struct {
 }
 
 type Click struct {
-	ID        int32            `json:"id"`
-	ShortID   pgtype.Text      `json:"short_id"`
-	IpAddress pgtype.Text      `json:"ip_address"`
-	UserAgent pgtype.Text      `json:"user_agent"`
+	ID          int32            `json:"id"`
+	ShortID     pgtype.Text      `json:"short_id"`
+	IpAddress   pgtype.Text      `json:"ip_address"`
+	UserAgent   pgtype.Text      `json:"user_agent"`
+	ClickedAt   pgtype.Timestamp `json:"clicked_at"`
+	VariantID   pgtype.UUID      `json:"variant_id"`
+	Source      pgtype.Text      `json:"source"`
+	Country     pgtype.Text      `json:"country"`
+	Location    pgtype.Text      `json:"location"`
+	Device      pgtype.Text      `json:"device"`
+	Referrer    pgtype.Text      `json:"referrer"`
+	VisitorHash pgtype.Text      `json:"visitor_hash"`
+	ClickID     pgtype.UUID      `json:"click_id"`
+}
+
+type ClickID struct {
+	ClickID   uuid.UUID        `json:"click_id"`
+	ShortID   string           `json:"short_id"`
 	ClickedAt pgtype.Timestamp `json:"clicked_at"`
 }
 
-type Url struct {
+type ClickRollupsDaily struct {
+	ShortID  string           `json:"short_id"`
+	Bucket   pgtype.Timestamp `json:"bucket"`
+	Country  string           `json:"country"`
+	Location string           `json:"location"`
+	Device   string           `json:"device"`
+	Referrer string           `json:"referrer"`
+	Clicks   int64            `json:"clicks"`
+}
+
+type ClickRollupsHourly struct {
+	ShortID  string           `json:"short_id"`
+	Bucket   pgtype.Timestamp `json:"bucket"`
+	Country  string           `json:"country"`
+	Location string           `json:"location"`
+	Device   string           `json:"device"`
+	Referrer string           `json:"referrer"`
+	Clicks   int64            `json:"clicks"`
+}
+
+type Conversion struct {
+	ConversionID uuid.UUID        `json:"conversion_id"`
+	ClickID      uuid.UUID        `json:"click_id"`
+	ShortID      string           `json:"short_id"`
+	Event        string           `json:"event"`
+	Value        pgtype.Numeric   `json:"value"`
+	Currency     pgtype.Text      `json:"currency"`
+	Source       string           `json:"source"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+}
+
+type ExpiryNotification struct {
 	ShortID    string           `json:"short_id"`
-	LongUrl    string           `json:"long_url"`
-	UserID     pgtype.UUID      `json:"user_id"`
-	CreatedAt  pgtype.Timestamp `json:"created_at"`
-	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
-	ClickLimit pgtype.Int4      `json:"click_limit"`
+	NotifiedAt pgtype.Timestamp `json:"notified_at"`
+}
+
+type Folder struct {
+	FolderID  uuid.UUID        `json:"folder_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Name      string           `json:"name"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type JobRun struct {
+	RunID      uuid.UUID        `json:"run_id"`
+	JobName    string           `json:"job_name"`
+	Instance   string           `json:"instance"`
+	StartedAt  pgtype.Timestamp `json:"started_at"`
+	FinishedAt pgtype.Timestamp `json:"finished_at"`
+	Status     string           `json:"status"`
+	Affected   pgtype.Int8      `json:"affected"`
+	Error      pgtype.Text      `json:"error"`
+}
+
+type RedirectRule struct {
+	RuleID      uuid.UUID        `json:"rule_id"`
+	ShortID     string           `json:"short_id"`
+	Position    int32            `json:"position"`
+	MatchType   string           `json:"match_type"`
+	MatchValue  string           `json:"match_value"`
+	Destination string           `json:"destination"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+}
+
+type RollupState struct {
+	Name       string           `json:"name"`
+	RolledUpTo pgtype.Timestamp `json:"rolled_up_to"`
+}
+
+type Tag struct {
+	TagID     uuid.UUID        `json:"tag_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Name      string           `json:"name"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type Url struct {
+	ShortID            string           `json:"short_id"`
+	LongUrl            string           `json:"long_url"`
+	UserID             pgtype.UUID      `json:"user_id"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
+	ClickLimit         pgtype.Int4      `json:"click_limit"`
+	FolderID           pgtype.UUID      `json:"folder_id"`
+	Title              pgtype.Text      `json:"title"`
+	Description        pgtype.Text      `json:"description"`
+	Notes              pgtype.Text      `json:"notes"`
+	FaviconUrl         pgtype.Text      `json:"favicon_url"`
+	RedirectType       pgtype.Text      `json:"redirect_type"`
+	PasswordHash       pgtype.Text      `json:"password_hash"`
+	QueryMode          pgtype.Text      `json:"query_mode"`
+	ForwardPath        bool             `json:"forward_path"`
+	IosAppUrl          pgtype.Text      `json:"ios_app_url"`
+	AndroidAppUrl      pgtype.Text      `json:"android_app_url"`
+	ActivatesAt        pgtype.Timestamp `json:"activates_at"`
+	ExpiredRedirectUrl pgtype.Text      `json:"expired_redirect_url"`
+	ClickIDMode        pgtype.Text      `json:"click_id_mode"`
+	TotalClicks        int64            `json:"total_clicks"`
+	LastClickedAt      pgtype.Timestamp `json:"last_clicked_at"`
+}
+
+type UrlCounter struct {
+	ShortID        string           `json:"short_id"`
+	ClickCount     int64            `json:"click_count"`
+	UniqueVisitors int64            `json:"unique_visitors"`
+	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
+}
+
+type UrlTag struct {
+	ShortID string    `json:"short_id"`
+	TagID   uuid.UUID `json:"tag_id"`
+}
+
+type UrlVariant struct {
+	VariantID   uuid.UUID        `json:"variant_id"`
+	ShortID     string           `json:"short_id"`
+	Name        string           `json:"name"`
+	Destination string           `json:"destination"`
+	Weight      int32            `json:"weight"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
 }
 
 type User struct {
@@ -38,4 +165,31 @@ type User struct {
 	Email     string           `json:"email"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+	Plan      string           `json:"plan"`
+	IsAdmin   bool             `json:"is_admin"`
+}
+
+type Webhook struct {
+	WebhookID uuid.UUID        `json:"webhook_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Url       string           `json:"url"`
+	Secret    string           `json:"secret"`
+	Events    []string         `json:"events"`
+	Active    bool             `json:"active"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	DeliveryID     uuid.UUID        `json:"delivery_id"`
+	WebhookID      uuid.UUID        `json:"webhook_id"`
+	Event          string           `json:"event"`
+	Payload        string           `json:"payload"`
+	Status         string           `json:"status"`
+	Attempts       int32            `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamp `json:"next_attempt_at"`
+	LastAttemptAt  pgtype.Timestamp `json:"last_attempt_at"`
+	LastStatusCode pgtype.Int4      `json:"last_status_code"`
+	LastError      pgtype.Text      `json:"last_error"`
+	DeliveredAt    pgtype.Timestamp `json:"delivered_at"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
 }
@@ -12,24 +12,122 @@ import (
 )
 
 type Querier interface {
+	AddURLTag(ctx context.Context, arg AddURLTagParams) error
+	// Clicks of several links in [from_time, to_time), which must be whole days,
+	// counted per link and per country, device and referrer. Sources are split
+	// at the watermark like ClickRollupTotals.
+	AggregateClickRollups(ctx context.Context, arg AggregateClickRollupsParams) ([]AggregateClickRollupsRow, error)
+	AggregateConversionRevenue(ctx context.Context, arg AggregateConversionRevenueParams) ([]AggregateConversionRevenueRow, error)
+	AggregateConversions(ctx context.Context, arg AggregateConversionsParams) ([]AggregateConversionsRow, error)
+	ClaimExpiredLinks(ctx context.Context) ([]string, error)
+	// Claimed deliveries are leased until lease_until, so a dispatcher that dies
+	// mid-delivery hands them to the next one
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error)
+	ClearExpiryNotification(ctx context.Context, shortID string) error
+	ClearURLTags(ctx context.Context, shortID string) error
+	// Daily rollups cover whole days before the watermark, hourly rollups the
+	// rest of the day, and raw clicks everything after it.
+	ClickRollupTotals(ctx context.Context, arg ClickRollupTotalsParams) ([]ClickRollupTotalsRow, error)
+	ClickSeriesDaily(ctx context.Context, arg ClickSeriesDailyParams) ([]ClickSeriesDailyRow, error)
+	ClickSeriesHourly(ctx context.Context, arg ClickSeriesHourlyParams) ([]ClickSeriesHourlyRow, error)
+	CountClicksBySource(ctx context.Context, shortID pgtype.Text) ([]CountClicksBySourceRow, error)
+	CountClicksByVariant(ctx context.Context, shortID pgtype.Text) ([]CountClicksByVariantRow, error)
+	// Visitor hashes rotate daily, so distinct hashes are daily unique visitors
+	CountUniqueVisitors(ctx context.Context, arg CountUniqueVisitorsParams) ([]CountUniqueVisitorsRow, error)
 	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	// A click converts at most once per event; repeats return no rows
+	CreateConversion(ctx context.Context, arg CreateConversionParams) (Conversion, error)
+	CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error)
+	CreateJobRun(ctx context.Context, arg CreateJobRunParams) (JobRun, error)
+	CreateRedirectRule(ctx context.Context, arg CreateRedirectRuleParams) (RedirectRule, error)
+	CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error)
 	CreateURL(ctx context.Context, arg CreateURLParams) (Url, error)
+	CreateURLVariant(ctx context.Context, arg CreateURLVariantParams) (UrlVariant, error)
 	// queries.sql
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) error
-	DeleteURL(ctx context.Context, arg DeleteURLParams) error
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) (int64, error)
+	DeleteClickIDsBefore(ctx context.Context, before pgtype.Timestamp) (int64, error)
+	// Clicks are only deleted once they are rolled up, so a rollup that fails or
+	// lags behind never loses history.
+	DeleteClicksBefore(ctx context.Context, arg DeleteClicksBeforeParams) (int64, error)
+	DeleteExpiredURLs(ctx context.Context, before pgtype.Timestamp) ([]string, error)
+	DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error)
+	DeleteHourlyRollupsBefore(ctx context.Context, bucket pgtype.Timestamp) (int64, error)
+	DeleteJobRunsBefore(ctx context.Context, startedAt pgtype.Timestamp) (int64, error)
+	DeleteRedirectRule(ctx context.Context, arg DeleteRedirectRuleParams) (int64, error)
+	DeleteRedirectRules(ctx context.Context, shortID string) error
+	DeleteTag(ctx context.Context, arg DeleteTagParams) (int64, error)
+	DeleteURL(ctx context.Context, arg DeleteURLParams) (int64, error)
+	DeleteURLVariant(ctx context.Context, variantID uuid.UUID) error
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error)
+	DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt pgtype.Timestamp) (int64, error)
+	EnqueueLinkWebhookEvent(ctx context.Context, arg EnqueueLinkWebhookEventParams) (int64, error)
+	EnqueueUserWebhookEvent(ctx context.Context, arg EnqueueUserWebhookEventParams) (int64, error)
+	ExportClicks(ctx context.Context, arg ExportClicksParams) ([]ExportClicksRow, error)
+	FinishJobRun(ctx context.Context, arg FinishJobRunParams) error
 	GetAPIKey(ctx context.Context, key uuid.UUID) (ApiKey, error)
+	GetClickCountSeed(ctx context.Context, shortID string) (int64, error)
+	GetConversionByEvent(ctx context.Context, arg GetConversionByEventParams) (Conversion, error)
+	GetConversionClick(ctx context.Context, clickID uuid.UUID) (GetConversionClickRow, error)
+	GetConversionTotals(ctx context.Context, shortID string) (GetConversionTotalsRow, error)
+	GetFolder(ctx context.Context, arg GetFolderParams) (Folder, error)
+	GetNextRulePosition(ctx context.Context, shortID string) (int32, error)
+	GetOrCreateTag(ctx context.Context, arg GetOrCreateTagParams) (Tag, error)
+	GetRollupWatermark(ctx context.Context) (pgtype.Timestamp, error)
+	GetTag(ctx context.Context, arg GetTagParams) (Tag, error)
+	// Click counts come from the running totals, which click retention keeps.
+	GetTagAnalytics(ctx context.Context, userID uuid.UUID) ([]GetTagAnalyticsRow, error)
+	GetTagByName(ctx context.Context, arg GetTagByNameParams) (Tag, error)
+	GetTagURLClickCounts(ctx context.Context, tagID uuid.UUID) ([]GetTagURLClickCountsRow, error)
+	// Summed from the running totals, which click retention keeps
 	GetTotalClicks(ctx context.Context) (int64, error)
 	GetTotalURLs(ctx context.Context) (int64, error)
 	GetTotalUsers(ctx context.Context) (int64, error)
 	GetURL(ctx context.Context, shortID string) (Url, error)
+	GetURLClickTotal(ctx context.Context, shortID string) (int64, error)
+	GetURLPasswordHash(ctx context.Context, shortID string) (pgtype.Text, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, userID uuid.UUID) (User, error)
+	GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error)
+	LatestJobRuns(ctx context.Context) ([]JobRun, error)
+	ListAnalyticsLinks(ctx context.Context, arg ListAnalyticsLinksParams) ([]ListAnalyticsLinksRow, error)
 	ListClicks(ctx context.Context, shortID pgtype.Text) ([]Click, error)
+	ListConversionEvents(ctx context.Context, shortID string) ([]ListConversionEventsRow, error)
+	ListConversionRevenue(ctx context.Context, shortID string) ([]ListConversionRevenueRow, error)
+	ListDeadWebhookDeliveries(ctx context.Context, arg ListDeadWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListExistingShortIDs(ctx context.Context, shortIds []string) ([]string, error)
+	ListFolderURLs(ctx context.Context, arg ListFolderURLsParams) ([]Url, error)
+	ListJobRuns(ctx context.Context, arg ListJobRunsParams) ([]JobRun, error)
+	ListRedirectRules(ctx context.Context, shortID string) ([]RedirectRule, error)
+	ListTagNamesForURLs(ctx context.Context, shortIds []string) ([]ListTagNamesForURLsRow, error)
+	ListTagURLs(ctx context.Context, arg ListTagURLsParams) ([]Url, error)
+	ListURLCounters(ctx context.Context, shortIds []string) ([]ListURLCountersRow, error)
+	ListURLVariants(ctx context.Context, shortID string) ([]UrlVariant, error)
 	ListUserAPIKeys(ctx context.Context, userID uuid.UUID) ([]ApiKey, error)
+	ListUserFolders(ctx context.Context, userID uuid.UUID) ([]Folder, error)
+	ListUserTags(ctx context.Context, userID uuid.UUID) ([]Tag, error)
 	ListUserURLs(ctx context.Context, userID pgtype.UUID) ([]Url, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooks(ctx context.Context, userID uuid.UUID) ([]Webhook, error)
 	LogClick(ctx context.Context, arg LogClickParams) error
+	RecordClickID(ctx context.Context, arg RecordClickIDParams) error
+	RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) error
+	RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) (WebhookDelivery, error)
+	RollupDailyClicks(ctx context.Context, arg RollupDailyClicksParams) (int64, error)
+	RollupHourlyClicks(ctx context.Context, arg RollupHourlyClicksParams) (int64, error)
+	SetRollupWatermark(ctx context.Context, rolledUpTo pgtype.Timestamp) error
+	TryAdvisoryLock(ctx context.Context, lockKey int64) (bool, error)
+	UpdateFolder(ctx context.Context, arg UpdateFolderParams) (Folder, error)
+	UpdateTag(ctx context.Context, arg UpdateTagParams) (Tag, error)
+	// The favicon of the old page is dropped when the destination changes
 	UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, error)
+	// Fetched metadata only fills in what is empty, so titles and descriptions
+	// set by the user are kept
+	UpdateURLMetadata(ctx context.Context, arg UpdateURLMetadataParams) error
+	UpdateURLVariant(ctx context.Context, arg UpdateURLVariantParams) (UrlVariant, error)
+	UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error)
+	UpsertURLCounter(ctx context.Context, arg UpsertURLCounterParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -13,34 +13,110 @@ VALUES ($1, $2, $3)
 RETURNING *;
 
 -- name: CreateURL :one
-INSERT INTO urls (short_id, long_url, user_id, created_at, expires_at, click_limit)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO urls (short_id, long_url, user_id, created_at, expires_at, click_limit, folder_id, title, description, notes, redirect_type, password_hash, query_mode, forward_path, ios_app_url, android_app_url, activates_at, expired_redirect_url, click_id_mode)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 RETURNING *;
 
 -- name: GetURL :one
 SELECT * FROM urls WHERE short_id = $1;
 
+-- name: GetURLPasswordHash :one
+SELECT password_hash FROM urls WHERE short_id = $1;
+
 -- name: LogClick :exec
-INSERT INTO clicks (short_id, ip_address, user_agent, clicked_at)
-VALUES ($1, $2, $3, $4);
+WITH logged AS (
+    INSERT INTO clicks (short_id, ip_address, user_agent, clicked_at, variant_id, source, country, location, device, referrer, visitor_hash, click_id)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    RETURNING short_id, clicked_at
+)
+UPDATE urls SET total_clicks = urls.total_clicks + 1,
+    last_clicked_at = GREATEST(urls.last_clicked_at, logged.clicked_at)
+FROM logged
+WHERE urls.short_id = logged.short_id;
 
 -- name: ListClicks :many
 SELECT * FROM clicks WHERE short_id = $1;
 
+-- name: GetURLClickTotal :one
+SELECT total_clicks FROM urls WHERE short_id = $1;
+
+-- name: CountClicksByVariant :many
+SELECT COALESCE(url_variants.name, '')::TEXT AS variant, COUNT(clicks.id) AS clicks
+FROM clicks
+LEFT JOIN url_variants ON url_variants.variant_id = clicks.variant_id
+WHERE clicks.short_id = $1
+GROUP BY url_variants.name;
+
+-- name: CountUniqueVisitors :many
+-- Visitor hashes rotate daily, so distinct hashes are daily unique visitors
+SELECT (CASE @group_by::TEXT
+    WHEN 'country' THEN COALESCE(c.country, '')
+    WHEN 'device' THEN COALESCE(c.device, '')
+    WHEN 'referrer' THEN COALESCE(c.referrer, '')
+    WHEN 'source' THEN COALESCE(c.source, 'direct')
+    WHEN 'variant' THEN COALESCE(v.name, '')
+    WHEN 'hour' THEN to_char(date_trunc('hour', c.clicked_at), 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    WHEN 'day' THEN to_char(date_trunc('day', c.clicked_at), 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    ELSE COALESCE(c.location, '')
+END)::TEXT AS dimension, COUNT(DISTINCT c.visitor_hash) AS visitors
+FROM clicks c
+LEFT JOIN url_variants v ON v.variant_id = c.variant_id
+WHERE c.short_id = @short_id AND c.visitor_hash IS NOT NULL
+GROUP BY 1;
+
+-- name: CountClicksBySource :many
+SELECT COALESCE(source, 'direct')::TEXT AS source, COUNT(id) AS clicks
+FROM clicks
+WHERE short_id = $1
+GROUP BY source;
+
 -- name: ListUserURLs :many
 SELECT * FROM urls WHERE user_id = $1 ORDER BY created_at DESC;
 
--- name: DeleteURL :exec
+-- name: ListFolderURLs :many
+SELECT * FROM urls WHERE user_id = $1 AND folder_id = $2 ORDER BY created_at DESC;
+
+-- name: ListTagURLs :many
+SELECT urls.* FROM urls
+JOIN url_tags ON url_tags.short_id = urls.short_id
+WHERE urls.user_id = $1 AND url_tags.tag_id = $2
+ORDER BY urls.created_at DESC;
+
+-- name: DeleteURL :execrows
 DELETE FROM urls WHERE short_id = $1 AND user_id = $2;
 
 -- name: UpdateURL :one
+-- The favicon of the old page is dropped when the destination changes
 UPDATE urls
 SET long_url = COALESCE($2, long_url),
+    favicon_url = CASE WHEN COALESCE($2, long_url) <> long_url THEN NULL ELSE favicon_url END,
     expires_at = COALESCE($3, expires_at),
-    click_limit = COALESCE($4, click_limit)
+    click_limit = COALESCE($4, click_limit),
+    folder_id = $6,
+    title = $7,
+    description = $8,
+    notes = $9,
+    redirect_type = $10,
+    password_hash = $11,
+    query_mode = $12,
+    forward_path = $13,
+    ios_app_url = $14,
+    android_app_url = $15,
+    activates_at = $16,
+    expired_redirect_url = $17,
+    click_id_mode = $18
 WHERE short_id = $1 AND user_id = $5
 RETURNING *;
 
+-- name: UpdateURLMetadata :exec
+-- Fetched metadata only fills in what is empty, so titles and descriptions
+-- set by the user are kept
+UPDATE urls
+SET title = COALESCE(title, $2),
+    description = COALESCE(description, $3),
+    favicon_url = $4
+WHERE short_id = $1;
+
 -- name: GetUserByID :one
 SELECT * FROM users WHERE user_id = $1;
 
@@ -50,14 +126,467 @@ SELECT * FROM users WHERE email = $1;
 -- name: ListUserAPIKeys :many
 SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC;
 
--- name: DeleteAPIKey :exec
+-- name: DeleteAPIKey :execrows
 DELETE FROM api_keys WHERE key = $1 AND user_id = $2;
 
 -- name: GetTotalURLs :one
 SELECT COUNT(*) as total FROM urls;
 
 -- name: GetTotalClicks :one
-SELECT COUNT(*) as total FROM clicks;
+-- Summed from the running totals, which click retention keeps
+SELECT COALESCE(SUM(total_clicks), 0)::BIGINT as total FROM urls;
 
 -- name: GetTotalUsers :one
 SELECT COUNT(*) as total FROM users;
+
+-- name: CreateFolder :one
+INSERT INTO folders (folder_id, user_id, name, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetFolder :one
+SELECT * FROM folders WHERE folder_id = $1 AND user_id = $2;
+
+-- name: ListUserFolders :many
+SELECT * FROM folders WHERE user_id = $1 ORDER BY name;
+
+-- name: UpdateFolder :one
+UPDATE folders SET name = $3
+WHERE folder_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders WHERE folder_id = $1 AND user_id = $2;
+
+-- name: CreateTag :one
+INSERT INTO tags (tag_id, user_id, name, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetOrCreateTag :one
+INSERT INTO tags (tag_id, user_id, name, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: GetTag :one
+SELECT * FROM tags WHERE tag_id = $1 AND user_id = $2;
+
+-- name: GetTagByName :one
+SELECT * FROM tags WHERE user_id = $1 AND name = $2;
+
+-- name: ListUserTags :many
+SELECT * FROM tags WHERE user_id = $1 ORDER BY name;
+
+-- name: UpdateTag :one
+UPDATE tags SET name = $3
+WHERE tag_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteTag :execrows
+DELETE FROM tags WHERE tag_id = $1 AND user_id = $2;
+
+-- name: AddURLTag :exec
+INSERT INTO url_tags (short_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: ClearURLTags :exec
+DELETE FROM url_tags WHERE short_id = $1;
+
+-- name: ListTagNamesForURLs :many
+SELECT url_tags.short_id, tags.name FROM tags
+JOIN url_tags ON url_tags.tag_id = tags.tag_id
+WHERE url_tags.short_id = ANY(@short_ids::text[])
+ORDER BY url_tags.short_id, tags.name;
+
+-- name: GetTagAnalytics :many
+-- Click counts come from the running totals, which click retention keeps.
+SELECT tags.tag_id, tags.name,
+       COUNT(urls.short_id) AS url_count,
+       COALESCE(SUM(urls.total_clicks), 0)::BIGINT AS click_count
+FROM tags
+LEFT JOIN url_tags ON url_tags.tag_id = tags.tag_id
+LEFT JOIN urls ON urls.short_id = url_tags.short_id
+WHERE tags.user_id = $1
+GROUP BY tags.tag_id, tags.name
+ORDER BY click_count DESC, tags.name;
+
+-- name: GetTagURLClickCounts :many
+SELECT url_tags.short_id, urls.total_clicks AS click_count
+FROM url_tags
+JOIN urls ON urls.short_id = url_tags.short_id
+WHERE url_tags.tag_id = $1
+ORDER BY click_count DESC;
+
+-- name: CreateRedirectRule :one
+INSERT INTO redirect_rules (rule_id, short_id, position, match_type, match_value, destination, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: ListRedirectRules :many
+SELECT * FROM redirect_rules WHERE short_id = $1 ORDER BY position, created_at;
+
+-- name: GetNextRulePosition :one
+SELECT (COALESCE(MAX(position), 0) + 1)::INTEGER AS position FROM redirect_rules WHERE short_id = $1;
+
+-- name: DeleteRedirectRule :execrows
+DELETE FROM redirect_rules WHERE rule_id = $1 AND short_id = $2;
+
+-- name: DeleteRedirectRules :exec
+DELETE FROM redirect_rules WHERE short_id = $1;
+
+-- name: CreateURLVariant :one
+INSERT INTO url_variants (variant_id, short_id, name, destination, weight, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListURLVariants :many
+SELECT * FROM url_variants WHERE short_id = $1 ORDER BY created_at, name;
+
+-- name: UpdateURLVariant :one
+UPDATE url_variants SET destination = $2, weight = $3
+WHERE variant_id = $1
+RETURNING *;
+
+-- name: DeleteURLVariant :exec
+DELETE FROM url_variants WHERE variant_id = $1;
+
+-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock(@lock_key::BIGINT)::BOOLEAN AS acquired;
+
+-- name: CreateJobRun :one
+INSERT INTO job_runs (run_id, job_name, instance, started_at, status)
+VALUES ($1, $2, $3, $4, 'running')
+RETURNING *;
+
+-- name: FinishJobRun :exec
+UPDATE job_runs
+SET finished_at = $2, status = $3, affected = $4, error = $5
+WHERE run_id = $1;
+
+-- name: ListJobRuns :many
+SELECT * FROM job_runs
+WHERE (sqlc.narg(job_name)::TEXT IS NULL OR job_name = sqlc.narg(job_name))
+ORDER BY started_at DESC
+LIMIT $1;
+
+-- name: LatestJobRuns :many
+SELECT DISTINCT ON (job_name) *
+FROM job_runs
+ORDER BY job_name, started_at DESC;
+
+-- name: DeleteJobRunsBefore :execrows
+DELETE FROM job_runs WHERE started_at < $1;
+
+-- name: DeleteExpiredURLs :many
+DELETE FROM urls
+WHERE (expires_at IS NOT NULL AND expires_at < @before)
+   OR (click_limit IS NOT NULL AND total_clicks >= click_limit AND last_clicked_at < @before)
+RETURNING short_id;
+
+-- name: DeleteClicksBefore :execrows
+-- Clicks are only deleted once they are rolled up, so a rollup that fails or
+-- lags behind never loses history.
+DELETE FROM clicks
+WHERE clicked_at < @before
+  AND clicked_at < (
+    SELECT COALESCE(MAX(rolled_up_to), '1970-01-01'::TIMESTAMP) FROM rollup_state WHERE name = 'clicks'
+  )
+  AND short_id IN (
+    SELECT urls.short_id FROM urls
+    LEFT JOIN users ON users.user_id = urls.user_id
+    WHERE COALESCE(users.plan, 'anonymous') = @plan::TEXT
+  );
+
+-- name: ListExistingShortIDs :many
+SELECT short_id FROM urls WHERE short_id = ANY(@short_ids::TEXT[]);
+
+-- name: GetRollupWatermark :one
+SELECT COALESCE(MAX(rolled_up_to), '1970-01-01'::TIMESTAMP)::TIMESTAMP AS rolled_up_to
+FROM rollup_state WHERE name = 'clicks';
+
+-- name: SetRollupWatermark :exec
+INSERT INTO rollup_state (name, rolled_up_to) VALUES ('clicks', $1)
+ON CONFLICT (name) DO UPDATE SET rolled_up_to = EXCLUDED.rolled_up_to;
+
+-- name: RollupHourlyClicks :execrows
+INSERT INTO click_rollups_hourly (short_id, bucket, country, location, device, referrer, clicks)
+SELECT short_id, date_trunc('hour', clicked_at), COALESCE(country, ''), COALESCE(location, ''),
+       COALESCE(device, ''), COALESCE(referrer, ''), COUNT(*)
+FROM clicks
+WHERE clicked_at >= @from_time AND clicked_at < @to_time AND short_id IS NOT NULL
+GROUP BY 1, 2, 3, 4, 5, 6
+ON CONFLICT (short_id, bucket, country, location, device, referrer)
+DO UPDATE SET clicks = EXCLUDED.clicks;
+
+-- name: RollupDailyClicks :execrows
+INSERT INTO click_rollups_daily (short_id, bucket, country, location, device, referrer, clicks)
+SELECT h.short_id, date_trunc('day', h.bucket), h.country, h.location, h.device, h.referrer, SUM(h.clicks)::BIGINT
+FROM click_rollups_hourly AS h
+WHERE h.bucket >= date_trunc('day', @from_time::TIMESTAMP) AND h.bucket < @to_time
+GROUP BY 1, 2, 3, 4, 5, 6
+ON CONFLICT (short_id, bucket, country, location, device, referrer)
+DO UPDATE SET clicks = EXCLUDED.clicks;
+
+-- name: DeleteHourlyRollupsBefore :execrows
+DELETE FROM click_rollups_hourly WHERE bucket < $1;
+
+-- name: ClickRollupTotals :many
+-- Daily rollups cover whole days before the watermark, hourly rollups the
+-- rest of the day, and raw clicks everything after it.
+SELECT country, location, device, referrer, SUM(clicks)::BIGINT AS clicks
+FROM (
+    SELECT d.country, d.location, d.device, d.referrer, d.clicks FROM click_rollups_daily AS d
+    WHERE d.short_id = @short_id AND d.bucket < date_trunc('day', @rolled_up_to::TIMESTAMP)
+    UNION ALL
+    SELECT h.country, h.location, h.device, h.referrer, h.clicks FROM click_rollups_hourly AS h
+    WHERE h.short_id = @short_id AND h.bucket >= date_trunc('day', @rolled_up_to::TIMESTAMP) AND h.bucket < @rolled_up_to
+    UNION ALL
+    SELECT COALESCE(country, '')::VARCHAR, COALESCE(location, '')::VARCHAR, COALESCE(device, '')::VARCHAR,
+           COALESCE(referrer, '')::VARCHAR, 1::BIGINT
+    FROM clicks AS c
+    WHERE c.short_id = @short_id AND c.clicked_at >= @rolled_up_to
+) AS combined
+GROUP BY country, location, device, referrer;
+
+-- name: ClickSeriesHourly :many
+SELECT bucket::TIMESTAMP AS bucket, SUM(clicks)::BIGINT AS clicks
+FROM (
+    SELECT h.bucket, h.clicks FROM click_rollups_hourly AS h
+    WHERE h.short_id = @short_id AND h.bucket < @rolled_up_to
+    UNION ALL
+    SELECT date_trunc('hour', clicked_at), 1::BIGINT FROM clicks AS c
+    WHERE c.short_id = @short_id AND c.clicked_at >= @rolled_up_to
+) AS combined
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: ClickSeriesDaily :many
+SELECT bucket::TIMESTAMP AS bucket, SUM(clicks)::BIGINT AS clicks
+FROM (
+    SELECT d.bucket, d.clicks FROM click_rollups_daily AS d
+    WHERE d.short_id = @short_id AND d.bucket < date_trunc('day', @rolled_up_to::TIMESTAMP)
+    UNION ALL
+    SELECT date_trunc('day', h.bucket), h.clicks FROM click_rollups_hourly AS h
+    WHERE h.short_id = @short_id AND h.bucket >= date_trunc('day', @rolled_up_to::TIMESTAMP) AND h.bucket < @rolled_up_to
+    UNION ALL
+    SELECT date_trunc('day', clicked_at), 1::BIGINT FROM clicks AS c
+    WHERE c.short_id = @short_id AND c.clicked_at >= @rolled_up_to
+) AS combined
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: ListURLCounters :many
+SELECT short_id, click_count, unique_visitors FROM url_counters
+WHERE short_id = ANY(@short_ids::text[]);
+
+-- name: GetClickCountSeed :one
+SELECT GREATEST(
+    COALESCE((SELECT uc.click_count FROM url_counters uc WHERE uc.short_id = @short_id::text), 0),
+    COALESCE((SELECT u.total_clicks FROM urls u WHERE u.short_id = @short_id::text), 0)
+)::BIGINT AS click_count;
+
+-- name: UpsertURLCounter :exec
+INSERT INTO url_counters (short_id, click_count, unique_visitors, updated_at)
+SELECT u.short_id, @click_count::BIGINT, @unique_visitors::BIGINT, NOW()
+FROM urls u WHERE u.short_id = @short_id::text
+ON CONFLICT (short_id) DO UPDATE SET
+    click_count = GREATEST(url_counters.click_count, EXCLUDED.click_count),
+    unique_visitors = GREATEST(url_counters.unique_visitors, EXCLUDED.unique_visitors),
+    updated_at = EXCLUDED.updated_at;
+
+-- name: CreateWebhook :one
+INSERT INTO webhooks (webhook_id, user_id, url, secret, events, active, created_at)
+VALUES ($1, $2, $3, $4, $5, TRUE, $6)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks WHERE webhook_id = $1 AND user_id = $2;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks WHERE user_id = $1 ORDER BY created_at;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks SET url = $3, events = $4, active = $5
+WHERE webhook_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE webhook_id = $1 AND user_id = $2;
+
+-- name: EnqueueUserWebhookEvent :execrows
+INSERT INTO webhook_deliveries (delivery_id, webhook_id, event, payload, status, attempts, next_attempt_at, created_at)
+SELECT gen_random_uuid(), w.webhook_id, @event::TEXT, @payload::TEXT, 'pending', 0, NOW(), NOW()
+FROM webhooks w
+WHERE w.user_id = @user_id AND w.active AND @event::TEXT = ANY(w.events);
+
+-- name: EnqueueLinkWebhookEvent :execrows
+INSERT INTO webhook_deliveries (delivery_id, webhook_id, event, payload, status, attempts, next_attempt_at, created_at)
+SELECT gen_random_uuid(), w.webhook_id, @event::TEXT, @payload::TEXT, 'pending', 0, NOW(), NOW()
+FROM webhooks w
+JOIN urls u ON u.user_id = w.user_id
+WHERE u.short_id = @short_id::TEXT AND w.active AND @event::TEXT = ANY(w.events);
+
+-- name: ClaimWebhookDeliveries :many
+-- Claimed deliveries are leased until lease_until, so a dispatcher that dies
+-- mid-delivery hands them to the next one
+UPDATE webhook_deliveries d
+SET next_attempt_at = @lease_until, attempts = d.attempts + 1
+FROM webhooks w
+WHERE w.webhook_id = d.webhook_id
+  AND d.delivery_id IN (
+    SELECT q.delivery_id FROM webhook_deliveries q
+    WHERE q.status = 'pending' AND q.next_attempt_at <= NOW()
+    ORDER BY q.next_attempt_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.delivery_id, d.webhook_id, d.event, d.payload, d.attempts, d.created_at, w.url, w.secret;
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = @status, next_attempt_at = @next_attempt_at, last_attempt_at = @attempted_at,
+    last_status_code = @last_status_code, last_error = @last_error, delivered_at = @delivered_at
+WHERE delivery_id = @delivery_id;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = @webhook_id
+  AND (sqlc.narg(status)::TEXT IS NULL OR status = sqlc.narg(status)::TEXT)
+ORDER BY created_at DESC
+LIMIT @max_results;
+
+-- name: ListDeadWebhookDeliveries :many
+SELECT d.* FROM webhook_deliveries d
+JOIN webhooks w ON w.webhook_id = d.webhook_id
+WHERE w.user_id = @user_id AND d.status = 'dead'
+ORDER BY d.created_at DESC
+LIMIT @max_results;
+
+-- name: RetryWebhookDelivery :one
+UPDATE webhook_deliveries d
+SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+FROM webhooks w
+WHERE w.webhook_id = d.webhook_id AND w.user_id = @user_id
+  AND d.delivery_id = @delivery_id AND d.webhook_id = @webhook_id
+RETURNING d.*;
+
+-- name: DeleteWebhookDeliveriesBefore :execrows
+DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1;
+
+-- name: ClaimExpiredLinks :many
+INSERT INTO expiry_notifications (short_id, notified_at)
+SELECT u.short_id, NOW() FROM urls u
+WHERE u.user_id IS NOT NULL
+  AND ((u.expires_at IS NOT NULL AND u.expires_at <= NOW())
+    OR (u.click_limit IS NOT NULL AND u.total_clicks >= u.click_limit))
+  AND NOT EXISTS (SELECT 1 FROM expiry_notifications e WHERE e.short_id = u.short_id)
+ON CONFLICT (short_id) DO NOTHING
+RETURNING short_id;
+
+-- name: ClearExpiryNotification :exec
+DELETE FROM expiry_notifications WHERE short_id = $1;
+
+-- name: ExportClicks :many
+SELECT c.id, c.short_id, c.clicked_at, c.ip_address, c.user_agent, c.country, c.location,
+       c.device, c.referrer, c.source, c.variant_id, c.visitor_hash, c.click_id
+FROM clicks c
+JOIN urls u ON u.short_id = c.short_id
+WHERE u.user_id = @user_id
+  AND (sqlc.narg(short_id)::text IS NULL OR c.short_id = sqlc.narg(short_id))
+  AND (sqlc.narg(from_time)::timestamp IS NULL OR c.clicked_at >= sqlc.narg(from_time))
+  AND (sqlc.narg(to_time)::timestamp IS NULL OR c.clicked_at < sqlc.narg(to_time))
+  AND c.id > @after_id
+ORDER BY c.id
+LIMIT @batch_size;
+
+-- name: ListAnalyticsLinks :many
+SELECT u.short_id, u.title, u.long_url FROM urls u
+WHERE u.user_id = @user_id
+  AND (sqlc.narg(tag_id)::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM url_tags t WHERE t.short_id = u.short_id AND t.tag_id = sqlc.narg(tag_id)))
+  AND (sqlc.narg(short_ids)::text[] IS NULL OR u.short_id = ANY(sqlc.narg(short_ids)::text[]));
+
+-- name: AggregateClickRollups :many
+-- Clicks of several links in [from_time, to_time), which must be whole days,
+-- counted per link and per country, device and referrer. Sources are split
+-- at the watermark like ClickRollupTotals.
+SELECT (CASE
+    WHEN GROUPING(combined.short_id) = 0 THEN 'link'
+    WHEN GROUPING(combined.country) = 0 THEN 'country'
+    WHEN GROUPING(combined.device) = 0 THEN 'device'
+    ELSE 'referrer'
+END)::TEXT AS dimension,
+COALESCE(combined.short_id, combined.country, combined.device, combined.referrer)::TEXT AS value,
+SUM(combined.clicks)::BIGINT AS clicks
+FROM (
+    SELECT d.short_id, d.country, d.device, d.referrer, d.clicks FROM click_rollups_daily AS d
+    WHERE d.short_id = ANY(@short_ids::text[]) AND d.bucket >= @from_time
+      AND d.bucket < LEAST(@to_time, date_trunc('day', @rolled_up_to::TIMESTAMP))
+    UNION ALL
+    SELECT h.short_id, h.country, h.device, h.referrer, h.clicks FROM click_rollups_hourly AS h
+    WHERE h.short_id = ANY(@short_ids::text[])
+      AND h.bucket >= GREATEST(@from_time, date_trunc('day', @rolled_up_to::TIMESTAMP))
+      AND h.bucket < LEAST(@to_time, @rolled_up_to)
+    UNION ALL
+    SELECT c.short_id, COALESCE(c.country, '')::VARCHAR, COALESCE(c.device, '')::VARCHAR,
+           COALESCE(c.referrer, '')::VARCHAR, 1::BIGINT
+    FROM clicks AS c
+    WHERE c.short_id = ANY(@short_ids::text[]) AND c.clicked_at >= GREATEST(@from_time, @rolled_up_to)
+      AND c.clicked_at < @to_time
+) AS combined
+GROUP BY GROUPING SETS ((combined.short_id), (combined.country), (combined.device), (combined.referrer));
+
+-- name: RecordClickID :exec
+INSERT INTO click_ids (click_id, short_id, clicked_at)
+VALUES (@click_id, @short_id, @clicked_at)
+ON CONFLICT (click_id) DO NOTHING;
+
+-- name: DeleteClickIDsBefore :execrows
+DELETE FROM click_ids WHERE clicked_at < @before;
+
+-- name: GetConversionClick :one
+SELECT k.short_id, k.clicked_at, u.user_id
+FROM click_ids k
+JOIN urls u ON u.short_id = k.short_id
+WHERE k.click_id = @click_id;
+
+-- name: CreateConversion :one
+-- A click converts at most once per event; repeats return no rows
+INSERT INTO conversions (conversion_id, click_id, short_id, event, value, currency, source, created_at)
+VALUES (@conversion_id, @click_id, @short_id, @event, @value, @currency, @source, @created_at)
+ON CONFLICT (click_id, event) DO NOTHING
+RETURNING *;
+
+-- name: GetConversionByEvent :one
+SELECT * FROM conversions WHERE click_id = @click_id AND event = @event;
+
+-- name: GetConversionTotals :one
+SELECT COUNT(*) AS conversions, COUNT(DISTINCT click_id) AS converted_clicks
+FROM conversions WHERE short_id = @short_id;
+
+-- name: ListConversionEvents :many
+SELECT event, COUNT(*) AS conversions FROM conversions
+WHERE short_id = @short_id
+GROUP BY event
+ORDER BY conversions DESC, event;
+
+-- name: ListConversionRevenue :many
+SELECT currency::TEXT AS currency, SUM(value)::FLOAT8 AS revenue FROM conversions
+WHERE short_id = @short_id AND value IS NOT NULL
+GROUP BY currency
+ORDER BY currency;
+
+-- name: AggregateConversions :many
+SELECT cv.short_id, COUNT(*) AS conversions, COUNT(DISTINCT cv.click_id) AS converted_clicks
+FROM conversions cv
+WHERE cv.short_id = ANY(@short_ids::text[]) AND cv.created_at >= @from_time AND cv.created_at < @to_time
+GROUP BY cv.short_id;
+
+-- name: AggregateConversionRevenue :many
+SELECT cv.short_id, cv.currency::TEXT AS currency, SUM(cv.value)::FLOAT8 AS revenue
+FROM conversions cv
+WHERE cv.short_id = ANY(@short_ids::text[]) AND cv.created_at >= @from_time AND cv.created_at < @to_time
+  AND cv.value IS NOT NULL
+GROUP BY cv.short_id, cv.currency;
@@ -12,236 +12,2237 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const createAPIKey = `-- name: CreateAPIKey :one
-INSERT INTO api_keys (key, user_id, created_at)
-VALUES ($1, $2, $3)
-RETURNING key, user_id, created_at
+const addURLTag = `-- name: AddURLTag :exec
+INSERT INTO url_tags (short_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
 `
 
-type CreateAPIKeyParams struct {
-	Key       uuid.UUID        `json:"key"`
-	UserID    uuid.UUID        `json:"user_id"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
+type AddURLTagParams struct {
+	ShortID string    `json:"short_id"`
+	TagID   uuid.UUID `json:"tag_id"`
 }
 
-func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
-	row := q.db.QueryRow(ctx, createAPIKey, arg.Key, arg.UserID, arg.CreatedAt)
-	var i ApiKey
-	err := row.Scan(&i.Key, &i.UserID, &i.CreatedAt)
-	return i, err
+func (q *Queries) AddURLTag(ctx context.Context, arg AddURLTagParams) error {
+	_, err := q.db.Exec(ctx, addURLTag, arg.ShortID, arg.TagID)
+	return err
 }
 
-const createURL = `-- name: CreateURL :one
-INSERT INTO urls (short_id, long_url, user_id, created_at, expires_at, click_limit)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING short_id, long_url, user_id, created_at, expires_at, click_limit
+const aggregateClickRollups = `-- name: AggregateClickRollups :many
+SELECT (CASE
+    WHEN GROUPING(combined.short_id) = 0 THEN 'link'
+    WHEN GROUPING(combined.country) = 0 THEN 'country'
+    WHEN GROUPING(combined.device) = 0 THEN 'device'
+    ELSE 'referrer'
+END)::TEXT AS dimension,
+COALESCE(combined.short_id, combined.country, combined.device, combined.referrer)::TEXT AS value,
+SUM(combined.clicks)::BIGINT AS clicks
+FROM (
+    SELECT d.short_id, d.country, d.device, d.referrer, d.clicks FROM click_rollups_daily AS d
+    WHERE d.short_id = ANY($1::text[]) AND d.bucket >= $2
+      AND d.bucket < LEAST($3, date_trunc('day', $4::TIMESTAMP))
+    UNION ALL
+    SELECT h.short_id, h.country, h.device, h.referrer, h.clicks FROM click_rollups_hourly AS h
+    WHERE h.short_id = ANY($1::text[])
+      AND h.bucket >= GREATEST($2, date_trunc('day', $4::TIMESTAMP))
+      AND h.bucket < LEAST($3, $4)
+    UNION ALL
+    SELECT c.short_id, COALESCE(c.country, '')::VARCHAR, COALESCE(c.device, '')::VARCHAR,
+           COALESCE(c.referrer, '')::VARCHAR, 1::BIGINT
+    FROM clicks AS c
+    WHERE c.short_id = ANY($1::text[]) AND c.clicked_at >= GREATEST($2, $4)
+      AND c.clicked_at < $3
+) AS combined
+GROUP BY GROUPING SETS ((combined.short_id), (combined.country), (combined.device), (combined.referrer))
 `
 
-type CreateURLParams struct {
-	ShortID    string           `json:"short_id"`
-	LongUrl    string           `json:"long_url"`
-	UserID     pgtype.UUID      `json:"user_id"`
-	CreatedAt  pgtype.Timestamp `json:"created_at"`
-	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
-	ClickLimit pgtype.Int4      `json:"click_limit"`
+type AggregateClickRollupsParams struct {
+	ShortIds   []string         `json:"short_ids"`
+	FromTime   pgtype.Timestamp `json:"from_time"`
+	ToTime     pgtype.Timestamp `json:"to_time"`
+	RolledUpTo pgtype.Timestamp `json:"rolled_up_to"`
 }
 
-func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, error) {
-	row := q.db.QueryRow(ctx, createURL,
-		arg.ShortID,
-		arg.LongUrl,
-		arg.UserID,
-		arg.CreatedAt,
-		arg.ExpiresAt,
-		arg.ClickLimit,
-	)
-	var i Url
-	err := row.Scan(
-		&i.ShortID,
-		&i.LongUrl,
-		&i.UserID,
-		&i.CreatedAt,
-		&i.ExpiresAt,
-		&i.ClickLimit,
+type AggregateClickRollupsRow struct {
+	Dimension string `json:"dimension"`
+	Value     string `json:"value"`
+	Clicks    int64  `json:"clicks"`
+}
+
+// Clicks of several links in [from_time, to_time), which must be whole days,
+// counted per link and per country, device and referrer. Sources are split
+// at the watermark like ClickRollupTotals.
+func (q *Queries) AggregateClickRollups(ctx context.Context, arg AggregateClickRollupsParams) ([]AggregateClickRollupsRow, error) {
+	rows, err := q.db.Query(ctx, aggregateClickRollups,
+		arg.ShortIds,
+		arg.FromTime,
+		arg.ToTime,
+		arg.RolledUpTo,
 	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateClickRollupsRow
+	for rows.Next() {
+		var i AggregateClickRollupsRow
+		if err := rows.Scan(&i.Dimension, &i.Value, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const createUser = `-- name: CreateUser :one
-INSERT INTO users (user_id, username, email, created_at)
-VALUES ($1, $2, $3, $4)
-RETURNING user_id, username, email, created_at, updated_at
+const aggregateConversionRevenue = `-- name: AggregateConversionRevenue :many
+SELECT cv.short_id, cv.currency::TEXT AS currency, SUM(cv.value)::FLOAT8 AS revenue
+FROM conversions cv
+WHERE cv.short_id = ANY($1::text[]) AND cv.created_at >= $2 AND cv.created_at < $3
+  AND cv.value IS NOT NULL
+GROUP BY cv.short_id, cv.currency
 `
 
-type CreateUserParams struct {
-	UserID    uuid.UUID        `json:"user_id"`
-	Username  string           `json:"username"`
-	Email     string           `json:"email"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
+type AggregateConversionRevenueParams struct {
+	ShortIds []string         `json:"short_ids"`
+	FromTime pgtype.Timestamp `json:"from_time"`
+	ToTime   pgtype.Timestamp `json:"to_time"`
 }
 
-// queries.sql
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, createUser,
-		arg.UserID,
-		arg.Username,
-		arg.Email,
-		arg.CreatedAt,
-	)
-	var i User
-	err := row.Scan(
-		&i.UserID,
-		&i.Username,
-		&i.Email,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+type AggregateConversionRevenueRow struct {
+	ShortID  string  `json:"short_id"`
+	Currency string  `json:"currency"`
+	Revenue  float64 `json:"revenue"`
 }
 
-const deleteAPIKey = `-- name: DeleteAPIKey :exec
-DELETE FROM api_keys WHERE key = $1 AND user_id = $2
+func (q *Queries) AggregateConversionRevenue(ctx context.Context, arg AggregateConversionRevenueParams) ([]AggregateConversionRevenueRow, error) {
+	rows, err := q.db.Query(ctx, aggregateConversionRevenue, arg.ShortIds, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateConversionRevenueRow
+	for rows.Next() {
+		var i AggregateConversionRevenueRow
+		if err := rows.Scan(&i.ShortID, &i.Currency, &i.Revenue); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const aggregateConversions = `-- name: AggregateConversions :many
+SELECT cv.short_id, COUNT(*) AS conversions, COUNT(DISTINCT cv.click_id) AS converted_clicks
+FROM conversions cv
+WHERE cv.short_id = ANY($1::text[]) AND cv.created_at >= $2 AND cv.created_at < $3
+GROUP BY cv.short_id
 `
 
-type DeleteAPIKeyParams struct {
-	Key    uuid.UUID `json:"key"`
-	UserID uuid.UUID `json:"user_id"`
+type AggregateConversionsParams struct {
+	ShortIds []string         `json:"short_ids"`
+	FromTime pgtype.Timestamp `json:"from_time"`
+	ToTime   pgtype.Timestamp `json:"to_time"`
 }
 
-func (q *Queries) DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) error {
-	_, err := q.db.Exec(ctx, deleteAPIKey, arg.Key, arg.UserID)
-	return err
+type AggregateConversionsRow struct {
+	ShortID         string `json:"short_id"`
+	Conversions     int64  `json:"conversions"`
+	ConvertedClicks int64  `json:"converted_clicks"`
 }
 
-const deleteURL = `-- name: DeleteURL :exec
-DELETE FROM urls WHERE short_id = $1 AND user_id = $2
+func (q *Queries) AggregateConversions(ctx context.Context, arg AggregateConversionsParams) ([]AggregateConversionsRow, error) {
+	rows, err := q.db.Query(ctx, aggregateConversions, arg.ShortIds, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateConversionsRow
+	for rows.Next() {
+		var i AggregateConversionsRow
+		if err := rows.Scan(&i.ShortID, &i.Conversions, &i.ConvertedClicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimExpiredLinks = `-- name: ClaimExpiredLinks :many
+INSERT INTO expiry_notifications (short_id, notified_at)
+SELECT u.short_id, NOW() FROM urls u
+WHERE u.user_id IS NOT NULL
+  AND ((u.expires_at IS NOT NULL AND u.expires_at <= NOW())
+    OR (u.click_limit IS NOT NULL AND u.total_clicks >= u.click_limit))
+  AND NOT EXISTS (SELECT 1 FROM expiry_notifications e WHERE e.short_id = u.short_id)
+ON CONFLICT (short_id) DO NOTHING
+RETURNING short_id
 `
 
-type DeleteURLParams struct {
-	ShortID string      `json:"short_id"`
-	UserID  pgtype.UUID `json:"user_id"`
+func (q *Queries) ClaimExpiredLinks(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, claimExpiredLinks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var short_id string
+		if err := rows.Scan(&short_id); err != nil {
+			return nil, err
+		}
+		items = append(items, short_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) DeleteURL(ctx context.Context, arg DeleteURLParams) error {
-	_, err := q.db.Exec(ctx, deleteURL, arg.ShortID, arg.UserID)
+const claimWebhookDeliveries = `-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET next_attempt_at = $1, attempts = d.attempts + 1
+FROM webhooks w
+WHERE w.webhook_id = d.webhook_id
+  AND d.delivery_id IN (
+    SELECT q.delivery_id FROM webhook_deliveries q
+    WHERE q.status = 'pending' AND q.next_attempt_at <= NOW()
+    ORDER BY q.next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.delivery_id, d.webhook_id, d.event, d.payload, d.attempts, d.created_at, w.url, w.secret
+`
+
+type ClaimWebhookDeliveriesParams struct {
+	LeaseUntil pgtype.Timestamp `json:"lease_until"`
+	BatchSize  int32            `json:"batch_size"`
+}
+
+type ClaimWebhookDeliveriesRow struct {
+	DeliveryID uuid.UUID        `json:"delivery_id"`
+	WebhookID  uuid.UUID        `json:"webhook_id"`
+	Event      string           `json:"event"`
+	Payload    string           `json:"payload"`
+	Attempts   int32            `json:"attempts"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	Url        string           `json:"url"`
+	Secret     string           `json:"secret"`
+}
+
+// Claimed deliveries are leased until lease_until, so a dispatcher that dies
+// mid-delivery hands them to the next one
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimWebhookDeliveries, arg.LeaseUntil, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimWebhookDeliveriesRow
+	for rows.Next() {
+		var i ClaimWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.DeliveryID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Attempts,
+			&i.CreatedAt,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clearExpiryNotification = `-- name: ClearExpiryNotification :exec
+DELETE FROM expiry_notifications WHERE short_id = $1
+`
+
+func (q *Queries) ClearExpiryNotification(ctx context.Context, shortID string) error {
+	_, err := q.db.Exec(ctx, clearExpiryNotification, shortID)
 	return err
 }
 
-const getAPIKey = `-- name: GetAPIKey :one
-SELECT key, user_id, created_at FROM api_keys WHERE key = $1
+const clearURLTags = `-- name: ClearURLTags :exec
+DELETE FROM url_tags WHERE short_id = $1
 `
 
-func (q *Queries) GetAPIKey(ctx context.Context, key uuid.UUID) (ApiKey, error) {
-	row := q.db.QueryRow(ctx, getAPIKey, key)
-	var i ApiKey
-	err := row.Scan(&i.Key, &i.UserID, &i.CreatedAt)
-	return i, err
+func (q *Queries) ClearURLTags(ctx context.Context, shortID string) error {
+	_, err := q.db.Exec(ctx, clearURLTags, shortID)
+	return err
 }
 
-const getTotalClicks = `-- name: GetTotalClicks :one
-SELECT COUNT(*) as total FROM clicks
+const clickRollupTotals = `-- name: ClickRollupTotals :many
+SELECT country, location, device, referrer, SUM(clicks)::BIGINT AS clicks
+FROM (
+    SELECT d.country, d.location, d.device, d.referrer, d.clicks FROM click_rollups_daily AS d
+    WHERE d.short_id = $1 AND d.bucket < date_trunc('day', $2::TIMESTAMP)
+    UNION ALL
+    SELECT h.country, h.location, h.device, h.referrer, h.clicks FROM click_rollups_hourly AS h
+    WHERE h.short_id = $1 AND h.bucket >= date_trunc('day', $2::TIMESTAMP) AND h.bucket < $2
+    UNION ALL
+    SELECT COALESCE(country, '')::VARCHAR, COALESCE(location, '')::VARCHAR, COALESCE(device, '')::VARCHAR,
+           COALESCE(referrer, '')::VARCHAR, 1::BIGINT
+    FROM clicks AS c
+    WHERE c.short_id = $1 AND c.clicked_at >= $2
+) AS combined
+GROUP BY country, location, device, referrer
 `
 
-func (q *Queries) GetTotalClicks(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, getTotalClicks)
-	var total int64
-	err := row.Scan(&total)
-	return total, err
+type ClickRollupTotalsParams struct {
+	ShortID    string           `json:"short_id"`
+	RolledUpTo pgtype.Timestamp `json:"rolled_up_to"`
 }
 
-const getTotalURLs = `-- name: GetTotalURLs :one
-SELECT COUNT(*) as total FROM urls
+type ClickRollupTotalsRow struct {
+	Country  string `json:"country"`
+	Location string `json:"location"`
+	Device   string `json:"device"`
+	Referrer string `json:"referrer"`
+	Clicks   int64  `json:"clicks"`
+}
+
+// Daily rollups cover whole days before the watermark, hourly rollups the
+// rest of the day, and raw clicks everything after it.
+func (q *Queries) ClickRollupTotals(ctx context.Context, arg ClickRollupTotalsParams) ([]ClickRollupTotalsRow, error) {
+	rows, err := q.db.Query(ctx, clickRollupTotals, arg.ShortID, arg.RolledUpTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClickRollupTotalsRow
+	for rows.Next() {
+		var i ClickRollupTotalsRow
+		if err := rows.Scan(
+			&i.Country,
+			&i.Location,
+			&i.Device,
+			&i.Referrer,
+			&i.Clicks,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clickSeriesDaily = `-- name: ClickSeriesDaily :many
+SELECT bucket::TIMESTAMP AS bucket, SUM(clicks)::BIGINT AS clicks
+FROM (
+    SELECT d.bucket, d.clicks FROM click_rollups_daily AS d
+    WHERE d.short_id = $1 AND d.bucket < date_trunc('day', $2::TIMESTAMP)
+    UNION ALL
+    SELECT date_trunc('day', h.bucket), h.clicks FROM click_rollups_hourly AS h
+    WHERE h.short_id = $1 AND h.bucket >= date_trunc('day', $2::TIMESTAMP) AND h.bucket < $2
+    UNION ALL
+    SELECT date_trunc('day', clicked_at), 1::BIGINT FROM clicks AS c
+    WHERE c.short_id = $1 AND c.clicked_at >= $2
+) AS combined
+GROUP BY bucket
+ORDER BY bucket
 `
 
-func (q *Queries) GetTotalURLs(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, getTotalURLs)
-	var total int64
-	err := row.Scan(&total)
-	return total, err
+type ClickSeriesDailyParams struct {
+	ShortID    string           `json:"short_id"`
+	RolledUpTo pgtype.Timestamp `json:"rolled_up_to"`
 }
 
-const getTotalUsers = `-- name: GetTotalUsers :one
-SELECT COUNT(*) as total FROM users
+type ClickSeriesDailyRow struct {
+	Bucket pgtype.Timestamp `json:"bucket"`
+	Clicks int64            `json:"clicks"`
+}
+
+func (q *Queries) ClickSeriesDaily(ctx context.Context, arg ClickSeriesDailyParams) ([]ClickSeriesDailyRow, error) {
+	rows, err := q.db.Query(ctx, clickSeriesDaily, arg.ShortID, arg.RolledUpTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClickSeriesDailyRow
+	for rows.Next() {
+		var i ClickSeriesDailyRow
+		if err := rows.Scan(&i.Bucket, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clickSeriesHourly = `-- name: ClickSeriesHourly :many
+SELECT bucket::TIMESTAMP AS bucket, SUM(clicks)::BIGINT AS clicks
+FROM (
+    SELECT h.bucket, h.clicks FROM click_rollups_hourly AS h
+    WHERE h.short_id = $1 AND h.bucket < $2
+    UNION ALL
+    SELECT date_trunc('hour', clicked_at), 1::BIGINT FROM clicks AS c
+    WHERE c.short_id = $1 AND c.clicked_at >= $2
+) AS combined
+GROUP BY bucket
+ORDER BY bucket
 `
 
-func (q *Queries) GetTotalUsers(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, getTotalUsers)
-	var total int64
-	err := row.Scan(&total)
-	return total, err
+type ClickSeriesHourlyParams struct {
+	ShortID    string           `json:"short_id"`
+	RolledUpTo pgtype.Timestamp `json:"rolled_up_to"`
 }
 
-const getURL = `-- name: GetURL :one
-SELECT short_id, long_url, user_id, created_at, expires_at, click_limit FROM urls WHERE short_id = $1
+type ClickSeriesHourlyRow struct {
+	Bucket pgtype.Timestamp `json:"bucket"`
+	Clicks int64            `json:"clicks"`
+}
+
+func (q *Queries) ClickSeriesHourly(ctx context.Context, arg ClickSeriesHourlyParams) ([]ClickSeriesHourlyRow, error) {
+	rows, err := q.db.Query(ctx, clickSeriesHourly, arg.ShortID, arg.RolledUpTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClickSeriesHourlyRow
+	for rows.Next() {
+		var i ClickSeriesHourlyRow
+		if err := rows.Scan(&i.Bucket, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countClicksBySource = `-- name: CountClicksBySource :many
+SELECT COALESCE(source, 'direct')::TEXT AS source, COUNT(id) AS clicks
+FROM clicks
+WHERE short_id = $1
+GROUP BY source
 `
 
-func (q *Queries) GetURL(ctx context.Context, shortID string) (Url, error) {
-	row := q.db.QueryRow(ctx, getURL, shortID)
-	var i Url
-	err := row.Scan(
-		&i.ShortID,
-		&i.LongUrl,
-		&i.UserID,
-		&i.CreatedAt,
-		&i.ExpiresAt,
-		&i.ClickLimit,
-	)
-	return i, err
+type CountClicksBySourceRow struct {
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+func (q *Queries) CountClicksBySource(ctx context.Context, shortID pgtype.Text) ([]CountClicksBySourceRow, error) {
+	rows, err := q.db.Query(ctx, countClicksBySource, shortID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountClicksBySourceRow
+	for rows.Next() {
+		var i CountClicksBySourceRow
+		if err := rows.Scan(&i.Source, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countClicksByVariant = `-- name: CountClicksByVariant :many
+SELECT COALESCE(url_variants.name, '')::TEXT AS variant, COUNT(clicks.id) AS clicks
+FROM clicks
+LEFT JOIN url_variants ON url_variants.variant_id = clicks.variant_id
+WHERE clicks.short_id = $1
+GROUP BY url_variants.name
+`
+
+type CountClicksByVariantRow struct {
+	Variant string `json:"variant"`
+	Clicks  int64  `json:"clicks"`
+}
+
+func (q *Queries) CountClicksByVariant(ctx context.Context, shortID pgtype.Text) ([]CountClicksByVariantRow, error) {
+	rows, err := q.db.Query(ctx, countClicksByVariant, shortID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountClicksByVariantRow
+	for rows.Next() {
+		var i CountClicksByVariantRow
+		if err := rows.Scan(&i.Variant, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countUniqueVisitors = `-- name: CountUniqueVisitors :many
+SELECT (CASE $1::TEXT
+    WHEN 'country' THEN COALESCE(c.country, '')
+    WHEN 'device' THEN COALESCE(c.device, '')
+    WHEN 'referrer' THEN COALESCE(c.referrer, '')
+    WHEN 'source' THEN COALESCE(c.source, 'direct')
+    WHEN 'variant' THEN COALESCE(v.name, '')
+    WHEN 'hour' THEN to_char(date_trunc('hour', c.clicked_at), 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    WHEN 'day' THEN to_char(date_trunc('day', c.clicked_at), 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    ELSE COALESCE(c.location, '')
+END)::TEXT AS dimension, COUNT(DISTINCT c.visitor_hash) AS visitors
+FROM clicks c
+LEFT JOIN url_variants v ON v.variant_id = c.variant_id
+WHERE c.short_id = $2 AND c.visitor_hash IS NOT NULL
+GROUP BY 1
+`
+
+type CountUniqueVisitorsParams struct {
+	GroupBy string      `json:"group_by"`
+	ShortID pgtype.Text `json:"short_id"`
+}
+
+type CountUniqueVisitorsRow struct {
+	Dimension string `json:"dimension"`
+	Visitors  int64  `json:"visitors"`
+}
+
+// Visitor hashes rotate daily, so distinct hashes are daily unique visitors
+func (q *Queries) CountUniqueVisitors(ctx context.Context, arg CountUniqueVisitorsParams) ([]CountUniqueVisitorsRow, error) {
+	rows, err := q.db.Query(ctx, countUniqueVisitors, arg.GroupBy, arg.ShortID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountUniqueVisitorsRow
+	for rows.Next() {
+		var i CountUniqueVisitorsRow
+		if err := rows.Scan(&i.Dimension, &i.Visitors); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (key, user_id, created_at)
+VALUES ($1, $2, $3)
+RETURNING key, user_id, created_at
+`
+
+type CreateAPIKeyParams struct {
+	Key       uuid.UUID        `json:"key"`
+	UserID    uuid.UUID        `json:"user_id"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, createAPIKey, arg.Key, arg.UserID, arg.CreatedAt)
+	var i ApiKey
+	err := row.Scan(&i.Key, &i.UserID, &i.CreatedAt)
+	return i, err
+}
+
+const createConversion = `-- name: CreateConversion :one
+INSERT INTO conversions (conversion_id, click_id, short_id, event, value, currency, source, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (click_id, event) DO NOTHING
+RETURNING conversion_id, click_id, short_id, event, value, currency, source, created_at
+`
+
+type CreateConversionParams struct {
+	ConversionID uuid.UUID        `json:"conversion_id"`
+	ClickID      uuid.UUID        `json:"click_id"`
+	ShortID      string           `json:"short_id"`
+	Event        string           `json:"event"`
+	Value        pgtype.Numeric   `json:"value"`
+	Currency     pgtype.Text      `json:"currency"`
+	Source       string           `json:"source"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+}
+
+// A click converts at most once per event; repeats return no rows
+func (q *Queries) CreateConversion(ctx context.Context, arg CreateConversionParams) (Conversion, error) {
+	row := q.db.QueryRow(ctx, createConversion,
+		arg.ConversionID,
+		arg.ClickID,
+		arg.ShortID,
+		arg.Event,
+		arg.Value,
+		arg.Currency,
+		arg.Source,
+		arg.CreatedAt,
+	)
+	var i Conversion
+	err := row.Scan(
+		&i.ConversionID,
+		&i.ClickID,
+		&i.ShortID,
+		&i.Event,
+		&i.Value,
+		&i.Currency,
+		&i.Source,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createFolder = `-- name: CreateFolder :one
+INSERT INTO folders (folder_id, user_id, name, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING folder_id, user_id, name, created_at
+`
+
+type CreateFolderParams struct {
+	FolderID  uuid.UUID        `json:"folder_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Name      string           `json:"name"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error) {
+	row := q.db.QueryRow(ctx, createFolder,
+		arg.FolderID,
+		arg.UserID,
+		arg.Name,
+		arg.CreatedAt,
+	)
+	var i Folder
+	err := row.Scan(
+		&i.FolderID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createJobRun = `-- name: CreateJobRun :one
+INSERT INTO job_runs (run_id, job_name, instance, started_at, status)
+VALUES ($1, $2, $3, $4, 'running')
+RETURNING run_id, job_name, instance, started_at, finished_at, status, affected, error
+`
+
+type CreateJobRunParams struct {
+	RunID     uuid.UUID        `json:"run_id"`
+	JobName   string           `json:"job_name"`
+	Instance  string           `json:"instance"`
+	StartedAt pgtype.Timestamp `json:"started_at"`
+}
+
+func (q *Queries) CreateJobRun(ctx context.Context, arg CreateJobRunParams) (JobRun, error) {
+	row := q.db.QueryRow(ctx, createJobRun,
+		arg.RunID,
+		arg.JobName,
+		arg.Instance,
+		arg.StartedAt,
+	)
+	var i JobRun
+	err := row.Scan(
+		&i.RunID,
+		&i.JobName,
+		&i.Instance,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.Status,
+		&i.Affected,
+		&i.Error,
+	)
+	return i, err
+}
+
+const createRedirectRule = `-- name: CreateRedirectRule :one
+INSERT INTO redirect_rules (rule_id, short_id, position, match_type, match_value, destination, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING rule_id, short_id, position, match_type, match_value, destination, created_at
+`
+
+type CreateRedirectRuleParams struct {
+	RuleID      uuid.UUID        `json:"rule_id"`
+	ShortID     string           `json:"short_id"`
+	Position    int32            `json:"position"`
+	MatchType   string           `json:"match_type"`
+	MatchValue  string           `json:"match_value"`
+	Destination string           `json:"destination"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) CreateRedirectRule(ctx context.Context, arg CreateRedirectRuleParams) (RedirectRule, error) {
+	row := q.db.QueryRow(ctx, createRedirectRule,
+		arg.RuleID,
+		arg.ShortID,
+		arg.Position,
+		arg.MatchType,
+		arg.MatchValue,
+		arg.Destination,
+		arg.CreatedAt,
+	)
+	var i RedirectRule
+	err := row.Scan(
+		&i.RuleID,
+		&i.ShortID,
+		&i.Position,
+		&i.MatchType,
+		&i.MatchValue,
+		&i.Destination,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createTag = `-- name: CreateTag :one
+INSERT INTO tags (tag_id, user_id, name, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING tag_id, user_id, name, created_at
+`
+
+type CreateTagParams struct {
+	TagID     uuid.UUID        `json:"tag_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Name      string           `json:"name"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, createTag,
+		arg.TagID,
+		arg.UserID,
+		arg.Name,
+		arg.CreatedAt,
+	)
+	var i Tag
+	err := row.Scan(
+		&i.TagID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createURL = `-- name: CreateURL :one
+INSERT INTO urls (short_id, long_url, user_id, created_at, expires_at, click_limit, folder_id, title, description, notes, redirect_type, password_hash, query_mode, forward_path, ios_app_url, android_app_url, activates_at, expired_redirect_url, click_id_mode)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+RETURNING short_id, long_url, user_id, created_at, expires_at, click_limit, folder_id, title, description, notes, favicon_url, redirect_type, password_hash, query_mode, forward_path, ios_app_url, android_app_url, activates_at, expired_redirect_url, click_id_mode, total_clicks, last_clicked_at
+`
+
+type CreateURLParams struct {
+	ShortID            string           `json:"short_id"`
+	LongUrl            string           `json:"long_url"`
+	UserID             pgtype.UUID      `json:"user_id"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
+	ClickLimit         pgtype.Int4      `json:"click_limit"`
+	FolderID           pgtype.UUID      `json:"folder_id"`
+	Title              pgtype.Text      `json:"title"`
+	Description        pgtype.Text      `json:"description"`
+	Notes              pgtype.Text      `json:"notes"`
+	RedirectType       pgtype.Text      `json:"redirect_type"`
+	PasswordHash       pgtype.Text      `json:"password_hash"`
+	QueryMode          pgtype.Text      `json:"query_mode"`
+	ForwardPath        bool             `json:"forward_path"`
+	IosAppUrl          pgtype.Text      `json:"ios_app_url"`
+	AndroidAppUrl      pgtype.Text      `json:"android_app_url"`
+	ActivatesAt        pgtype.Timestamp `json:"activates_at"`
+	ExpiredRedirectUrl pgtype.Text      `json:"expired_redirect_url"`
+	ClickIDMode        pgtype.Text      `json:"click_id_mode"`
+}
+
+func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, error) {
+	row := q.db.QueryRow(ctx, createURL,
+		arg.ShortID,
+		arg.LongUrl,
+		arg.UserID,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+		arg.ClickLimit,
+		arg.FolderID,
+		arg.Title,
+		arg.Description,
+		arg.Notes,
+		arg.RedirectType,
+		arg.PasswordHash,
+		arg.QueryMode,
+		arg.ForwardPath,
+		arg.IosAppUrl,
+		arg.AndroidAppUrl,
+		arg.ActivatesAt,
+		arg.ExpiredRedirectUrl,
+		arg.ClickIDMode,
+	)
+	var i Url
+	err := row.Scan(
+		&i.ShortID,
+		&i.LongUrl,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.ClickLimit,
+		&i.FolderID,
+		&i.Title,
+		&i.Description,
+		&i.Notes,
+		&i.FaviconUrl,
+		&i.RedirectType,
+		&i.PasswordHash,
+		&i.QueryMode,
+		&i.ForwardPath,
+		&i.IosAppUrl,
+		&i.AndroidAppUrl,
+		&i.ActivatesAt,
+		&i.ExpiredRedirectUrl,
+		&i.ClickIDMode,
+		&i.TotalClicks,
+		&i.LastClickedAt,
+	)
+	return i, err
+}
+
+const createURLVariant = `-- name: CreateURLVariant :one
+INSERT INTO url_variants (variant_id, short_id, name, destination, weight, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING variant_id, short_id, name, destination, weight, created_at
+`
+
+type CreateURLVariantParams struct {
+	VariantID   uuid.UUID        `json:"variant_id"`
+	ShortID     string           `json:"short_id"`
+	Name        string           `json:"name"`
+	Destination string           `json:"destination"`
+	Weight      int32            `json:"weight"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) CreateURLVariant(ctx context.Context, arg CreateURLVariantParams) (UrlVariant, error) {
+	row := q.db.QueryRow(ctx, createURLVariant,
+		arg.VariantID,
+		arg.ShortID,
+		arg.Name,
+		arg.Destination,
+		arg.Weight,
+		arg.CreatedAt,
+	)
+	var i UrlVariant
+	err := row.Scan(
+		&i.VariantID,
+		&i.ShortID,
+		&i.Name,
+		&i.Destination,
+		&i.Weight,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (user_id, username, email, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING user_id, username, email, created_at, updated_at, plan, is_admin
+`
+
+type CreateUserParams struct {
+	UserID    uuid.UUID        `json:"user_id"`
+	Username  string           `json:"username"`
+	Email     string           `json:"email"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+// queries.sql
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, createUser,
+		arg.UserID,
+		arg.Username,
+		arg.Email,
+		arg.CreatedAt,
+	)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.Username,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Plan,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (webhook_id, user_id, url, secret, events, active, created_at)
+VALUES ($1, $2, $3, $4, $5, TRUE, $6)
+RETURNING webhook_id, user_id, url, secret, events, active, created_at
+`
+
+type CreateWebhookParams struct {
+	WebhookID uuid.UUID        `json:"webhook_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Url       string           `json:"url"`
+	Secret    string           `json:"secret"`
+	Events    []string         `json:"events"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, createWebhook,
+		arg.WebhookID,
+		arg.UserID,
+		arg.Url,
+		arg.Secret,
+		arg.Events,
+		arg.CreatedAt,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.WebhookID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteAPIKey = `-- name: DeleteAPIKey :execrows
+DELETE FROM api_keys WHERE key = $1 AND user_id = $2
+`
+
+type DeleteAPIKeyParams struct {
+	Key    uuid.UUID `json:"key"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAPIKey, arg.Key, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteClickIDsBefore = `-- name: DeleteClickIDsBefore :execrows
+DELETE FROM click_ids WHERE clicked_at < $1
+`
+
+func (q *Queries) DeleteClickIDsBefore(ctx context.Context, before pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteClickIDsBefore, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteClicksBefore = `-- name: DeleteClicksBefore :execrows
+DELETE FROM clicks
+WHERE clicked_at < $1
+  AND clicked_at < (
+    SELECT COALESCE(MAX(rolled_up_to), '1970-01-01'::TIMESTAMP) FROM rollup_state WHERE name = 'clicks'
+  )
+  AND short_id IN (
+    SELECT urls.short_id FROM urls
+    LEFT JOIN users ON users.user_id = urls.user_id
+    WHERE COALESCE(users.plan, 'anonymous') = $2::TEXT
+  )
+`
+
+type DeleteClicksBeforeParams struct {
+	Before pgtype.Timestamp `json:"before"`
+	Plan   string           `json:"plan"`
+}
+
+// Clicks are only deleted once they are rolled up, so a rollup that fails or
+// lags behind never loses history.
+func (q *Queries) DeleteClicksBefore(ctx context.Context, arg DeleteClicksBeforeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteClicksBefore, arg.Before, arg.Plan)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredURLs = `-- name: DeleteExpiredURLs :many
+DELETE FROM urls
+WHERE (expires_at IS NOT NULL AND expires_at < $1)
+   OR (click_limit IS NOT NULL AND total_clicks >= click_limit AND last_clicked_at < $1)
+RETURNING short_id
+`
+
+func (q *Queries) DeleteExpiredURLs(ctx context.Context, before pgtype.Timestamp) ([]string, error) {
+	rows, err := q.db.Query(ctx, deleteExpiredURLs, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var short_id string
+		if err := rows.Scan(&short_id); err != nil {
+			return nil, err
+		}
+		items = append(items, short_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteFolder = `-- name: DeleteFolder :execrows
+DELETE FROM folders WHERE folder_id = $1 AND user_id = $2
+`
+
+type DeleteFolderParams struct {
+	FolderID uuid.UUID `json:"folder_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteFolder, arg.FolderID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteHourlyRollupsBefore = `-- name: DeleteHourlyRollupsBefore :execrows
+DELETE FROM click_rollups_hourly WHERE bucket < $1
+`
+
+func (q *Queries) DeleteHourlyRollupsBefore(ctx context.Context, bucket pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteHourlyRollupsBefore, bucket)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteJobRunsBefore = `-- name: DeleteJobRunsBefore :execrows
+DELETE FROM job_runs WHERE started_at < $1
+`
+
+func (q *Queries) DeleteJobRunsBefore(ctx context.Context, startedAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteJobRunsBefore, startedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRedirectRule = `-- name: DeleteRedirectRule :execrows
+DELETE FROM redirect_rules WHERE rule_id = $1 AND short_id = $2
+`
+
+type DeleteRedirectRuleParams struct {
+	RuleID  uuid.UUID `json:"rule_id"`
+	ShortID string    `json:"short_id"`
+}
+
+func (q *Queries) DeleteRedirectRule(ctx context.Context, arg DeleteRedirectRuleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRedirectRule, arg.RuleID, arg.ShortID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRedirectRules = `-- name: DeleteRedirectRules :exec
+DELETE FROM redirect_rules WHERE short_id = $1
+`
+
+func (q *Queries) DeleteRedirectRules(ctx context.Context, shortID string) error {
+	_, err := q.db.Exec(ctx, deleteRedirectRules, shortID)
+	return err
+}
+
+const deleteTag = `-- name: DeleteTag :execrows
+DELETE FROM tags WHERE tag_id = $1 AND user_id = $2
+`
+
+type DeleteTagParams struct {
+	TagID  uuid.UUID `json:"tag_id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteTag(ctx context.Context, arg DeleteTagParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTag, arg.TagID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteURL = `-- name: DeleteURL :execrows
+DELETE FROM urls WHERE short_id = $1 AND user_id = $2
+`
+
+type DeleteURLParams struct {
+	ShortID string      `json:"short_id"`
+	UserID  pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteURL(ctx context.Context, arg DeleteURLParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteURL, arg.ShortID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteURLVariant = `-- name: DeleteURLVariant :exec
+DELETE FROM url_variants WHERE variant_id = $1
+`
+
+func (q *Queries) DeleteURLVariant(ctx context.Context, variantID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteURLVariant, variantID)
+	return err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE webhook_id = $1 AND user_id = $2
+`
+
+type DeleteWebhookParams struct {
+	WebhookID uuid.UUID `json:"webhook_id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhook, arg.WebhookID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWebhookDeliveriesBefore = `-- name: DeleteWebhookDeliveriesBefore :execrows
+DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1
+`
+
+func (q *Queries) DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookDeliveriesBefore, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const enqueueLinkWebhookEvent = `-- name: EnqueueLinkWebhookEvent :execrows
+INSERT INTO webhook_deliveries (delivery_id, webhook_id, event, payload, status, attempts, next_attempt_at, created_at)
+SELECT gen_random_uuid(), w.webhook_id, $1::TEXT, $2::TEXT, 'pending', 0, NOW(), NOW()
+FROM webhooks w
+JOIN urls u ON u.user_id = w.user_id
+WHERE u.short_id = $3::TEXT AND w.active AND $1::TEXT = ANY(w.events)
+`
+
+type EnqueueLinkWebhookEventParams struct {
+	Event   string `json:"event"`
+	Payload string `json:"payload"`
+	ShortID string `json:"short_id"`
+}
+
+func (q *Queries) EnqueueLinkWebhookEvent(ctx context.Context, arg EnqueueLinkWebhookEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, enqueueLinkWebhookEvent, arg.Event, arg.Payload, arg.ShortID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const enqueueUserWebhookEvent = `-- name: EnqueueUserWebhookEvent :execrows
+INSERT INTO webhook_deliveries (delivery_id, webhook_id, event, payload, status, attempts, next_attempt_at, created_at)
+SELECT gen_random_uuid(), w.webhook_id, $1::TEXT, $2::TEXT, 'pending', 0, NOW(), NOW()
+FROM webhooks w
+WHERE w.user_id = $3 AND w.active AND $1::TEXT = ANY(w.events)
+`
+
+type EnqueueUserWebhookEventParams struct {
+	Event   string    `json:"event"`
+	Payload string    `json:"payload"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) EnqueueUserWebhookEvent(ctx context.Context, arg EnqueueUserWebhookEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, enqueueUserWebhookEvent, arg.Event, arg.Payload, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const exportClicks = `-- name: ExportClicks :many
+SELECT c.id, c.short_id, c.clicked_at, c.ip_address, c.user_agent, c.country, c.location,
+       c.device, c.referrer, c.source, c.variant_id, c.visitor_hash, c.click_id
+FROM clicks c
+JOIN urls u ON u.short_id = c.short_id
+WHERE u.user_id = $1
+  AND ($2::text IS NULL OR c.short_id = $2)
+  AND ($3::timestamp IS NULL OR c.clicked_at >= $3)
+  AND ($4::timestamp IS NULL OR c.clicked_at < $4)
+  AND c.id > $5
+ORDER BY c.id
+LIMIT $6
+`
+
+type ExportClicksParams struct {
+	UserID    pgtype.UUID      `json:"user_id"`
+	ShortID   pgtype.Text      `json:"short_id"`
+	FromTime  pgtype.Timestamp `json:"from_time"`
+	ToTime    pgtype.Timestamp `json:"to_time"`
+	AfterID   int32            `json:"after_id"`
+	BatchSize int32            `json:"batch_size"`
+}
+
+type ExportClicksRow struct {
+	ID          int32            `json:"id"`
+	ShortID     pgtype.Text      `json:"short_id"`
+	ClickedAt   pgtype.Timestamp `json:"clicked_at"`
+	IpAddress   pgtype.Text      `json:"ip_address"`
+	UserAgent   pgtype.Text      `json:"user_agent"`
+	Country     pgtype.Text      `json:"country"`
+	Location    pgtype.Text      `json:"location"`
+	Device      pgtype.Text      `json:"device"`
+	Referrer    pgtype.Text      `json:"referrer"`
+	Source      pgtype.Text      `json:"source"`
+	VariantID   pgtype.UUID      `json:"variant_id"`
+	VisitorHash pgtype.Text      `json:"visitor_hash"`
+	ClickID     pgtype.UUID      `json:"click_id"`
+}
+
+func (q *Queries) ExportClicks(ctx context.Context, arg ExportClicksParams) ([]ExportClicksRow, error) {
+	rows, err := q.db.Query(ctx, exportClicks,
+		arg.UserID,
+		arg.ShortID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.AfterID,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportClicksRow
+	for rows.Next() {
+		var i ExportClicksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortID,
+			&i.ClickedAt,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.Country,
+			&i.Location,
+			&i.Device,
+			&i.Referrer,
+			&i.Source,
+			&i.VariantID,
+			&i.VisitorHash,
+			&i.ClickID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const finishJobRun = `-- name: FinishJobRun :exec
+UPDATE job_runs
+SET finished_at = $2, status = $3, affected = $4, error = $5
+WHERE run_id = $1
+`
+
+type FinishJobRunParams struct {
+	RunID      uuid.UUID        `json:"run_id"`
+	FinishedAt pgtype.Timestamp `json:"finished_at"`
+	Status     string           `json:"status"`
+	Affected   pgtype.Int8      `json:"affected"`
+	Error      pgtype.Text      `json:"error"`
+}
+
+func (q *Queries) FinishJobRun(ctx context.Context, arg FinishJobRunParams) error {
+	_, err := q.db.Exec(ctx, finishJobRun,
+		arg.RunID,
+		arg.FinishedAt,
+		arg.Status,
+		arg.Affected,
+		arg.Error,
+	)
+	return err
+}
+
+const getAPIKey = `-- name: GetAPIKey :one
+SELECT key, user_id, created_at FROM api_keys WHERE key = $1
+`
+
+func (q *Queries) GetAPIKey(ctx context.Context, key uuid.UUID) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, getAPIKey, key)
+	var i ApiKey
+	err := row.Scan(&i.Key, &i.UserID, &i.CreatedAt)
+	return i, err
+}
+
+const getClickCountSeed = `-- name: GetClickCountSeed :one
+SELECT GREATEST(
+    COALESCE((SELECT uc.click_count FROM url_counters uc WHERE uc.short_id = $1::text), 0),
+    COALESCE((SELECT u.total_clicks FROM urls u WHERE u.short_id = $1::text), 0)
+)::BIGINT AS click_count
+`
+
+func (q *Queries) GetClickCountSeed(ctx context.Context, shortID string) (int64, error) {
+	row := q.db.QueryRow(ctx, getClickCountSeed, shortID)
+	var click_count int64
+	err := row.Scan(&click_count)
+	return click_count, err
+}
+
+const getConversionByEvent = `-- name: GetConversionByEvent :one
+SELECT conversion_id, click_id, short_id, event, value, currency, source, created_at FROM conversions WHERE click_id = $1 AND event = $2
+`
+
+type GetConversionByEventParams struct {
+	ClickID uuid.UUID `json:"click_id"`
+	Event   string    `json:"event"`
+}
+
+func (q *Queries) GetConversionByEvent(ctx context.Context, arg GetConversionByEventParams) (Conversion, error) {
+	row := q.db.QueryRow(ctx, getConversionByEvent, arg.ClickID, arg.Event)
+	var i Conversion
+	err := row.Scan(
+		&i.ConversionID,
+		&i.ClickID,
+		&i.ShortID,
+		&i.Event,
+		&i.Value,
+		&i.Currency,
+		&i.Source,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getConversionClick = `-- name: GetConversionClick :one
+SELECT k.short_id, k.clicked_at, u.user_id
+FROM click_ids k
+JOIN urls u ON u.short_id = k.short_id
+WHERE k.click_id = $1
+`
+
+type GetConversionClickRow struct {
+	ShortID   string           `json:"short_id"`
+	ClickedAt pgtype.Timestamp `json:"clicked_at"`
+	UserID    pgtype.UUID      `json:"user_id"`
+}
+
+func (q *Queries) GetConversionClick(ctx context.Context, clickID uuid.UUID) (GetConversionClickRow, error) {
+	row := q.db.QueryRow(ctx, getConversionClick, clickID)
+	var i GetConversionClickRow
+	err := row.Scan(&i.ShortID, &i.ClickedAt, &i.UserID)
+	return i, err
+}
+
+const getConversionTotals = `-- name: GetConversionTotals :one
+SELECT COUNT(*) AS conversions, COUNT(DISTINCT click_id) AS converted_clicks
+FROM conversions WHERE short_id = $1
+`
+
+type GetConversionTotalsRow struct {
+	Conversions     int64 `json:"conversions"`
+	ConvertedClicks int64 `json:"converted_clicks"`
+}
+
+func (q *Queries) GetConversionTotals(ctx context.Context, shortID string) (GetConversionTotalsRow, error) {
+	row := q.db.QueryRow(ctx, getConversionTotals, shortID)
+	var i GetConversionTotalsRow
+	err := row.Scan(&i.Conversions, &i.ConvertedClicks)
+	return i, err
+}
+
+const getFolder = `-- name: GetFolder :one
+SELECT folder_id, user_id, name, created_at FROM folders WHERE folder_id = $1 AND user_id = $2
+`
+
+type GetFolderParams struct {
+	FolderID uuid.UUID `json:"folder_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetFolder(ctx context.Context, arg GetFolderParams) (Folder, error) {
+	row := q.db.QueryRow(ctx, getFolder, arg.FolderID, arg.UserID)
+	var i Folder
+	err := row.Scan(
+		&i.FolderID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getNextRulePosition = `-- name: GetNextRulePosition :one
+SELECT (COALESCE(MAX(position), 0) + 1)::INTEGER AS position FROM redirect_rules WHERE short_id = $1
+`
+
+func (q *Queries) GetNextRulePosition(ctx context.Context, shortID string) (int32, error) {
+	row := q.db.QueryRow(ctx, getNextRulePosition, shortID)
+	var position int32
+	err := row.Scan(&position)
+	return position, err
+}
+
+const getOrCreateTag = `-- name: GetOrCreateTag :one
+INSERT INTO tags (tag_id, user_id, name, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING tag_id, user_id, name, created_at
+`
+
+type GetOrCreateTagParams struct {
+	TagID     uuid.UUID        `json:"tag_id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Name      string           `json:"name"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) GetOrCreateTag(ctx context.Context, arg GetOrCreateTagParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, getOrCreateTag,
+		arg.TagID,
+		arg.UserID,
+		arg.Name,
+		arg.CreatedAt,
+	)
+	var i Tag
+	err := row.Scan(
+		&i.TagID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRollupWatermark = `-- name: GetRollupWatermark :one
+SELECT COALESCE(MAX(rolled_up_to), '1970-01-01'::TIMESTAMP)::TIMESTAMP AS rolled_up_to
+FROM rollup_state WHERE name = 'clicks'
+`
+
+func (q *Queries) GetRollupWatermark(ctx context.Context) (pgtype.Timestamp, error) {
+	row := q.db.QueryRow(ctx, getRollupWatermark)
+	var rolled_up_to pgtype.Timestamp
+	err := row.Scan(&rolled_up_to)
+	return rolled_up_to, err
+}
+
+const getTag = `-- name: GetTag :one
+SELECT tag_id, user_id, name, created_at FROM tags WHERE tag_id = $1 AND user_id = $2
+`
+
+type GetTagParams struct {
+	TagID  uuid.UUID `json:"tag_id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetTag(ctx context.Context, arg GetTagParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, getTag, arg.TagID, arg.UserID)
+	var i Tag
+	err := row.Scan(
+		&i.TagID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTagAnalytics = `-- name: GetTagAnalytics :many
+SELECT tags.tag_id, tags.name,
+       COUNT(urls.short_id) AS url_count,
+       COALESCE(SUM(urls.total_clicks), 0)::BIGINT AS click_count
+FROM tags
+LEFT JOIN url_tags ON url_tags.tag_id = tags.tag_id
+LEFT JOIN urls ON urls.short_id = url_tags.short_id
+WHERE tags.user_id = $1
+GROUP BY tags.tag_id, tags.name
+ORDER BY click_count DESC, tags.name
+`
+
+type GetTagAnalyticsRow struct {
+	TagID      uuid.UUID `json:"tag_id"`
+	Name       string    `json:"name"`
+	UrlCount   int64     `json:"url_count"`
+	ClickCount int64     `json:"click_count"`
+}
+
+// Click counts come from the running totals, which click retention keeps.
+func (q *Queries) GetTagAnalytics(ctx context.Context, userID uuid.UUID) ([]GetTagAnalyticsRow, error) {
+	rows, err := q.db.Query(ctx, getTagAnalytics, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagAnalyticsRow
+	for rows.Next() {
+		var i GetTagAnalyticsRow
+		if err := rows.Scan(
+			&i.TagID,
+			&i.Name,
+			&i.UrlCount,
+			&i.ClickCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTagByName = `-- name: GetTagByName :one
+SELECT tag_id, user_id, name, created_at FROM tags WHERE user_id = $1 AND name = $2
+`
+
+type GetTagByNameParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+}
+
+func (q *Queries) GetTagByName(ctx context.Context, arg GetTagByNameParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, getTagByName, arg.UserID, arg.Name)
+	var i Tag
+	err := row.Scan(
+		&i.TagID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTagURLClickCounts = `-- name: GetTagURLClickCounts :many
+SELECT url_tags.short_id, urls.total_clicks AS click_count
+FROM url_tags
+JOIN urls ON urls.short_id = url_tags.short_id
+WHERE url_tags.tag_id = $1
+ORDER BY click_count DESC
+`
+
+type GetTagURLClickCountsRow struct {
+	ShortID    string `json:"short_id"`
+	ClickCount int64  `json:"click_count"`
+}
+
+func (q *Queries) GetTagURLClickCounts(ctx context.Context, tagID uuid.UUID) ([]GetTagURLClickCountsRow, error) {
+	rows, err := q.db.Query(ctx, getTagURLClickCounts, tagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagURLClickCountsRow
+	for rows.Next() {
+		var i GetTagURLClickCountsRow
+		if err := rows.Scan(&i.ShortID, &i.ClickCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTotalClicks = `-- name: GetTotalClicks :one
+SELECT COALESCE(SUM(total_clicks), 0)::BIGINT as total FROM urls
+`
+
+// Summed from the running totals, which click retention keeps
+func (q *Queries) GetTotalClicks(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, getTotalClicks)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const getTotalURLs = `-- name: GetTotalURLs :one
+SELECT COUNT(*) as total FROM urls
+`
+
+func (q *Queries) GetTotalURLs(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, getTotalURLs)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const getTotalUsers = `-- name: GetTotalUsers :one
+SELECT COUNT(*) as total FROM users
+`
+
+func (q *Queries) GetTotalUsers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, getTotalUsers)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const getURL = `-- name: GetURL :one
+SELECT short_id, long_url, user_id, created_at, expires_at, click_limit, folder_id, title, description, notes, favicon_url, redirect_type, password_hash, query_mode, forward_path, ios_app_url, android_app_url, activates_at, expired_redirect_url, click_id_mode, total_clicks, last_clicked_at FROM urls WHERE short_id = $1
+`
+
+func (q *Queries) GetURL(ctx context.Context, shortID string) (Url, error) {
+	row := q.db.QueryRow(ctx, getURL, shortID)
+	var i Url
+	err := row.Scan(
+		&i.ShortID,
+		&i.LongUrl,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.ClickLimit,
+		&i.FolderID,
+		&i.Title,
+		&i.Description,
+		&i.Notes,
+		&i.FaviconUrl,
+		&i.RedirectType,
+		&i.PasswordHash,
+		&i.QueryMode,
+		&i.ForwardPath,
+		&i.IosAppUrl,
+		&i.AndroidAppUrl,
+		&i.ActivatesAt,
+		&i.ExpiredRedirectUrl,
+		&i.ClickIDMode,
+		&i.TotalClicks,
+		&i.LastClickedAt,
+	)
+	return i, err
+}
+
+const getURLClickTotal = `-- name: GetURLClickTotal :one
+SELECT total_clicks FROM urls WHERE short_id = $1
+`
+
+func (q *Queries) GetURLClickTotal(ctx context.Context, shortID string) (int64, error) {
+	row := q.db.QueryRow(ctx, getURLClickTotal, shortID)
+	var total_clicks int64
+	err := row.Scan(&total_clicks)
+	return total_clicks, err
+}
+
+const getURLPasswordHash = `-- name: GetURLPasswordHash :one
+SELECT password_hash FROM urls WHERE short_id = $1
+`
+
+func (q *Queries) GetURLPasswordHash(ctx context.Context, shortID string) (pgtype.Text, error) {
+	row := q.db.QueryRow(ctx, getURLPasswordHash, shortID)
+	var password_hash pgtype.Text
+	err := row.Scan(&password_hash)
+	return password_hash, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT user_id, username, email, created_at, updated_at, plan, is_admin FROM users WHERE email = $1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.Username,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Plan,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT user_id, username, email, created_at, updated_at, plan, is_admin FROM users WHERE user_id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByID, userID)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.Username,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Plan,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const getWebhook = `-- name: GetWebhook :one
+SELECT webhook_id, user_id, url, secret, events, active, created_at FROM webhooks WHERE webhook_id = $1 AND user_id = $2
+`
+
+type GetWebhookParams struct {
+	WebhookID uuid.UUID `json:"webhook_id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, getWebhook, arg.WebhookID, arg.UserID)
+	var i Webhook
+	err := row.Scan(
+		&i.WebhookID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const latestJobRuns = `-- name: LatestJobRuns :many
+SELECT DISTINCT ON (job_name) run_id, job_name, instance, started_at, finished_at, status, affected, error
+FROM job_runs
+ORDER BY job_name, started_at DESC
+`
+
+func (q *Queries) LatestJobRuns(ctx context.Context) ([]JobRun, error) {
+	rows, err := q.db.Query(ctx, latestJobRuns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []JobRun
+	for rows.Next() {
+		var i JobRun
+		if err := rows.Scan(
+			&i.RunID,
+			&i.JobName,
+			&i.Instance,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.Status,
+			&i.Affected,
+			&i.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAnalyticsLinks = `-- name: ListAnalyticsLinks :many
+SELECT u.short_id, u.title, u.long_url FROM urls u
+WHERE u.user_id = $1
+  AND ($2::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM url_tags t WHERE t.short_id = u.short_id AND t.tag_id = $2))
+  AND ($3::text[] IS NULL OR u.short_id = ANY($3::text[]))
+`
+
+type ListAnalyticsLinksParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	TagID    pgtype.UUID `json:"tag_id"`
+	ShortIds []string    `json:"short_ids"`
+}
+
+type ListAnalyticsLinksRow struct {
+	ShortID string      `json:"short_id"`
+	Title   pgtype.Text `json:"title"`
+	LongUrl string      `json:"long_url"`
+}
+
+func (q *Queries) ListAnalyticsLinks(ctx context.Context, arg ListAnalyticsLinksParams) ([]ListAnalyticsLinksRow, error) {
+	rows, err := q.db.Query(ctx, listAnalyticsLinks, arg.UserID, arg.TagID, arg.ShortIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAnalyticsLinksRow
+	for rows.Next() {
+		var i ListAnalyticsLinksRow
+		if err := rows.Scan(&i.ShortID, &i.Title, &i.LongUrl); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listClicks = `-- name: ListClicks :many
+SELECT id, short_id, ip_address, user_agent, clicked_at, variant_id, source, country, location, device, referrer, visitor_hash, click_id FROM clicks WHERE short_id = $1
+`
+
+func (q *Queries) ListClicks(ctx context.Context, shortID pgtype.Text) ([]Click, error) {
+	rows, err := q.db.Query(ctx, listClicks, shortID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Click
+	for rows.Next() {
+		var i Click
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortID,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.ClickedAt,
+			&i.VariantID,
+			&i.Source,
+			&i.Country,
+			&i.Location,
+			&i.Device,
+			&i.Referrer,
+			&i.VisitorHash,
+			&i.ClickID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversionEvents = `-- name: ListConversionEvents :many
+SELECT event, COUNT(*) AS conversions FROM conversions
+WHERE short_id = $1
+GROUP BY event
+ORDER BY conversions DESC, event
+`
+
+type ListConversionEventsRow struct {
+	Event       string `json:"event"`
+	Conversions int64  `json:"conversions"`
+}
+
+func (q *Queries) ListConversionEvents(ctx context.Context, shortID string) ([]ListConversionEventsRow, error) {
+	rows, err := q.db.Query(ctx, listConversionEvents, shortID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListConversionEventsRow
+	for rows.Next() {
+		var i ListConversionEventsRow
+		if err := rows.Scan(&i.Event, &i.Conversions); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversionRevenue = `-- name: ListConversionRevenue :many
+SELECT currency::TEXT AS currency, SUM(value)::FLOAT8 AS revenue FROM conversions
+WHERE short_id = $1 AND value IS NOT NULL
+GROUP BY currency
+ORDER BY currency
+`
+
+type ListConversionRevenueRow struct {
+	Currency string  `json:"currency"`
+	Revenue  float64 `json:"revenue"`
+}
+
+func (q *Queries) ListConversionRevenue(ctx context.Context, shortID string) ([]ListConversionRevenueRow, error) {
+	rows, err := q.db.Query(ctx, listConversionRevenue, shortID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListConversionRevenueRow
+	for rows.Next() {
+		var i ListConversionRevenueRow
+		if err := rows.Scan(&i.Currency, &i.Revenue); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeadWebhookDeliveries = `-- name: ListDeadWebhookDeliveries :many
+SELECT d.delivery_id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at FROM webhook_deliveries d
+JOIN webhooks w ON w.webhook_id = d.webhook_id
+WHERE w.user_id = $1 AND d.status = 'dead'
+ORDER BY d.created_at DESC
+LIMIT $2
+`
+
+type ListDeadWebhookDeliveriesParams struct {
+	UserID     uuid.UUID `json:"user_id"`
+	MaxResults int32     `json:"max_results"`
+}
+
+func (q *Queries) ListDeadWebhookDeliveries(ctx context.Context, arg ListDeadWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, listDeadWebhookDeliveries, arg.UserID, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.DeliveryID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastAttemptAt,
+			&i.LastStatusCode,
+			&i.LastError,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExistingShortIDs = `-- name: ListExistingShortIDs :many
+SELECT short_id FROM urls WHERE short_id = ANY($1::TEXT[])
+`
+
+func (q *Queries) ListExistingShortIDs(ctx context.Context, shortIds []string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listExistingShortIDs, shortIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var short_id string
+		if err := rows.Scan(&short_id); err != nil {
+			return nil, err
+		}
+		items = append(items, short_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFolderURLs = `-- name: ListFolderURLs :many
+SELECT short_id, long_url, user_id, created_at, expires_at, click_limit, folder_id, title, description, notes, favicon_url, redirect_type, password_hash, query_mode, forward_path, ios_app_url, android_app_url, activates_at, expired_redirect_url, click_id_mode, total_clicks, last_clicked_at FROM urls WHERE user_id = $1 AND folder_id = $2 ORDER BY created_at DESC
+`
+
+type ListFolderURLsParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	FolderID pgtype.UUID `json:"folder_id"`
+}
+
+func (q *Queries) ListFolderURLs(ctx context.Context, arg ListFolderURLsParams) ([]Url, error) {
+	rows, err := q.db.Query(ctx, listFolderURLs, arg.UserID, arg.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Url
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ShortID,
+			&i.LongUrl,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ClickLimit,
+			&i.FolderID,
+			&i.Title,
+			&i.Description,
+			&i.Notes,
+			&i.FaviconUrl,
+			&i.RedirectType,
+			&i.PasswordHash,
+			&i.QueryMode,
+			&i.ForwardPath,
+			&i.IosAppUrl,
+			&i.AndroidAppUrl,
+			&i.ActivatesAt,
+			&i.ExpiredRedirectUrl,
+			&i.ClickIDMode,
+			&i.TotalClicks,
+			&i.LastClickedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listJobRuns = `-- name: ListJobRuns :many
+SELECT run_id, job_name, instance, started_at, finished_at, status, affected, error FROM job_runs
+WHERE ($2::TEXT IS NULL OR job_name = $2)
+ORDER BY started_at DESC
+LIMIT $1
+`
+
+type ListJobRunsParams struct {
+	Limit   int32       `json:"limit"`
+	JobName pgtype.Text `json:"job_name"`
+}
+
+func (q *Queries) ListJobRuns(ctx context.Context, arg ListJobRunsParams) ([]JobRun, error) {
+	rows, err := q.db.Query(ctx, listJobRuns, arg.Limit, arg.JobName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []JobRun
+	for rows.Next() {
+		var i JobRun
+		if err := rows.Scan(
+			&i.RunID,
+			&i.JobName,
+			&i.Instance,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.Status,
+			&i.Affected,
+			&i.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRedirectRules = `-- name: ListRedirectRules :many
+SELECT rule_id, short_id, position, match_type, match_value, destination, created_at FROM redirect_rules WHERE short_id = $1 ORDER BY position, created_at
+`
+
+func (q *Queries) ListRedirectRules(ctx context.Context, shortID string) ([]RedirectRule, error) {
+	rows, err := q.db.Query(ctx, listRedirectRules, shortID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RedirectRule
+	for rows.Next() {
+		var i RedirectRule
+		if err := rows.Scan(
+			&i.RuleID,
+			&i.ShortID,
+			&i.Position,
+			&i.MatchType,
+			&i.MatchValue,
+			&i.Destination,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagNamesForURLs = `-- name: ListTagNamesForURLs :many
+SELECT url_tags.short_id, tags.name FROM tags
+JOIN url_tags ON url_tags.tag_id = tags.tag_id
+WHERE url_tags.short_id = ANY($1::text[])
+ORDER BY url_tags.short_id, tags.name
+`
+
+type ListTagNamesForURLsRow struct {
+	ShortID string `json:"short_id"`
+	Name    string `json:"name"`
+}
+
+func (q *Queries) ListTagNamesForURLs(ctx context.Context, shortIds []string) ([]ListTagNamesForURLsRow, error) {
+	rows, err := q.db.Query(ctx, listTagNamesForURLs, shortIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTagNamesForURLsRow
+	for rows.Next() {
+		var i ListTagNamesForURLsRow
+		if err := rows.Scan(&i.ShortID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagURLs = `-- name: ListTagURLs :many
+SELECT urls.short_id, urls.long_url, urls.user_id, urls.created_at, urls.expires_at, urls.click_limit, urls.folder_id, urls.title, urls.description, urls.notes, urls.favicon_url, urls.redirect_type, urls.password_hash, urls.query_mode, urls.forward_path, urls.ios_app_url, urls.android_app_url, urls.activates_at, urls.expired_redirect_url, urls.click_id_mode, urls.total_clicks, urls.last_clicked_at FROM urls
+JOIN url_tags ON url_tags.short_id = urls.short_id
+WHERE urls.user_id = $1 AND url_tags.tag_id = $2
+ORDER BY urls.created_at DESC
+`
+
+type ListTagURLsParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	TagID  uuid.UUID   `json:"tag_id"`
+}
+
+func (q *Queries) ListTagURLs(ctx context.Context, arg ListTagURLsParams) ([]Url, error) {
+	rows, err := q.db.Query(ctx, listTagURLs, arg.UserID, arg.TagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Url
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ShortID,
+			&i.LongUrl,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ClickLimit,
+			&i.FolderID,
+			&i.Title,
+			&i.Description,
+			&i.Notes,
+			&i.FaviconUrl,
+			&i.RedirectType,
+			&i.PasswordHash,
+			&i.QueryMode,
+			&i.ForwardPath,
+			&i.IosAppUrl,
+			&i.AndroidAppUrl,
+			&i.ActivatesAt,
+			&i.ExpiredRedirectUrl,
+			&i.ClickIDMode,
+			&i.TotalClicks,
+			&i.LastClickedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT user_id, username, email, created_at, updated_at FROM users WHERE email = $1
+const listURLCounters = `-- name: ListURLCounters :many
+SELECT short_id, click_count, unique_visitors FROM url_counters
+WHERE short_id = ANY($1::text[])
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRow(ctx, getUserByEmail, email)
-	var i User
-	err := row.Scan(
-		&i.UserID,
-		&i.Username,
-		&i.Email,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+type ListURLCountersRow struct {
+	ShortID        string `json:"short_id"`
+	ClickCount     int64  `json:"click_count"`
+	UniqueVisitors int64  `json:"unique_visitors"`
 }
 
-const getUserByID = `-- name: GetUserByID :one
-SELECT user_id, username, email, created_at, updated_at FROM users WHERE user_id = $1
-`
-
-func (q *Queries) GetUserByID(ctx context.Context, userID uuid.UUID) (User, error) {
-	row := q.db.QueryRow(ctx, getUserByID, userID)
-	var i User
-	err := row.Scan(
-		&i.UserID,
-		&i.Username,
-		&i.Email,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+func (q *Queries) ListURLCounters(ctx context.Context, shortIds []string) ([]ListURLCountersRow, error) {
+	rows, err := q.db.Query(ctx, listURLCounters, shortIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListURLCountersRow
+	for rows.Next() {
+		var i ListURLCountersRow
+		if err := rows.Scan(&i.ShortID, &i.ClickCount, &i.UniqueVisitors); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const listClicks = `-- name: ListClicks :many
-SELECT id, short_id, ip_address, user_agent, clicked_at FROM clicks WHERE short_id = $1
+const listURLVariants = `-- name: ListURLVariants :many
+SELECT variant_id, short_id, name, destination, weight, created_at FROM url_variants WHERE short_id = $1 ORDER BY created_at, name
 `
 
-func (q *Queries) ListClicks(ctx context.Context, shortID pgtype.Text) ([]Click, error) {
-	rows, err := q.db.Query(ctx, listClicks, shortID)
+func (q *Queries) ListURLVariants(ctx context.Context, shortID string) ([]UrlVariant, error) {
+	rows, err := q.db.Query(ctx, listURLVariants, shortID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Click
+	var items []UrlVariant
 	for rows.Next() {
-		var i Click
+		var i UrlVariant
 		if err := rows.Scan(
-			&i.ID,
+			&i.VariantID,
 			&i.ShortID,
-			&i.IpAddress,
-			&i.UserAgent,
-			&i.ClickedAt,
+			&i.Name,
+			&i.Destination,
+			&i.Weight,
+			&i.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -277,8 +2278,66 @@ func (q *Queries) ListUserAPIKeys(ctx context.Context, userID uuid.UUID) ([]ApiK
 	return items, nil
 }
 
+const listUserFolders = `-- name: ListUserFolders :many
+SELECT folder_id, user_id, name, created_at FROM folders WHERE user_id = $1 ORDER BY name
+`
+
+func (q *Queries) ListUserFolders(ctx context.Context, userID uuid.UUID) ([]Folder, error) {
+	rows, err := q.db.Query(ctx, listUserFolders, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Folder
+	for rows.Next() {
+		var i Folder
+		if err := rows.Scan(
+			&i.FolderID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserTags = `-- name: ListUserTags :many
+SELECT tag_id, user_id, name, created_at FROM tags WHERE user_id = $1 ORDER BY name
+`
+
+func (q *Queries) ListUserTags(ctx context.Context, userID uuid.UUID) ([]Tag, error) {
+	rows, err := q.db.Query(ctx, listUserTags, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.TagID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUserURLs = `-- name: ListUserURLs :many
-SELECT short_id, long_url, user_id, created_at, expires_at, click_limit FROM urls WHERE user_id = $1 ORDER BY created_at DESC
+SELECT short_id, long_url, user_id, created_at, expires_at, click_limit, folder_id, title, description, notes, favicon_url, redirect_type, password_hash, query_mode, forward_path, ios_app_url, android_app_url, activates_at, expired_redirect_url, click_id_mode, total_clicks, last_clicked_at FROM urls WHERE user_id = $1 ORDER BY created_at DESC
 `
 
 func (q *Queries) ListUserURLs(ctx context.Context, userID pgtype.UUID) ([]Url, error) {
@@ -297,6 +2356,101 @@ func (q *Queries) ListUserURLs(ctx context.Context, userID pgtype.UUID) ([]Url,
 			&i.CreatedAt,
 			&i.ExpiresAt,
 			&i.ClickLimit,
+			&i.FolderID,
+			&i.Title,
+			&i.Description,
+			&i.Notes,
+			&i.FaviconUrl,
+			&i.RedirectType,
+			&i.PasswordHash,
+			&i.QueryMode,
+			&i.ForwardPath,
+			&i.IosAppUrl,
+			&i.AndroidAppUrl,
+			&i.ActivatesAt,
+			&i.ExpiredRedirectUrl,
+			&i.ClickIDMode,
+			&i.TotalClicks,
+			&i.LastClickedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT delivery_id, webhook_id, event, payload, status, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error, delivered_at, created_at FROM webhook_deliveries
+WHERE webhook_id = $1
+  AND ($2::TEXT IS NULL OR status = $2::TEXT)
+ORDER BY created_at DESC
+LIMIT $3
+`
+
+type ListWebhookDeliveriesParams struct {
+	WebhookID  uuid.UUID   `json:"webhook_id"`
+	Status     pgtype.Text `json:"status"`
+	MaxResults int32       `json:"max_results"`
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveries, arg.WebhookID, arg.Status, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.DeliveryID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastAttemptAt,
+			&i.LastStatusCode,
+			&i.LastError,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhooks = `-- name: ListWebhooks :many
+SELECT webhook_id, user_id, url, secret, events, active, created_at FROM webhooks WHERE user_id = $1 ORDER BY created_at
+`
+
+func (q *Queries) ListWebhooks(ctx context.Context, userID uuid.UUID) ([]Webhook, error) {
+	rows, err := q.db.Query(ctx, listWebhooks, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.WebhookID,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.Active,
+			&i.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -309,15 +2463,30 @@ func (q *Queries) ListUserURLs(ctx context.Context, userID pgtype.UUID) ([]Url,
 }
 
 const logClick = `-- name: LogClick :exec
-INSERT INTO clicks (short_id, ip_address, user_agent, clicked_at)
-VALUES ($1, $2, $3, $4)
+WITH logged AS (
+    INSERT INTO clicks (short_id, ip_address, user_agent, clicked_at, variant_id, source, country, location, device, referrer, visitor_hash, click_id)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    RETURNING short_id, clicked_at
+)
+UPDATE urls SET total_clicks = urls.total_clicks + 1,
+    last_clicked_at = GREATEST(urls.last_clicked_at, logged.clicked_at)
+FROM logged
+WHERE urls.short_id = logged.short_id
 `
 
 type LogClickParams struct {
-	ShortID   pgtype.Text      `json:"short_id"`
-	IpAddress pgtype.Text      `json:"ip_address"`
-	UserAgent pgtype.Text      `json:"user_agent"`
-	ClickedAt pgtype.Timestamp `json:"clicked_at"`
+	ShortID     pgtype.Text      `json:"short_id"`
+	IpAddress   pgtype.Text      `json:"ip_address"`
+	UserAgent   pgtype.Text      `json:"user_agent"`
+	ClickedAt   pgtype.Timestamp `json:"clicked_at"`
+	VariantID   pgtype.UUID      `json:"variant_id"`
+	Source      pgtype.Text      `json:"source"`
+	Country     pgtype.Text      `json:"country"`
+	Location    pgtype.Text      `json:"location"`
+	Device      pgtype.Text      `json:"device"`
+	Referrer    pgtype.Text      `json:"referrer"`
+	VisitorHash pgtype.Text      `json:"visitor_hash"`
+	ClickID     pgtype.UUID      `json:"click_id"`
 }
 
 func (q *Queries) LogClick(ctx context.Context, arg LogClickParams) error {
@@ -326,27 +2495,261 @@ func (q *Queries) LogClick(ctx context.Context, arg LogClickParams) error {
 		arg.IpAddress,
 		arg.UserAgent,
 		arg.ClickedAt,
+		arg.VariantID,
+		arg.Source,
+		arg.Country,
+		arg.Location,
+		arg.Device,
+		arg.Referrer,
+		arg.VisitorHash,
+		arg.ClickID,
+	)
+	return err
+}
+
+const recordClickID = `-- name: RecordClickID :exec
+INSERT INTO click_ids (click_id, short_id, clicked_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (click_id) DO NOTHING
+`
+
+type RecordClickIDParams struct {
+	ClickID   uuid.UUID        `json:"click_id"`
+	ShortID   string           `json:"short_id"`
+	ClickedAt pgtype.Timestamp `json:"clicked_at"`
+}
+
+func (q *Queries) RecordClickID(ctx context.Context, arg RecordClickIDParams) error {
+	_, err := q.db.Exec(ctx, recordClickID, arg.ClickID, arg.ShortID, arg.ClickedAt)
+	return err
+}
+
+const recordWebhookAttempt = `-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = $1, next_attempt_at = $2, last_attempt_at = $3,
+    last_status_code = $4, last_error = $5, delivered_at = $6
+WHERE delivery_id = $7
+`
+
+type RecordWebhookAttemptParams struct {
+	Status         string           `json:"status"`
+	NextAttemptAt  pgtype.Timestamp `json:"next_attempt_at"`
+	AttemptedAt    pgtype.Timestamp `json:"attempted_at"`
+	LastStatusCode pgtype.Int4      `json:"last_status_code"`
+	LastError      pgtype.Text      `json:"last_error"`
+	DeliveredAt    pgtype.Timestamp `json:"delivered_at"`
+	DeliveryID     uuid.UUID        `json:"delivery_id"`
+}
+
+func (q *Queries) RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) error {
+	_, err := q.db.Exec(ctx, recordWebhookAttempt,
+		arg.Status,
+		arg.NextAttemptAt,
+		arg.AttemptedAt,
+		arg.LastStatusCode,
+		arg.LastError,
+		arg.DeliveredAt,
+		arg.DeliveryID,
+	)
+	return err
+}
+
+const retryWebhookDelivery = `-- name: RetryWebhookDelivery :one
+UPDATE webhook_deliveries d
+SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+FROM webhooks w
+WHERE w.webhook_id = d.webhook_id AND w.user_id = $1
+  AND d.delivery_id = $2 AND d.webhook_id = $3
+RETURNING d.delivery_id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at
+`
+
+type RetryWebhookDeliveryParams struct {
+	UserID     uuid.UUID `json:"user_id"`
+	DeliveryID uuid.UUID `json:"delivery_id"`
+	WebhookID  uuid.UUID `json:"webhook_id"`
+}
+
+func (q *Queries) RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, retryWebhookDelivery, arg.UserID, arg.DeliveryID, arg.WebhookID)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.DeliveryID,
+		&i.WebhookID,
+		&i.Event,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastAttemptAt,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.DeliveredAt,
+		&i.CreatedAt,
 	)
+	return i, err
+}
+
+const rollupDailyClicks = `-- name: RollupDailyClicks :execrows
+INSERT INTO click_rollups_daily (short_id, bucket, country, location, device, referrer, clicks)
+SELECT h.short_id, date_trunc('day', h.bucket), h.country, h.location, h.device, h.referrer, SUM(h.clicks)::BIGINT
+FROM click_rollups_hourly AS h
+WHERE h.bucket >= date_trunc('day', $1::TIMESTAMP) AND h.bucket < $2
+GROUP BY 1, 2, 3, 4, 5, 6
+ON CONFLICT (short_id, bucket, country, location, device, referrer)
+DO UPDATE SET clicks = EXCLUDED.clicks
+`
+
+type RollupDailyClicksParams struct {
+	FromTime pgtype.Timestamp `json:"from_time"`
+	ToTime   pgtype.Timestamp `json:"to_time"`
+}
+
+func (q *Queries) RollupDailyClicks(ctx context.Context, arg RollupDailyClicksParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rollupDailyClicks, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const rollupHourlyClicks = `-- name: RollupHourlyClicks :execrows
+INSERT INTO click_rollups_hourly (short_id, bucket, country, location, device, referrer, clicks)
+SELECT short_id, date_trunc('hour', clicked_at), COALESCE(country, ''), COALESCE(location, ''),
+       COALESCE(device, ''), COALESCE(referrer, ''), COUNT(*)
+FROM clicks
+WHERE clicked_at >= $1 AND clicked_at < $2 AND short_id IS NOT NULL
+GROUP BY 1, 2, 3, 4, 5, 6
+ON CONFLICT (short_id, bucket, country, location, device, referrer)
+DO UPDATE SET clicks = EXCLUDED.clicks
+`
+
+type RollupHourlyClicksParams struct {
+	FromTime pgtype.Timestamp `json:"from_time"`
+	ToTime   pgtype.Timestamp `json:"to_time"`
+}
+
+func (q *Queries) RollupHourlyClicks(ctx context.Context, arg RollupHourlyClicksParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rollupHourlyClicks, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setRollupWatermark = `-- name: SetRollupWatermark :exec
+INSERT INTO rollup_state (name, rolled_up_to) VALUES ('clicks', $1)
+ON CONFLICT (name) DO UPDATE SET rolled_up_to = EXCLUDED.rolled_up_to
+`
+
+func (q *Queries) SetRollupWatermark(ctx context.Context, rolledUpTo pgtype.Timestamp) error {
+	_, err := q.db.Exec(ctx, setRollupWatermark, rolledUpTo)
 	return err
 }
 
+const tryAdvisoryLock = `-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock($1::BIGINT)::BOOLEAN AS acquired
+`
+
+func (q *Queries) TryAdvisoryLock(ctx context.Context, lockKey int64) (bool, error) {
+	row := q.db.QueryRow(ctx, tryAdvisoryLock, lockKey)
+	var acquired bool
+	err := row.Scan(&acquired)
+	return acquired, err
+}
+
+const updateFolder = `-- name: UpdateFolder :one
+UPDATE folders SET name = $3
+WHERE folder_id = $1 AND user_id = $2
+RETURNING folder_id, user_id, name, created_at
+`
+
+type UpdateFolderParams struct {
+	FolderID uuid.UUID `json:"folder_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Name     string    `json:"name"`
+}
+
+func (q *Queries) UpdateFolder(ctx context.Context, arg UpdateFolderParams) (Folder, error) {
+	row := q.db.QueryRow(ctx, updateFolder, arg.FolderID, arg.UserID, arg.Name)
+	var i Folder
+	err := row.Scan(
+		&i.FolderID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateTag = `-- name: UpdateTag :one
+UPDATE tags SET name = $3
+WHERE tag_id = $1 AND user_id = $2
+RETURNING tag_id, user_id, name, created_at
+`
+
+type UpdateTagParams struct {
+	TagID  uuid.UUID `json:"tag_id"`
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+}
+
+func (q *Queries) UpdateTag(ctx context.Context, arg UpdateTagParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, updateTag, arg.TagID, arg.UserID, arg.Name)
+	var i Tag
+	err := row.Scan(
+		&i.TagID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const updateURL = `-- name: UpdateURL :one
 UPDATE urls
 SET long_url = COALESCE($2, long_url),
+    favicon_url = CASE WHEN COALESCE($2, long_url) <> long_url THEN NULL ELSE favicon_url END,
     expires_at = COALESCE($3, expires_at),
-    click_limit = COALESCE($4, click_limit)
+    click_limit = COALESCE($4, click_limit),
+    folder_id = $6,
+    title = $7,
+    description = $8,
+    notes = $9,
+    redirect_type = $10,
+    password_hash = $11,
+    query_mode = $12,
+    forward_path = $13,
+    ios_app_url = $14,
+    android_app_url = $15,
+    activates_at = $16,
+    expired_redirect_url = $17,
+    click_id_mode = $18
 WHERE short_id = $1 AND user_id = $5
-RETURNING short_id, long_url, user_id, created_at, expires_at, click_limit
+RETURNING short_id, long_url, user_id, created_at, expires_at, click_limit, folder_id, title, description, notes, favicon_url, redirect_type, password_hash, query_mode, forward_path, ios_app_url, android_app_url, activates_at, expired_redirect_url, click_id_mode, total_clicks, last_clicked_at
 `
 
 type UpdateURLParams struct {
-	ShortID    string           `json:"short_id"`
-	LongUrl    string           `json:"long_url"`
-	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
-	ClickLimit pgtype.Int4      `json:"click_limit"`
-	UserID     pgtype.UUID      `json:"user_id"`
+	ShortID            string           `json:"short_id"`
+	LongUrl            string           `json:"long_url"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
+	ClickLimit         pgtype.Int4      `json:"click_limit"`
+	UserID             pgtype.UUID      `json:"user_id"`
+	FolderID           pgtype.UUID      `json:"folder_id"`
+	Title              pgtype.Text      `json:"title"`
+	Description        pgtype.Text      `json:"description"`
+	Notes              pgtype.Text      `json:"notes"`
+	RedirectType       pgtype.Text      `json:"redirect_type"`
+	PasswordHash       pgtype.Text      `json:"password_hash"`
+	QueryMode          pgtype.Text      `json:"query_mode"`
+	ForwardPath        bool             `json:"forward_path"`
+	IosAppUrl          pgtype.Text      `json:"ios_app_url"`
+	AndroidAppUrl      pgtype.Text      `json:"android_app_url"`
+	ActivatesAt        pgtype.Timestamp `json:"activates_at"`
+	ExpiredRedirectUrl pgtype.Text      `json:"expired_redirect_url"`
+	ClickIDMode        pgtype.Text      `json:"click_id_mode"`
 }
 
+// The favicon of the old page is dropped when the destination changes
 func (q *Queries) UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, error) {
 	row := q.db.QueryRow(ctx, updateURL,
 		arg.ShortID,
@@ -354,6 +2757,19 @@ func (q *Queries) UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, erro
 		arg.ExpiresAt,
 		arg.ClickLimit,
 		arg.UserID,
+		arg.FolderID,
+		arg.Title,
+		arg.Description,
+		arg.Notes,
+		arg.RedirectType,
+		arg.PasswordHash,
+		arg.QueryMode,
+		arg.ForwardPath,
+		arg.IosAppUrl,
+		arg.AndroidAppUrl,
+		arg.ActivatesAt,
+		arg.ExpiredRedirectUrl,
+		arg.ClickIDMode,
 	)
 	var i Url
 	err := row.Scan(
@@ -363,6 +2779,131 @@ func (q *Queries) UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, erro
 		&i.CreatedAt,
 		&i.ExpiresAt,
 		&i.ClickLimit,
+		&i.FolderID,
+		&i.Title,
+		&i.Description,
+		&i.Notes,
+		&i.FaviconUrl,
+		&i.RedirectType,
+		&i.PasswordHash,
+		&i.QueryMode,
+		&i.ForwardPath,
+		&i.IosAppUrl,
+		&i.AndroidAppUrl,
+		&i.ActivatesAt,
+		&i.ExpiredRedirectUrl,
+		&i.ClickIDMode,
+		&i.TotalClicks,
+		&i.LastClickedAt,
+	)
+	return i, err
+}
+
+const updateURLMetadata = `-- name: UpdateURLMetadata :exec
+UPDATE urls
+SET title = COALESCE(title, $2),
+    description = COALESCE(description, $3),
+    favicon_url = $4
+WHERE short_id = $1
+`
+
+type UpdateURLMetadataParams struct {
+	ShortID     string      `json:"short_id"`
+	Title       pgtype.Text `json:"title"`
+	Description pgtype.Text `json:"description"`
+	FaviconUrl  pgtype.Text `json:"favicon_url"`
+}
+
+// Fetched metadata only fills in what is empty, so titles and descriptions
+// set by the user are kept
+func (q *Queries) UpdateURLMetadata(ctx context.Context, arg UpdateURLMetadataParams) error {
+	_, err := q.db.Exec(ctx, updateURLMetadata,
+		arg.ShortID,
+		arg.Title,
+		arg.Description,
+		arg.FaviconUrl,
+	)
+	return err
+}
+
+const updateURLVariant = `-- name: UpdateURLVariant :one
+UPDATE url_variants SET destination = $2, weight = $3
+WHERE variant_id = $1
+RETURNING variant_id, short_id, name, destination, weight, created_at
+`
+
+type UpdateURLVariantParams struct {
+	VariantID   uuid.UUID `json:"variant_id"`
+	Destination string    `json:"destination"`
+	Weight      int32     `json:"weight"`
+}
+
+func (q *Queries) UpdateURLVariant(ctx context.Context, arg UpdateURLVariantParams) (UrlVariant, error) {
+	row := q.db.QueryRow(ctx, updateURLVariant, arg.VariantID, arg.Destination, arg.Weight)
+	var i UrlVariant
+	err := row.Scan(
+		&i.VariantID,
+		&i.ShortID,
+		&i.Name,
+		&i.Destination,
+		&i.Weight,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateWebhook = `-- name: UpdateWebhook :one
+UPDATE webhooks SET url = $3, events = $4, active = $5
+WHERE webhook_id = $1 AND user_id = $2
+RETURNING webhook_id, user_id, url, secret, events, active, created_at
+`
+
+type UpdateWebhookParams struct {
+	WebhookID uuid.UUID `json:"webhook_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+}
+
+func (q *Queries) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, updateWebhook,
+		arg.WebhookID,
+		arg.UserID,
+		arg.Url,
+		arg.Events,
+		arg.Active,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.WebhookID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.Active,
+		&i.CreatedAt,
 	)
 	return i, err
 }
+
+const upsertURLCounter = `-- name: UpsertURLCounter :exec
+INSERT INTO url_counters (short_id, click_count, unique_visitors, updated_at)
+SELECT u.short_id, $1::BIGINT, $2::BIGINT, NOW()
+FROM urls u WHERE u.short_id = $3::text
+ON CONFLICT (short_id) DO UPDATE SET
+    click_count = GREATEST(url_counters.click_count, EXCLUDED.click_count),
+    unique_visitors = GREATEST(url_counters.unique_visitors, EXCLUDED.unique_visitors),
+    updated_at = EXCLUDED.updated_at
+`
+
+type UpsertURLCounterParams struct {
+	ClickCount     int64  `json:"click_count"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+	ShortID        string `json:"short_id"`
+}
+
+func (q *Queries) UpsertURLCounter(ctx context.Context, arg UpsertURLCounterParams) error {
+	_, err := q.db.Exec(ctx, upsertURLCounter, arg.ClickCount, arg.UniqueVisitors, arg.ShortID)
+	return err
+}
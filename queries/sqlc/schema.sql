@@ -4,7 +4,17 @@ CREATE TABLE users (
     username VARCHAR(50) UNIQUE NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP
+    updated_at TIMESTAMP,
+    plan VARCHAR(20) NOT NULL DEFAULT 'free',
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE folders (
+    folder_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id),
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
 );
 
 CREATE TABLE urls (
@@ -13,7 +23,32 @@ CREATE TABLE urls (
     user_id UUID REFERENCES users(user_id),
     created_at TIMESTAMP NOT NULL,
     expires_at TIMESTAMP,
-    click_limit INTEGER
+    click_limit INTEGER,
+    folder_id UUID REFERENCES folders(folder_id) ON DELETE SET NULL,
+    title TEXT,
+    description TEXT,
+    notes TEXT,
+    favicon_url TEXT,
+    redirect_type VARCHAR(16),
+    password_hash TEXT,
+    query_mode VARCHAR(16),
+    forward_path BOOLEAN NOT NULL DEFAULT FALSE,
+    ios_app_url TEXT,
+    android_app_url TEXT,
+    activates_at TIMESTAMP,
+    expired_redirect_url TEXT,
+    click_id_mode VARCHAR(16),
+    total_clicks BIGINT NOT NULL DEFAULT 0,
+    last_clicked_at TIMESTAMP
+);
+
+CREATE TABLE url_variants (
+    variant_id UUID PRIMARY KEY,
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    destination TEXT NOT NULL,
+    weight INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL
 );
 
 CREATE TABLE clicks (
@@ -21,7 +56,15 @@ CREATE TABLE clicks (
     short_id VARCHAR(10) REFERENCES urls(short_id),
     ip_address VARCHAR(45),
     user_agent TEXT,
-    clicked_at TIMESTAMP NOT NULL
+    clicked_at TIMESTAMP NOT NULL,
+    variant_id UUID REFERENCES url_variants(variant_id) ON DELETE SET NULL,
+    source VARCHAR(16),
+    country VARCHAR(2),
+    location VARCHAR(255),
+    device VARCHAR(16),
+    referrer VARCHAR(255),
+    visitor_hash VARCHAR(64),
+    click_id UUID
 );
 
 CREATE TABLE api_keys (
@@ -29,3 +72,120 @@ CREATE TABLE api_keys (
     user_id UUID NOT NULL REFERENCES users(user_id),
     created_at TIMESTAMP NOT NULL
 );
+
+CREATE TABLE tags (
+    tag_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id),
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE url_tags (
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (short_id, tag_id)
+);
+
+CREATE TABLE redirect_rules (
+    rule_id UUID PRIMARY KEY,
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    match_type VARCHAR(16) NOT NULL,
+    match_value TEXT NOT NULL,
+    destination TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE job_runs (
+    run_id UUID PRIMARY KEY,
+    job_name VARCHAR(50) NOT NULL,
+    instance VARCHAR(255) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    status VARCHAR(16) NOT NULL,
+    affected BIGINT,
+    error TEXT
+);
+
+CREATE TABLE click_rollups_hourly (
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL,
+    country VARCHAR(2) NOT NULL DEFAULT '',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    device VARCHAR(16) NOT NULL DEFAULT '',
+    referrer VARCHAR(255) NOT NULL DEFAULT '',
+    clicks BIGINT NOT NULL,
+    PRIMARY KEY (short_id, bucket, country, location, device, referrer)
+);
+
+CREATE TABLE click_rollups_daily (
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL,
+    country VARCHAR(2) NOT NULL DEFAULT '',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    device VARCHAR(16) NOT NULL DEFAULT '',
+    referrer VARCHAR(255) NOT NULL DEFAULT '',
+    clicks BIGINT NOT NULL,
+    PRIMARY KEY (short_id, bucket, country, location, device, referrer)
+);
+
+CREATE TABLE webhooks (
+    webhook_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    delivery_id UUID PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+    event VARCHAR(32) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_attempt_at TIMESTAMP,
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE expiry_notifications (
+    short_id VARCHAR(10) PRIMARY KEY REFERENCES urls(short_id) ON DELETE CASCADE,
+    notified_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE url_counters (
+    short_id VARCHAR(10) PRIMARY KEY REFERENCES urls(short_id) ON DELETE CASCADE,
+    click_count BIGINT NOT NULL DEFAULT 0,
+    unique_visitors BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE rollup_state (
+    name VARCHAR(50) PRIMARY KEY,
+    rolled_up_to TIMESTAMP NOT NULL
+);
+
+CREATE TABLE click_ids (
+    click_id UUID PRIMARY KEY,
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    clicked_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE conversions (
+    conversion_id UUID PRIMARY KEY,
+    click_id UUID NOT NULL,
+    short_id VARCHAR(10) NOT NULL REFERENCES urls(short_id) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    value NUMERIC(18, 4),
+    currency VARCHAR(3),
+    source VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (click_id, event)
+);
@@ -0,0 +1,67 @@
+package streamtoken
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix is the prefix of the token keys (stream_token:{token})
+const keyPrefix = "stream_token:"
+
+// TTL is how long a token can be redeemed after it was issued
+const TTL = time.Minute
+
+// ErrInvalid is returned for tokens that are unknown, expired, already used
+// or issued for another link
+var ErrInvalid = errors.New("invalid or expired stream token")
+
+// grant is what a token stands for
+type grant struct {
+	UserID  string `json:"user_id"`
+	ShortID string `json:"short_id"`
+}
+
+// Issue creates a token that lets its bearer open one click stream of a
+// link on behalf of a user. Browsers cannot set headers on EventSource and
+// WebSocket requests, so they pass it in the query string instead of an API
+// key; tokens are short-lived and single-use because URLs end up in logs.
+func Issue(ctx context.Context, redisClient *redis.Client, userID, shortID string) (string, time.Time, error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(random)
+
+	data, err := json.Marshal(grant{UserID: userID, ShortID: shortID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := redisClient.Set(ctx, keyPrefix+token, data, TTL).Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, time.Now().Add(TTL), nil
+}
+
+// Redeem uses up a token and returns the user it was issued to. The token
+// must have been issued for shortID.
+func Redeem(ctx context.Context, redisClient *redis.Client, token, shortID string) (string, error) {
+	data, err := redisClient.GetDel(ctx, keyPrefix+token).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var g grant
+	if err := json.Unmarshal(data, &g); err != nil || g.ShortID != shortID {
+		return "", ErrInvalid
+	}
+	return g.UserID, nil
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+const instrumentationName = "github.com/yeboahd24/url-shortener"
+
+// Options configure how traces are sampled and where they are sent
+type Options struct {
+	Exporter    string
+	Endpoint    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// Tracer returns the tracer used for spans of the service. It goes through the
+// global provider, so spans are dropped until Setup or Install has run.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// NewProvider creates a provider that batches spans to exporter. Root spans
+// are sampled at ratio; spans with a parent follow the parent's decision.
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string, ratio float64) *sdktrace.TracerProvider {
+	res, _ := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+}
+
+// Install makes provider the global tracer provider and propagates W3C trace
+// context and baggage in and out of HTTP requests.
+func Install(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// Setup installs a provider exporting with opts and returns a function that
+// flushes pending spans on shutdown. With the none exporter trace context is
+// still propagated, but no spans are recorded.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	switch opts.Exporter {
+	case "", ExporterNone:
+		Install(otel.GetTracerProvider())
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var exporterOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+		if err != nil {
+			return nil, err
+		}
+		provider := NewProvider(exporter, opts.ServiceName, opts.SampleRatio)
+		Install(provider)
+		return provider.Shutdown, nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+}
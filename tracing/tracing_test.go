@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs a provider exporting to memory for the duration of a test
+// and returns a function that flushes it and returns the ended spans by name.
+func record(t *testing.T) func() map[string]tracetest.SpanStub {
+	t.Helper()
+	previous := otel.GetTracerProvider()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, "url-shortener-test", 1)
+	Install(provider)
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+
+	return func() map[string]tracetest.SpanStub {
+		if err := provider.ForceFlush(context.Background()); err != nil {
+			t.Fatalf("ForceFlush: %v", err)
+		}
+		spans := map[string]tracetest.SpanStub{}
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = span
+		}
+		return spans
+	}
+}
+
+// redirectRouter serves a route that runs a query and a Redis command the
+// way the redirect handler does, through the same instrumentation.
+func redirectRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/{shortID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := QueryTracer{}.TraceQueryStart(r.Context(), nil, pgx.TraceQueryStartData{
+			SQL: "-- name: GetURL :one\nSELECT short_id FROM urls WHERE short_id = $1",
+		})
+		QueryTracer{}.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+
+		get := RedisHook{}.ProcessHook(func(context.Context, redis.Cmder) error { return nil })
+		get(r.Context(), redis.NewStringCmd(r.Context(), "get", "url:"+chi.URLParam(r, "shortID")))
+
+		w.WriteHeader(http.StatusFound)
+	})
+	return r
+}
+
+func TestRequestSpansShareOneTrace(t *testing.T) {
+	spans := record(t)
+
+	rec := httptest.NewRecorder()
+	redirectRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/abc123", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusFound)
+	}
+
+	got := spans()
+	request, ok := got["GET /{shortID}"]
+	if !ok {
+		t.Fatalf("no request span named after the route, got %v", names(got))
+	}
+	for _, name := range []string{"GetURL", "redis GET"} {
+		span, ok := got[name]
+		if !ok {
+			t.Fatalf("no %q span, got %v", name, names(got))
+		}
+		if span.SpanContext.TraceID() != request.SpanContext.TraceID() {
+			t.Errorf("%q trace = %s, want the request trace %s", name, span.SpanContext.TraceID(), request.SpanContext.TraceID())
+		}
+		if span.Parent.SpanID() != request.SpanContext.SpanID() {
+			t.Errorf("%q parent = %s, want the request span %s", name, span.Parent.SpanID(), request.SpanContext.SpanID())
+		}
+	}
+
+	// A lookup that finds nothing is a normal outcome, not a failed query
+	if query := got["GetURL"]; len(query.Events) != 0 {
+		t.Errorf("GetURL recorded %d events for pgx.ErrNoRows, want none", len(query.Events))
+	}
+}
+
+func TestRequestContinuesIncomingTrace(t *testing.T) {
+	spans := record(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	redirectRouter().ServeHTTP(httptest.NewRecorder(), req)
+
+	got := spans()
+	if len(got) != 3 {
+		t.Fatalf("got spans %v, want the request, query and Redis spans", names(got))
+	}
+	for name, span := range got {
+		if span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("%q trace = %s, want the incoming trace %s", name, span.SpanContext.TraceID(), traceID)
+		}
+	}
+	if parent := got["GET /{shortID}"].Parent.SpanID().String(); parent != "00f067aa0ba902b7" {
+		t.Errorf("request parent = %s, want the incoming span 00f067aa0ba902b7", parent)
+	}
+}
+
+func names(spans map[string]tracetest.SpanStub) []string {
+	var list []string
+	for name := range spans {
+		list = append(list, name)
+	}
+	return list
+}
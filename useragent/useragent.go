@@ -0,0 +1,68 @@
+package useragent
+
+import "strings"
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Operating systems
+const (
+	OSIOS     = "ios"
+	OSAndroid = "android"
+	OSWindows = "windows"
+	OSMacOS   = "macos"
+	OSLinux   = "linux"
+	OSOther   = "other"
+)
+
+// Info is the device and operating system detected from a User-Agent header
+type Info struct {
+	Device string
+	OS     string
+}
+
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview", "curl/", "wget/", "python-requests", "go-http-client"}
+
+// Parse detects the device type and operating system from a User-Agent
+// header. It only looks for well-known markers and is deliberately cheap,
+// since it runs on every redirect.
+func Parse(ua string) Info {
+	s := strings.ToLower(ua)
+
+	info := Info{Device: DeviceDesktop, OS: OSOther}
+	switch {
+	case strings.Contains(s, "iphone"), strings.Contains(s, "ipod"):
+		info.OS, info.Device = OSIOS, DeviceMobile
+	case strings.Contains(s, "ipad"):
+		info.OS, info.Device = OSIOS, DeviceTablet
+	case strings.Contains(s, "android"):
+		info.OS, info.Device = OSAndroid, DeviceMobile
+		// Android tablets leave "mobile" out of their User-Agent
+		if !strings.Contains(s, "mobile") {
+			info.Device = DeviceTablet
+		}
+	case strings.Contains(s, "windows"):
+		info.OS = OSWindows
+	case strings.Contains(s, "macintosh"), strings.Contains(s, "mac os x"):
+		info.OS = OSMacOS
+	case strings.Contains(s, "linux"), strings.Contains(s, "x11"):
+		info.OS = OSLinux
+	}
+
+	if s == "" {
+		info.Device = DeviceBot
+		return info
+	}
+	for _, marker := range botMarkers {
+		if strings.Contains(s, marker) {
+			info.Device = DeviceBot
+			break
+		}
+	}
+	return info
+}
@@ -0,0 +1,72 @@
+package visitorid
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// saltPrefix is the prefix of the daily salt keys (visitor_salt:{YYYY-MM-DD})
+const saltPrefix = "visitor_salt:"
+
+// saltTTL keeps a salt around for the clicks logged late on its day; once it
+// expires, that day's hashes can no longer be linked to an IP address.
+const saltTTL = 48 * time.Hour
+
+// Hasher derives anonymous visitor IDs from the IP address and user agent of
+// a visit. IDs are salted with a random value that rotates every UTC day and
+// is shared by all replicas through Redis, so the same visitor gets the same
+// ID for a day and an unrelated one the next.
+type Hasher struct {
+	redis *redis.Client
+
+	mu   sync.Mutex
+	day  string
+	salt string
+}
+
+// NewHasher creates a Hasher that keeps its salts in Redis
+func NewHasher(redisClient *redis.Client) *Hasher {
+	return &Hasher{redis: redisClient}
+}
+
+// ID returns the visitor ID of a visit made at the given time
+func (h *Hasher) ID(ctx context.Context, ip, userAgent string, at time.Time) (string, error) {
+	salt, err := h.saltFor(ctx, at.UTC().Format("2006-01-02"))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(salt + "|" + ip + "|" + userAgent))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// saltFor returns the salt of a day, creating it if this is the first replica
+// to need it.
+func (h *Hasher) saltFor(ctx context.Context, day string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.day == day {
+		return h.salt, nil
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	key := saltPrefix + day
+	if err := h.redis.SetNX(ctx, key, hex.EncodeToString(random), saltTTL).Err(); err != nil {
+		return "", err
+	}
+	salt, err := h.redis.Get(ctx, key).Result()
+	if err != nil {
+		return "", err
+	}
+
+	h.day, h.salt = day, salt
+	return salt, nil
+}
@@ -0,0 +1,219 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yeboahd24/url-shortener/database"
+	"github.com/yeboahd24/url-shortener/netguard"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+	"github.com/yeboahd24/url-shortener/tracing"
+)
+
+// claimBatch is how many due deliveries a dispatcher claims at once
+const claimBatch = 50
+
+// Retry backoff: the first retry waits retryBase, each later one twice as
+// long as the previous, up to retryMax.
+const (
+	retryBase = 30 * time.Second
+	retryMax  = 6 * time.Hour
+)
+
+// maxErrorLength caps the error text kept for a failed attempt
+const maxErrorLength = 1000
+
+// Dispatcher delivers queued webhook events. Every replica can run one:
+// deliveries are claimed with FOR UPDATE SKIP LOCKED and leased while they
+// are sent, so each goes out from one dispatcher at a time and one that dies
+// mid-delivery only delays it.
+type Dispatcher struct {
+	dsn         string
+	poll        time.Duration
+	timeout     time.Duration
+	maxAttempts int32
+	client      *http.Client
+
+	conn *pgx.Conn
+	db   *sqlc.Queries
+}
+
+// NewDispatcher creates a Dispatcher that connects with dsn, checks for due
+// deliveries every poll and gives up on a delivery after maxAttempts. Unless
+// allowPrivate is set, webhooks pointing at private networks are refused.
+func NewDispatcher(dsn string, poll, timeout time.Duration, maxAttempts int, allowPrivate bool) *Dispatcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = netguard.RefusePrivate
+	}
+	return &Dispatcher{
+		dsn:         dsn,
+		poll:        poll,
+		timeout:     timeout,
+		maxAttempts: int32(maxAttempts),
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: tracing.Transport(&http.Transport{DialContext: dialer.DialContext}),
+			// Redirects could lead anywhere; receivers must answer directly
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// retryDelay returns how long to wait before the attempt after the given one
+func retryDelay(attempt int32) time.Duration {
+	delay := retryBase
+	for i := int32(1); i < attempt && delay < retryMax; i++ {
+		delay *= 2
+	}
+	if delay > retryMax {
+		delay = retryMax
+	}
+	return delay
+}
+
+// Run delivers due events until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.poll)
+	defer ticker.Stop()
+	defer d.disconnect()
+
+	for {
+		// A full batch means more are probably waiting
+		for d.connect(ctx) {
+			if d.dispatch(ctx) < claimBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) disconnect() {
+	if d.conn != nil {
+		d.conn.Close(context.Background())
+	}
+	d.conn, d.db = nil, nil
+}
+
+// connect (re)connects when needed and reports whether it is connected
+func (d *Dispatcher) connect(ctx context.Context) bool {
+	if d.conn != nil && d.conn.Ping(ctx) != nil {
+		d.disconnect()
+	}
+	if d.conn == nil {
+		conn, err := database.ConnectSession(ctx, d.dsn)
+		if err != nil {
+			slog.Error("Webhook dispatcher failed to connect", "error", err)
+			return false
+		}
+		d.conn, d.db = conn, sqlc.New(conn)
+	}
+	return true
+}
+
+// dispatch sends one batch of due deliveries and returns its size
+func (d *Dispatcher) dispatch(ctx context.Context) int {
+	// The batch is sent one by one, so the lease covers every attempt timing out
+	deliveries, err := d.db.ClaimWebhookDeliveries(ctx, sqlc.ClaimWebhookDeliveriesParams{
+		LeaseUntil: pgtype.Timestamp{Time: time.Now().Add(claimBatch * d.timeout), Valid: true},
+		BatchSize:  claimBatch,
+	})
+	if err != nil {
+		slog.Error("Failed to claim webhook deliveries", "error", err)
+		return 0
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+		d.deliver(ctx, delivery)
+	}
+	return len(deliveries)
+}
+
+// deliver makes one delivery attempt and records its outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery sqlc.ClaimWebhookDeliveriesRow) {
+	attempted := time.Now()
+	statusCode, err := d.send(ctx, delivery, attempted)
+
+	params := sqlc.RecordWebhookAttemptParams{
+		DeliveryID:    delivery.DeliveryID,
+		Status:        StatusDelivered,
+		NextAttemptAt: pgtype.Timestamp{Time: attempted, Valid: true},
+		AttemptedAt:   pgtype.Timestamp{Time: attempted, Valid: true},
+		LastStatusCode: pgtype.Int4{
+			Int32: int32(statusCode),
+			Valid: statusCode != 0,
+		},
+	}
+	switch {
+	case err == nil:
+		params.DeliveredAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+	case delivery.Attempts >= d.maxAttempts:
+		params.Status = StatusDead
+	default:
+		params.Status = StatusPending
+		params.NextAttemptAt = pgtype.Timestamp{Time: attempted.Add(retryDelay(delivery.Attempts)), Valid: true}
+	}
+	if err != nil {
+		slog.Warn("Webhook delivery attempt failed", "delivery_id", delivery.DeliveryID,
+			"attempt", delivery.Attempts, "status", params.Status, "error", err)
+		msg := err.Error()
+		if len(msg) > maxErrorLength {
+			msg = msg[:maxErrorLength]
+		}
+		params.LastError = pgtype.Text{String: msg, Valid: true}
+	}
+
+	if err := d.db.RecordWebhookAttempt(ctx, params); err != nil {
+		slog.Error("Failed to record webhook delivery", "delivery_id", delivery.DeliveryID, "error", err)
+	}
+}
+
+// send posts a delivery and returns the response status. Anything but a 2xx
+// answer is a failure.
+func (d *Dispatcher) send(ctx context.Context, delivery sqlc.ClaimWebhookDeliveriesRow, at time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := at.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "url-shortener-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.DeliveryID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,147 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeboahd24/url-shortener/queries/sqlc"
+)
+
+// Event types a webhook can subscribe to
+const (
+	EventLinkCreated = "link.created"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
+	EventLinkExpired = "link.expired"
+	EventLinkClicked = "link.clicked"
+)
+
+// Events lists every event type
+var Events = []string{EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkExpired, EventLinkClicked}
+
+// Delivery statuses. Dead deliveries ran out of attempts and stay in the
+// dead-letter list until they are retried by hand.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Request headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// ValidEvent reports whether event is a known event type
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSecret generates the signing secret of a webhook
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header of a delivery: the hex HMAC-SHA256 of
+// "{timestamp}.{body}" keyed with the webhook secret. Receivers recompute it
+// and should reject old timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Event is the JSON body of a delivery
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Link is the data of link events
+type Link struct {
+	ShortID    string     `json:"short_id"`
+	LongURL    string     `json:"long_url,omitempty"`
+	Title      string     `json:"title,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	ClickLimit *int32     `json:"click_limit,omitempty"`
+}
+
+// LinkFromURL builds the event data of a link
+func LinkFromURL(url sqlc.Url) Link {
+	link := Link{
+		ShortID:   url.ShortID,
+		LongURL:   url.LongUrl,
+		Title:     url.Title.String,
+		CreatedAt: &url.CreatedAt.Time,
+	}
+	if url.ExpiresAt.Valid {
+		link.ExpiresAt = &url.ExpiresAt.Time
+	}
+	if url.ClickLimit.Valid {
+		link.ClickLimit = &url.ClickLimit.Int32
+	}
+	return link
+}
+
+func newEvent(event string, data interface{}) (string, error) {
+	payload, err := json.Marshal(Event{
+		ID:        uuid.New().String(),
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	return string(payload), err
+}
+
+// Enqueue queues an event for the webhooks of a user that subscribe to it
+func Enqueue(ctx context.Context, db *sqlc.Queries, userID uuid.UUID, event string, data interface{}) error {
+	payload, err := newEvent(event, data)
+	if err != nil {
+		return err
+	}
+	_, err = db.EnqueueUserWebhookEvent(ctx, sqlc.EnqueueUserWebhookEventParams{
+		Event:   event,
+		Payload: payload,
+		UserID:  userID,
+	})
+	return err
+}
+
+// EnqueueForLink queues an event for the webhooks of the owner of a link.
+// Links without an owner have no webhooks.
+func EnqueueForLink(ctx context.Context, db *sqlc.Queries, shortID, event string, data interface{}) error {
+	payload, err := newEvent(event, data)
+	if err != nil {
+		return err
+	}
+	_, err = db.EnqueueLinkWebhookEvent(ctx, sqlc.EnqueueLinkWebhookEventParams{
+		Event:   event,
+		Payload: payload,
+		ShortID: shortID,
+	})
+	return err
+}